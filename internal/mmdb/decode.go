@@ -0,0 +1,239 @@
+package mmdb
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"math/big"
+)
+
+// Data section field types
+const (
+	typeExtended  = 0
+	typePointer   = 1
+	typeString    = 2
+	typeDouble    = 3
+	typeBytes     = 4
+	typeUint16    = 5
+	typeUint32    = 6
+	typeMap       = 7
+	typeInt32     = 8
+	typeUint64    = 9
+	typeUint128   = 10
+	typeArray     = 11
+	typeContainer = 12
+	typeEnd       = 13
+	typeBool      = 14
+	typeFloat     = 15
+)
+
+// maxDepth bounds the nesting of maps and arrays, so a corrupt file can't
+// recurse without end
+const maxDepth = 32
+
+// decoder reads values from a data section. Pointers are offsets from the
+// start of buf.
+type decoder struct {
+	buf []byte
+}
+
+// decode reads the value at offset and returns it with the offset just past
+// it. Maps decode to map[string]interface{}, arrays to []interface{},
+// unsigned integers to uint64 (uint128 to *big.Int), int32 to int64 and
+// floats to float64.
+func (d *decoder) decode(offset uint) (interface{}, uint, error) {
+	return d.decodeDepth(offset, 0)
+}
+
+func (d *decoder) decodeDepth(offset uint, depth int) (interface{}, uint, error) {
+	if depth > maxDepth {
+		return nil, 0, fmt.Errorf("data nested deeper than %d", maxDepth)
+	}
+
+	typ, size, offset, err := d.control(offset)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if typ == typePointer {
+		// The value pointed to is decoded in place of the pointer; reading
+		// continues after the pointer itself
+		value, _, err := d.decodeDepth(size, depth+1)
+		return value, offset, err
+	}
+
+	switch typ {
+	case typeMap:
+		m := make(map[string]interface{}, size)
+		for i := uint(0); i < size; i++ {
+			var key, value interface{}
+			key, offset, err = d.decodeDepth(offset, depth+1)
+			if err != nil {
+				return nil, 0, err
+			}
+			name, ok := key.(string)
+			if !ok {
+				return nil, 0, fmt.Errorf("map key of type %T", key)
+			}
+			value, offset, err = d.decodeDepth(offset, depth+1)
+			if err != nil {
+				return nil, 0, err
+			}
+			m[name] = value
+		}
+		return m, offset, nil
+	case typeArray:
+		a := make([]interface{}, 0, size)
+		for i := uint(0); i < size; i++ {
+			var value interface{}
+			value, offset, err = d.decodeDepth(offset, depth+1)
+			if err != nil {
+				return nil, 0, err
+			}
+			a = append(a, value)
+		}
+		return a, offset, nil
+	case typeBool:
+		if size > 1 {
+			return nil, 0, fmt.Errorf("boolean of size %d", size)
+		}
+		return size == 1, offset, nil
+	}
+
+	if offset+size > uint(len(d.buf)) {
+		return nil, 0, fmt.Errorf("value at %d runs past the data section", offset)
+	}
+	raw := d.buf[offset : offset+size]
+	next := offset + size
+
+	switch typ {
+	case typeString:
+		return string(raw), next, nil
+	case typeBytes:
+		return append([]byte(nil), raw...), next, nil
+	case typeDouble:
+		if size != 8 {
+			return nil, 0, fmt.Errorf("double of size %d", size)
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(raw)), next, nil
+	case typeFloat:
+		if size != 4 {
+			return nil, 0, fmt.Errorf("float of size %d", size)
+		}
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(raw))), next, nil
+	case typeUint16, typeUint32, typeUint64:
+		limit := uint(8)
+		switch typ {
+		case typeUint16:
+			limit = 2
+		case typeUint32:
+			limit = 4
+		}
+		if size > limit {
+			return nil, 0, fmt.Errorf("unsigned integer of size %d", size)
+		}
+		var v uint64
+		for _, b := range raw {
+			v = v<<8 | uint64(b)
+		}
+		return v, next, nil
+	case typeInt32:
+		if size > 4 {
+			return nil, 0, fmt.Errorf("int32 of size %d", size)
+		}
+		var v uint32
+		for _, b := range raw {
+			v = v<<8 | uint32(b)
+		}
+		return int64(int32(v)), next, nil
+	case typeUint128:
+		if size > 16 {
+			return nil, 0, fmt.Errorf("uint128 of size %d", size)
+		}
+		return new(big.Int).SetBytes(raw), next, nil
+	default:
+		return nil, 0, fmt.Errorf("unsupported data type %d", typ)
+	}
+}
+
+// control reads the control byte(s) at offset and returns the field type,
+// its size (for pointers, the target offset) and the offset of the payload
+func (d *decoder) control(offset uint) (typ, size, next uint, err error) {
+	b, offset, err := d.bytes(offset, 1)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	ctrl := b[0]
+	typ = uint(ctrl >> 5)
+
+	if typ == typePointer {
+		return d.pointer(ctrl, offset)
+	}
+
+	if typ == typeExtended {
+		b, offset, err = d.bytes(offset, 1)
+		if err != nil {
+			return 0, 0, 0, err
+		}
+		typ = uint(b[0]) + 7
+		if typ <= typeMap || typ == typeContainer || typ == typeEnd {
+			return 0, 0, 0, fmt.Errorf("invalid extended type %d", typ)
+		}
+	}
+
+	size = uint(ctrl & 0x1f)
+	if size >= 29 {
+		n := size - 28
+		b, offset, err = d.bytes(offset, n)
+		if err != nil {
+			return 0, 0, 0, err
+		}
+		var extra uint
+		for _, c := range b {
+			extra = extra<<8 | uint(c)
+		}
+		switch n {
+		case 1:
+			size = 29 + extra
+		case 2:
+			size = 285 + extra
+		default:
+			size = 65821 + extra
+		}
+	}
+
+	return typ, size, offset, nil
+}
+
+// pointer decodes the target of a pointer whose control byte is ctrl
+func (d *decoder) pointer(ctrl byte, offset uint) (typ, target, next uint, err error) {
+	n := uint(ctrl>>3&0x3) + 1
+	b, offset, err := d.bytes(offset, n)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+
+	var v uint
+	if n < 4 {
+		v = uint(ctrl & 0x7)
+	}
+	for _, c := range b {
+		v = v<<8 | uint(c)
+	}
+	switch n {
+	case 2:
+		v += 2048
+	case 3:
+		v += 526336
+	}
+
+	return typePointer, v, offset, nil
+}
+
+// bytes returns n bytes at offset and the offset after them
+func (d *decoder) bytes(offset, n uint) ([]byte, uint, error) {
+	if offset+n > uint(len(d.buf)) {
+		return nil, 0, fmt.Errorf("unexpected end of data at %d", offset)
+	}
+	return d.buf[offset : offset+n], offset + n, nil
+}
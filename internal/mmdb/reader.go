@@ -0,0 +1,190 @@
+// Package mmdb reads MaxMind DB files, the format of the GeoLite2 and
+// GeoIP2 databases. It covers what lookups need: the binary search tree,
+// the data section and the metadata.
+package mmdb
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"os"
+)
+
+// metadataMarker precedes the metadata map at the end of the file
+var metadataMarker = []byte("\xAB\xCD\xEFMaxMind.com")
+
+// dataSeparator is the size of the zero block between the tree and the data
+const dataSeparator = 16
+
+// Metadata describes a database
+type Metadata struct {
+	DatabaseType string // e.g. GeoLite2-ASN, GeoLite2-City
+	IPVersion    int    // 4 or 6
+	NodeCount    uint
+	RecordSize   uint // bits per search tree record: 24, 28 or 32
+	BuildEpoch   uint64
+}
+
+// Reader looks up addresses in a database held in memory
+type Reader struct {
+	meta      Metadata
+	tree      []byte
+	data      decoder
+	ipv4Start uint // node reached after the 96 leading zero bits of an IPv4-mapped address
+}
+
+// Open reads the database at path
+func Open(path string) (*Reader, error) {
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := New(buf)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return r, nil
+}
+
+// New parses a database from buf
+func New(buf []byte) (*Reader, error) {
+	at := bytes.LastIndex(buf, metadataMarker)
+	if at < 0 {
+		return nil, fmt.Errorf("not a MaxMind DB file: metadata marker missing")
+	}
+
+	meta, err := parseMetadata(buf[at+len(metadataMarker):])
+	if err != nil {
+		return nil, err
+	}
+
+	treeSize := meta.NodeCount * meta.RecordSize / 4
+	if treeSize+dataSeparator > uint(at) {
+		return nil, fmt.Errorf("search tree of %d nodes runs past the data section", meta.NodeCount)
+	}
+
+	r := &Reader{
+		meta: meta,
+		tree: buf[:treeSize],
+		data: decoder{buf: buf[treeSize+dataSeparator : at]},
+	}
+
+	if meta.IPVersion == 6 {
+		node := uint(0)
+		for i := 0; i < 96 && node < meta.NodeCount; i++ {
+			node, err = r.record(node, 0)
+			if err != nil {
+				return nil, err
+			}
+		}
+		r.ipv4Start = node
+	}
+
+	return r, nil
+}
+
+// parseMetadata decodes the metadata map following the marker
+func parseMetadata(buf []byte) (Metadata, error) {
+	d := decoder{buf: buf}
+	value, _, err := d.decode(0)
+	if err != nil {
+		return Metadata{}, fmt.Errorf("metadata: %w", err)
+	}
+	m, ok := value.(map[string]interface{})
+	if !ok {
+		return Metadata{}, fmt.Errorf("metadata is a %T, not a map", value)
+	}
+
+	uintField := func(key string) uint64 {
+		v, _ := m[key].(uint64)
+		return v
+	}
+
+	meta := Metadata{
+		IPVersion:  int(uintField("ip_version")),
+		NodeCount:  uint(uintField("node_count")),
+		RecordSize: uint(uintField("record_size")),
+		BuildEpoch: uintField("build_epoch"),
+	}
+	meta.DatabaseType, _ = m["database_type"].(string)
+
+	if major := uintField("binary_format_major_version"); major != 2 {
+		return Metadata{}, fmt.Errorf("unsupported format version %d", major)
+	}
+	if meta.RecordSize != 24 && meta.RecordSize != 28 && meta.RecordSize != 32 {
+		return Metadata{}, fmt.Errorf("unsupported record size %d", meta.RecordSize)
+	}
+	if meta.IPVersion != 4 && meta.IPVersion != 6 {
+		return Metadata{}, fmt.Errorf("unsupported IP version %d", meta.IPVersion)
+	}
+
+	return meta, nil
+}
+
+// Metadata returns the database's metadata
+func (r *Reader) Metadata() Metadata {
+	return r.meta
+}
+
+// Lookup returns the record for ip, or nil when the database has none.
+// IPv6 addresses can't be looked up in an IPv4 database.
+func (r *Reader) Lookup(ip net.IP) (interface{}, error) {
+	bits := ip.To4()
+	node := uint(0)
+	if bits != nil {
+		node = r.ipv4Start
+	} else {
+		if r.meta.IPVersion == 4 {
+			return nil, fmt.Errorf("IPv6 address %s in an IPv4 database", ip)
+		}
+		if bits = ip.To16(); bits == nil {
+			return nil, fmt.Errorf("invalid IP address %v", ip)
+		}
+	}
+
+	for i := 0; i < len(bits)*8 && node < r.meta.NodeCount; i++ {
+		bit := uint(bits[i/8]>>(7-i%8)) & 1
+		next, err := r.record(node, bit)
+		if err != nil {
+			return nil, err
+		}
+		node = next
+	}
+
+	switch {
+	case node == r.meta.NodeCount:
+		return nil, nil
+	case node < r.meta.NodeCount:
+		return nil, fmt.Errorf("search tree deeper than the address")
+	}
+
+	offset := node - r.meta.NodeCount - dataSeparator
+	value, _, err := r.data.decode(offset)
+	return value, err
+}
+
+// record reads the left (bit 0) or right (bit 1) record of node
+func (r *Reader) record(node, bit uint) (uint, error) {
+	size := r.meta.RecordSize / 4
+	base := node * size
+	if base+size > uint(len(r.tree)) {
+		return 0, fmt.Errorf("node %d outside the search tree", node)
+	}
+	b := r.tree[base : base+size]
+
+	switch r.meta.RecordSize {
+	case 24:
+		b = b[bit*3 : bit*3+3]
+		return uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2]), nil
+	case 28:
+		// The middle byte holds the high nibble of each record
+		if bit == 0 {
+			return uint(b[3]&0xf0)<<20 | uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2]), nil
+		}
+		return uint(b[3]&0x0f)<<24 | uint(b[4])<<16 | uint(b[5])<<8 | uint(b[6]), nil
+	default:
+		b = b[bit*4 : bit*4+4]
+		return uint(b[0])<<24 | uint(b[1])<<16 | uint(b[2])<<8 | uint(b[3]), nil
+	}
+}
@@ -0,0 +1,91 @@
+package resolve
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// maxTransferMessages bounds a zone transfer so a broken server can't stream
+// forever
+const maxTransferMessages = 10000
+
+// Transfer requests a full zone transfer (AXFR, RFC 5936) of zone from server
+// over TCP and returns every record of the zone, including the closing SOA.
+// UDP entries are transferred over TCP on the same address; DoT and DoH
+// servers are not supported.
+func (c *Client) Transfer(ctx context.Context, server, zone string) ([]RR, error) {
+	ep, err := ParseServer(server)
+	if err != nil {
+		return nil, err
+	}
+	if ep.Scheme != SchemeUDP && ep.Scheme != SchemeTCP {
+		return nil, fmt.Errorf("zone transfers are not supported over %s", ep.Scheme)
+	}
+
+	m := NewQuery(zone, TypeAXFR)
+	m.RecursionDesired = false
+	m.ID = randomID()
+	req, err := m.Pack()
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := c.dial(ctx, "tcp", ep.Addr)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if err := writeTCPMessage(conn, req); err != nil {
+		return nil, err
+	}
+
+	var records []RR
+	soas := 0
+	for i := 0; i < maxTransferMessages; i++ {
+		// Large zones take a while; give every message the full timeout
+		conn.SetReadDeadline(time.Now().Add(c.Timeout))
+
+		data, err := readTCPMessage(conn)
+		if err != nil {
+			return nil, fmt.Errorf("zone transfer from %s failed: %w", server, err)
+		}
+		resp, err := Unpack(data)
+		if err != nil {
+			return nil, err
+		}
+		if resp.ID != m.ID {
+			return nil, errors.New("dns: response ID mismatch")
+		}
+		if resp.Rcode != RcodeSuccess {
+			return nil, fmt.Errorf("zone transfer refused by %s: %s", server, RcodeString(resp.Rcode))
+		}
+		if i == 0 {
+			if err := checkResponse(m, resp); err != nil {
+				return nil, err
+			}
+			if len(resp.Answers) == 0 || resp.Answers[0].Type != TypeSOA {
+				return nil, fmt.Errorf("zone transfer from %s did not start with SOA", server)
+			}
+		}
+
+		// The transfer ends with the second copy of the SOA record
+		for _, rr := range resp.Answers {
+			records = append(records, rr)
+			if rr.Type == TypeSOA {
+				soas++
+				if soas == 2 {
+					return records, nil
+				}
+			}
+		}
+
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+	}
+
+	return nil, fmt.Errorf("zone transfer from %s did not finish", server)
+}
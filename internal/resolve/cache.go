@@ -0,0 +1,255 @@
+package resolve
+
+import (
+	"container/list"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultCacheSize is the number of hosts kept when no size is configured
+const defaultCacheSize = 100000
+
+// Cache is a bounded LRU of resolver answers that expire with their TTL.
+// Answers are keyed on the host and the record types asked for, as an answer
+// to fewer types than wanted would read as missing records.
+type Cache struct {
+	capacity int
+	entries  map[string]*list.Element
+	order    *list.List // most recently used at the front
+	mu       sync.Mutex
+	nowFunc  func() time.Time
+}
+
+type cacheEntry struct {
+	Result  *Result   `json:"result"`
+	Types   string    `json:"types"` // record types asked for, see typeSet
+	Expires time.Time `json:"expires"`
+}
+
+// key identifies the entry in the cache
+func (e *cacheEntry) key() string {
+	return cacheKey(e.Result.Host, e.Types)
+}
+
+func cacheKey(host, types string) string {
+	return types + " " + host
+}
+
+// typeSet returns record types as a sorted list of mnemonics, e.g. "A,AAAA"
+func typeSet(qtypes []uint16) string {
+	names := typeNamesOf(qtypes)
+	sort.Strings(names)
+
+	set := make([]string, 0, len(names))
+	for i, name := range names {
+		if i == 0 || name != names[i-1] {
+			set = append(set, name)
+		}
+	}
+	return strings.Join(set, ",")
+}
+
+// NewCache creates a cache holding at most capacity hosts
+func NewCache(capacity int) *Cache {
+	if capacity <= 0 {
+		capacity = defaultCacheSize
+	}
+
+	return &Cache{
+		capacity: capacity,
+		entries:  make(map[string]*list.Element),
+		order:    list.New(),
+		nowFunc:  time.Now,
+	}
+}
+
+// SetClock overrides the time source, used by tests
+func (c *Cache) SetClock(now func() time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.nowFunc = now
+}
+
+// Get returns the cached answer for host to exactly qtypes unless it has
+// expired
+func (c *Cache) Get(host string, qtypes []uint16) (*Result, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := cacheKey(host, typeSet(qtypes))
+	elem, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+
+	entry := elem.Value.(*cacheEntry)
+	if !c.nowFunc().Before(entry.Expires) {
+		c.order.Remove(elem)
+		delete(c.entries, key)
+		return nil, false
+	}
+
+	c.order.MoveToFront(elem)
+	return entry.Result, true
+}
+
+// Put stores an answer to qtypes for ttl, evicting the least recently used
+// host when the cache is full. Answers with no TTL are not stored.
+func (c *Cache) Put(result *Result, qtypes []uint16, ttl time.Duration) {
+	if result == nil || ttl <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.add(&cacheEntry{Result: result, Types: typeSet(qtypes), Expires: c.nowFunc().Add(ttl)})
+}
+
+// Len returns the number of cached hosts, including expired ones not yet
+// evicted
+func (c *Cache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+// add inserts an entry, called with c.mu held
+func (c *Cache) add(entry *cacheEntry) {
+	key := entry.key()
+	if elem, ok := c.entries[key]; ok {
+		elem.Value = entry
+		c.order.MoveToFront(elem)
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(entry)
+	for c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).key())
+	}
+}
+
+// snapshot returns the live entries from least to most recently used
+func (c *Cache) snapshot() []*cacheEntry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.nowFunc()
+	entries := make([]*cacheEntry, 0, c.order.Len())
+	for elem := c.order.Back(); elem != nil; elem = elem.Prev() {
+		entry := elem.Value.(*cacheEntry)
+		if now.Before(entry.Expires) {
+			entries = append(entries, entry)
+		}
+	}
+
+	return entries
+}
+
+// restore adds entries loaded from disk, skipping the ones that expired and
+// the ones that answer other record types than types
+func (c *Cache) restore(entries []*cacheEntry, types string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.nowFunc()
+	for _, entry := range entries {
+		if entry.Result == nil || entry.Types != types || !now.Before(entry.Expires) {
+			continue
+		}
+		c.add(entry)
+	}
+}
+
+// cacheFile is the on-disk format of a persisted resolver cache
+type cacheFile struct {
+	Positive []*cacheEntry `json:"positive"`
+	Negative []*cacheEntry `json:"negative"`
+}
+
+// SaveCache writes the unexpired answers of both caches to path
+func (r *Resolver) SaveCache(path string) error {
+	data, err := json.Marshal(cacheFile{
+		Positive: r.cache.snapshot(),
+		Negative: r.negative.snapshot(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode DNS cache: %w", err)
+	}
+
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			return fmt.Errorf("failed to create DNS cache directory: %w", err)
+		}
+	}
+
+	// Write to a temporary file first so a crash never leaves a truncated cache
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("failed to write DNS cache: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to write DNS cache: %w", err)
+	}
+
+	return nil
+}
+
+// LoadCache fills the caches with answers saved by SaveCache. Answers to
+// other record types than the resolver asks for are dropped. A missing file
+// is not an error.
+func (r *Resolver) LoadCache(path string) error {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read DNS cache: %w", err)
+	}
+
+	var file cacheFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("failed to parse DNS cache: %w", err)
+	}
+
+	types := typeSet(r.recordTypes)
+	r.cache.restore(file.Positive, types)
+	r.negative.restore(file.Negative, types)
+
+	return nil
+}
+
+// negativeTTL returns how long a response without answers may be cached:
+// the lesser of the SOA record's TTL and its minimum field (RFC 2308).
+// Responses without an SOA record must not be cached.
+func negativeTTL(resp *Message) (uint32, bool) {
+	for _, rr := range resp.Authority {
+		if rr.Type != TypeSOA {
+			continue
+		}
+
+		fields := strings.Fields(rr.Data)
+		if len(fields) != 7 {
+			return 0, false
+		}
+		minimum, err := strconv.ParseUint(fields[6], 10, 32)
+		if err != nil {
+			return 0, false
+		}
+
+		if uint32(minimum) < rr.TTL {
+			return uint32(minimum), true
+		}
+		return rr.TTL, true
+	}
+
+	return 0, false
+}
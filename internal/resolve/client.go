@@ -0,0 +1,219 @@
+package resolve
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"time"
+)
+
+// maxUDPSize is the EDNS0 payload size advertised in queries
+const maxUDPSize = 1232
+
+// Client sends DNS queries to a server. Plain servers are queried over UDP,
+// falling back to TCP when a response is truncated; URL-style servers pick
+// their transport from the scheme (see ParseServer).
+type Client struct {
+	Timeout    time.Duration
+	TLSConfig  *tls.Config  // used for DoT, nil means system defaults
+	HTTPClient *http.Client // used for DoH
+}
+
+// NewClient creates a new DNS client
+func NewClient(timeout time.Duration) *Client {
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+	return &Client{
+		Timeout: timeout,
+		HTTPClient: &http.Client{
+			Timeout: timeout,
+			Transport: &http.Transport{
+				MaxIdleConns:        100,
+				MaxIdleConnsPerHost: 10,
+				IdleConnTimeout:     90 * time.Second,
+				ForceAttemptHTTP2:   true,
+			},
+		},
+	}
+}
+
+// Query sends a recursive query for name and qtype to server
+func (c *Client) Query(ctx context.Context, server, name string, qtype uint16) (*Message, error) {
+	m := NewQuery(name, qtype)
+	m.SetEDNS0(maxUDPSize, false)
+	return c.Exchange(ctx, server, m)
+}
+
+// Exchange sends m to server and returns the response. The message ID is
+// assigned by the client.
+func (c *Client) Exchange(ctx context.Context, server string, m *Message) (*Message, error) {
+	ep, err := ParseServer(server)
+	if err != nil {
+		return nil, err
+	}
+
+	// DoH asks for ID 0 so responses stay cacheable
+	m.ID = randomID()
+	if ep.Scheme == SchemeHTTPS {
+		m.ID = 0
+	}
+
+	req, err := m.Pack()
+	if err != nil {
+		return nil, err
+	}
+
+	var resp *Message
+	switch ep.Scheme {
+	case SchemeUDP:
+		resp, err = c.exchangeUDP(ctx, ep.Addr, req, m.ID)
+		if err == nil && resp.Truncated {
+			resp, err = c.exchangeTCP(ctx, ep.Addr, req, m.ID)
+		}
+	case SchemeTCP:
+		resp, err = c.exchangeTCP(ctx, ep.Addr, req, m.ID)
+	case SchemeTLS:
+		resp, err = c.exchangeTLS(ctx, ep, req, m.ID)
+	case SchemeHTTPS:
+		resp, err = c.exchangeHTTPS(ctx, ep, req, m.ID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkResponse(m, resp); err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+func (c *Client) exchangeUDP(ctx context.Context, server string, req []byte, id uint16) (*Message, error) {
+	conn, err := c.dial(ctx, "udp", server)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if _, err := conn.Write(req); err != nil {
+		return nil, err
+	}
+
+	buf := make([]byte, 65535)
+	for {
+		n, err := conn.Read(buf)
+		if err != nil {
+			return nil, err
+		}
+
+		resp, err := Unpack(buf[:n])
+		if err != nil || resp.ID != id {
+			// Ignore garbage and stale answers, keep waiting for ours
+			continue
+		}
+
+		return resp, nil
+	}
+}
+
+func (c *Client) exchangeTCP(ctx context.Context, server string, req []byte, id uint16) (*Message, error) {
+	conn, err := c.dial(ctx, "tcp", server)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	return exchangeStream(conn, req, id)
+}
+
+// exchangeStream sends a query over an established stream connection
+func exchangeStream(conn net.Conn, req []byte, id uint16) (*Message, error) {
+	if err := writeTCPMessage(conn, req); err != nil {
+		return nil, err
+	}
+
+	data, err := readTCPMessage(conn)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := Unpack(data)
+	if err != nil {
+		return nil, err
+	}
+	if resp.ID != id {
+		return nil, errors.New("dns: response ID mismatch")
+	}
+
+	return resp, nil
+}
+
+// dial opens a connection whose deadline covers the whole exchange
+func (c *Client) dial(ctx context.Context, network, server string) (net.Conn, error) {
+	d := net.Dialer{Timeout: c.Timeout}
+	conn, err := d.DialContext(ctx, network, server)
+	if err != nil {
+		return nil, err
+	}
+
+	deadline := time.Now().Add(c.Timeout)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+	conn.SetDeadline(deadline)
+
+	return conn, nil
+}
+
+// writeTCPMessage writes a length-prefixed DNS message
+func writeTCPMessage(w io.Writer, msg []byte) error {
+	buf := make([]byte, 2, 2+len(msg))
+	binary.BigEndian.PutUint16(buf, uint16(len(msg)))
+	_, err := w.Write(append(buf, msg...))
+	return err
+}
+
+// readTCPMessage reads a length-prefixed DNS message
+func readTCPMessage(r io.Reader) ([]byte, error) {
+	var length [2]byte
+	if _, err := io.ReadFull(r, length[:]); err != nil {
+		return nil, err
+	}
+
+	msg := make([]byte, binary.BigEndian.Uint16(length[:]))
+	if _, err := io.ReadFull(r, msg); err != nil {
+		return nil, err
+	}
+
+	return msg, nil
+}
+
+// checkResponse makes sure resp answers the question asked in req
+func checkResponse(req, resp *Message) error {
+	if !resp.Response {
+		return errors.New("dns: received a query instead of a response")
+	}
+	if len(req.Questions) == 0 || len(resp.Questions) == 0 {
+		return nil
+	}
+
+	q, r := req.Questions[0], resp.Questions[0]
+	if CanonicalName(q.Name) != CanonicalName(r.Name) || q.Type != r.Type {
+		return fmt.Errorf("dns: response for %s %s does not match query", r.Name, TypeString(r.Type))
+	}
+
+	return nil
+}
+
+func randomID() uint16 {
+	var b [2]byte
+	rand.Read(b[:])
+	return binary.BigEndian.Uint16(b[:])
+}
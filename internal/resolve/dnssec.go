@@ -0,0 +1,252 @@
+package resolve
+
+import (
+	"crypto/sha1"
+	"encoding/base32"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// nsec3Encoding is the base32 alphabet of hashed owner names (RFC 4648 "hex")
+var nsec3Encoding = base32.HexEncoding.WithPadding(base32.NoPadding)
+
+// NSEC3 is the parsed data of an NSEC3 record
+type NSEC3 struct {
+	Algorithm  uint8
+	Flags      uint8
+	Iterations uint16
+	Salt       string // hex, empty for no salt
+	NextHash   string // lowercase base32hex
+	Types      []uint16
+}
+
+// ParseNSEC parses NSEC record data ("next.example.com A NS SOA") into the
+// next owner name and the types present at the owner
+func ParseNSEC(data string) (string, []uint16, error) {
+	fields := strings.Fields(data)
+	if len(fields) == 0 {
+		return "", nil, fmt.Errorf("dns: invalid NSEC data %q", data)
+	}
+
+	types, err := parseTypeList(fields[1:])
+	if err != nil {
+		return "", nil, err
+	}
+
+	return CanonicalName(fields[0]), types, nil
+}
+
+// ParseNSEC3 parses NSEC3 record data ("1 0 10 aabbccdd nexthash A RRSIG")
+func ParseNSEC3(data string) (*NSEC3, error) {
+	fields := strings.Fields(data)
+	if len(fields) < 5 {
+		return nil, fmt.Errorf("dns: invalid NSEC3 data %q", data)
+	}
+
+	alg, err1 := strconv.ParseUint(fields[0], 10, 8)
+	flags, err2 := strconv.ParseUint(fields[1], 10, 8)
+	iterations, err3 := strconv.ParseUint(fields[2], 10, 16)
+	if err1 != nil || err2 != nil || err3 != nil {
+		return nil, fmt.Errorf("dns: invalid NSEC3 data %q", data)
+	}
+
+	salt := strings.ToLower(fields[3])
+	if salt == "-" {
+		salt = ""
+	}
+	if _, err := hex.DecodeString(salt); err != nil {
+		return nil, fmt.Errorf("dns: invalid NSEC3 salt %q", fields[3])
+	}
+
+	types, err := parseTypeList(fields[5:])
+	if err != nil {
+		return nil, err
+	}
+
+	return &NSEC3{
+		Algorithm:  uint8(alg),
+		Flags:      uint8(flags),
+		Iterations: uint16(iterations),
+		Salt:       salt,
+		NextHash:   strings.ToLower(fields[4]),
+		Types:      types,
+	}, nil
+}
+
+// HashName computes the NSEC3 hashed owner name of name (RFC 5155, SHA-1)
+// as lowercase base32hex, the form used as the first label of NSEC3 owners
+func HashName(name string, iterations uint16, salt string) (string, error) {
+	saltBytes, err := hex.DecodeString(salt)
+	if err != nil {
+		return "", fmt.Errorf("dns: invalid NSEC3 salt %q", salt)
+	}
+
+	wire, err := packName(nil, name)
+	if err != nil {
+		return "", err
+	}
+
+	h := sha1.Sum(append(wire, saltBytes...))
+	for i := 0; i < int(iterations); i++ {
+		h = sha1.Sum(append(h[:], saltBytes...))
+	}
+
+	return strings.ToLower(nsec3Encoding.EncodeToString(h[:])), nil
+}
+
+func packNSEC(buf []byte, data string) ([]byte, error) {
+	next, types, err := ParseNSEC(data)
+	if err != nil {
+		return nil, err
+	}
+
+	if buf, err = packName(buf, next); err != nil {
+		return nil, err
+	}
+
+	return packTypeBitmap(buf, types), nil
+}
+
+func unpackNSEC(msg []byte, off, rdlen int) (string, error) {
+	next, n, err := unpackName(msg, off)
+	if err != nil {
+		return "", err
+	}
+	if n > off+rdlen {
+		return "", errors.New("dns: invalid NSEC record")
+	}
+
+	types, err := unpackTypeBitmap(msg[n : off+rdlen])
+	if err != nil {
+		return "", err
+	}
+
+	return strings.Join(append([]string{next}, typeNamesOf(types)...), " "), nil
+}
+
+func packNSEC3(buf []byte, data string) ([]byte, error) {
+	rec, err := ParseNSEC3(data)
+	if err != nil {
+		return nil, err
+	}
+
+	salt, _ := hex.DecodeString(rec.Salt)
+	next, err := nsec3Encoding.DecodeString(strings.ToUpper(rec.NextHash))
+	if err != nil {
+		return nil, fmt.Errorf("dns: invalid NSEC3 next hash %q", rec.NextHash)
+	}
+
+	buf = append(buf, rec.Algorithm, rec.Flags)
+	buf = binary.BigEndian.AppendUint16(buf, rec.Iterations)
+	buf = append(buf, byte(len(salt)))
+	buf = append(buf, salt...)
+	buf = append(buf, byte(len(next)))
+	buf = append(buf, next...)
+
+	return packTypeBitmap(buf, rec.Types), nil
+}
+
+func unpackNSEC3(rdata []byte) (string, error) {
+	if len(rdata) < 5 {
+		return "", errors.New("dns: invalid NSEC3 record")
+	}
+
+	saltLen := int(rdata[4])
+	if 5+saltLen+1 > len(rdata) {
+		return "", errors.New("dns: invalid NSEC3 record")
+	}
+	salt := hex.EncodeToString(rdata[5 : 5+saltLen])
+	if salt == "" {
+		salt = "-"
+	}
+
+	off := 5 + saltLen
+	hashLen := int(rdata[off])
+	if off+1+hashLen > len(rdata) {
+		return "", errors.New("dns: invalid NSEC3 record")
+	}
+	next := strings.ToLower(nsec3Encoding.EncodeToString(rdata[off+1 : off+1+hashLen]))
+
+	types, err := unpackTypeBitmap(rdata[off+1+hashLen:])
+	if err != nil {
+		return "", err
+	}
+
+	fields := []string{
+		strconv.Itoa(int(rdata[0])),
+		strconv.Itoa(int(rdata[1])),
+		strconv.Itoa(int(binary.BigEndian.Uint16(rdata[2:]))),
+		salt,
+		next,
+	}
+
+	return strings.Join(append(fields, typeNamesOf(types)...), " "), nil
+}
+
+// packTypeBitmap appends the windowed type bitmap of NSEC and NSEC3 records
+func packTypeBitmap(buf []byte, types []uint16) []byte {
+	sorted := append([]uint16(nil), types...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	for i := 0; i < len(sorted); {
+		window := sorted[i] >> 8
+		var bitmap [32]byte
+		length := 0
+		for ; i < len(sorted) && sorted[i]>>8 == window; i++ {
+			low := sorted[i] & 0xff
+			bitmap[low/8] |= 0x80 >> (low % 8)
+			length = int(low/8) + 1
+		}
+		buf = append(buf, byte(window), byte(length))
+		buf = append(buf, bitmap[:length]...)
+	}
+
+	return buf
+}
+
+// unpackTypeBitmap reads a windowed type bitmap
+func unpackTypeBitmap(data []byte) ([]uint16, error) {
+	var types []uint16
+	for len(data) > 0 {
+		if len(data) < 2 || int(data[1]) > 32 || len(data) < 2+int(data[1]) {
+			return nil, errors.New("dns: invalid type bitmap")
+		}
+
+		window, length := uint16(data[0]), int(data[1])
+		for i, b := range data[2 : 2+length] {
+			for bit := 0; bit < 8; bit++ {
+				if b&(0x80>>bit) != 0 {
+					types = append(types, window<<8|uint16(i*8+bit))
+				}
+			}
+		}
+		data = data[2+length:]
+	}
+
+	return types, nil
+}
+
+func parseTypeList(names []string) ([]uint16, error) {
+	types := make([]uint16, 0, len(names))
+	for _, name := range names {
+		t, err := ParseType(name)
+		if err != nil {
+			return nil, err
+		}
+		types = append(types, t)
+	}
+	return types, nil
+}
+
+func typeNamesOf(types []uint16) []string {
+	names := make([]string, len(types))
+	for i, t := range types {
+		names[i] = TypeString(t)
+	}
+	return names
+}
@@ -0,0 +1,591 @@
+package resolve
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// DNS record types
+const (
+	TypeA      uint16 = 1
+	TypeNS     uint16 = 2
+	TypeCNAME  uint16 = 5
+	TypeSOA    uint16 = 6
+	TypePTR    uint16 = 12
+	TypeMX     uint16 = 15
+	TypeTXT    uint16 = 16
+	TypeAAAA   uint16 = 28
+	TypeSRV    uint16 = 33
+	TypeOPT    uint16 = 41
+	TypeRRSIG  uint16 = 46
+	TypeNSEC   uint16 = 47
+	TypeDNSKEY uint16 = 48
+	TypeNSEC3  uint16 = 50
+	TypeAXFR   uint16 = 252
+)
+
+// ClassINET is the Internet class
+const ClassINET uint16 = 1
+
+// DNS response codes
+const (
+	RcodeSuccess  = 0
+	RcodeFormErr  = 1
+	RcodeServFail = 2
+	RcodeNXDomain = 3
+	RcodeNotImp   = 4
+	RcodeRefused  = 5
+)
+
+var typeNames = map[uint16]string{
+	TypeA:      "A",
+	TypeNS:     "NS",
+	TypeCNAME:  "CNAME",
+	TypeSOA:    "SOA",
+	TypePTR:    "PTR",
+	TypeMX:     "MX",
+	TypeTXT:    "TXT",
+	TypeAAAA:   "AAAA",
+	TypeSRV:    "SRV",
+	TypeOPT:    "OPT",
+	TypeRRSIG:  "RRSIG",
+	TypeNSEC:   "NSEC",
+	TypeDNSKEY: "DNSKEY",
+	TypeNSEC3:  "NSEC3",
+	TypeAXFR:   "AXFR",
+}
+
+var rcodeNames = map[int]string{
+	RcodeSuccess:  "NOERROR",
+	RcodeFormErr:  "FORMERR",
+	RcodeServFail: "SERVFAIL",
+	RcodeNXDomain: "NXDOMAIN",
+	RcodeNotImp:   "NOTIMP",
+	RcodeRefused:  "REFUSED",
+}
+
+// TypeString returns the mnemonic of a record type
+func TypeString(t uint16) string {
+	if name, ok := typeNames[t]; ok {
+		return name
+	}
+	return "TYPE" + strconv.Itoa(int(t))
+}
+
+// ParseType parses a record type mnemonic such as "AAAA"
+func ParseType(s string) (uint16, error) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	for t, name := range typeNames {
+		if name == s {
+			return t, nil
+		}
+	}
+	if n, err := strconv.Atoi(strings.TrimPrefix(s, "TYPE")); err == nil && n > 0 && n < 65536 {
+		return uint16(n), nil
+	}
+	return 0, fmt.Errorf("unknown record type: %s", s)
+}
+
+// RcodeString returns the mnemonic of a response code
+func RcodeString(rcode int) string {
+	if name, ok := rcodeNames[rcode]; ok {
+		return name
+	}
+	return "RCODE" + strconv.Itoa(rcode)
+}
+
+// Header holds the DNS message header flags
+type Header struct {
+	ID                 uint16
+	Response           bool
+	Opcode             int
+	Authoritative      bool
+	Truncated          bool
+	RecursionDesired   bool
+	RecursionAvailable bool
+	Rcode              int
+}
+
+// Question is an entry of the question section
+type Question struct {
+	Name  string
+	Type  uint16
+	Class uint16
+}
+
+// RR is a resource record. Data holds the record data in presentation
+// format, e.g. "192.0.2.1" for A or "10 mail.example.com" for MX. Types
+// without a known presentation format carry their raw data hex encoded.
+type RR struct {
+	Name  string
+	Type  uint16
+	Class uint16
+	TTL   uint32
+	Data  string
+}
+
+// String returns the record in zone file format
+func (rr RR) String() string {
+	return fmt.Sprintf("%s\t%d\t%s\t%s", rr.Name, rr.TTL, TypeString(rr.Type), rr.Data)
+}
+
+// Message is a DNS message
+type Message struct {
+	Header
+	Questions  []Question
+	Answers    []RR
+	Authority  []RR
+	Additional []RR
+}
+
+// NewQuery builds a recursive query for name and qtype
+func NewQuery(name string, qtype uint16) *Message {
+	return &Message{
+		Header: Header{
+			RecursionDesired: true,
+		},
+		Questions: []Question{
+			{Name: CanonicalName(name), Type: qtype, Class: ClassINET},
+		},
+	}
+}
+
+// SetEDNS0 adds an OPT record advertising the UDP payload size and, when
+// dnssec is set, the DO bit
+func (m *Message) SetEDNS0(udpSize uint16, dnssec bool) {
+	var ttl uint32
+	if dnssec {
+		ttl = 0x8000
+	}
+	m.Additional = append(m.Additional, RR{
+		Name:  ".",
+		Type:  TypeOPT,
+		Class: udpSize,
+		TTL:   ttl,
+	})
+}
+
+// Reply builds an empty response to m, used by servers and tests
+func (m *Message) Reply() *Message {
+	return &Message{
+		Header: Header{
+			ID:               m.ID,
+			Response:         true,
+			Opcode:           m.Opcode,
+			RecursionDesired: m.RecursionDesired,
+		},
+		Questions: append([]Question(nil), m.Questions...),
+	}
+}
+
+// CanonicalName lowercases a domain name and strips the trailing dot
+func CanonicalName(name string) string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "." || name == "" {
+		return "."
+	}
+	return strings.TrimSuffix(name, ".")
+}
+
+var errShortMessage = errors.New("dns: message too short")
+
+// Pack encodes the message into wire format
+func (m *Message) Pack() ([]byte, error) {
+	buf := make([]byte, 12, 512)
+
+	binary.BigEndian.PutUint16(buf[0:], m.ID)
+	var flags uint16
+	if m.Response {
+		flags |= 1 << 15
+	}
+	flags |= uint16(m.Opcode&0xf) << 11
+	if m.Authoritative {
+		flags |= 1 << 10
+	}
+	if m.Truncated {
+		flags |= 1 << 9
+	}
+	if m.RecursionDesired {
+		flags |= 1 << 8
+	}
+	if m.RecursionAvailable {
+		flags |= 1 << 7
+	}
+	flags |= uint16(m.Rcode & 0xf)
+	binary.BigEndian.PutUint16(buf[2:], flags)
+	binary.BigEndian.PutUint16(buf[4:], uint16(len(m.Questions)))
+	binary.BigEndian.PutUint16(buf[6:], uint16(len(m.Answers)))
+	binary.BigEndian.PutUint16(buf[8:], uint16(len(m.Authority)))
+	binary.BigEndian.PutUint16(buf[10:], uint16(len(m.Additional)))
+
+	var err error
+	for _, q := range m.Questions {
+		if buf, err = packName(buf, q.Name); err != nil {
+			return nil, err
+		}
+		buf = binary.BigEndian.AppendUint16(buf, q.Type)
+		buf = binary.BigEndian.AppendUint16(buf, q.Class)
+	}
+
+	for _, section := range [][]RR{m.Answers, m.Authority, m.Additional} {
+		for _, rr := range section {
+			if buf, err = packRR(buf, rr); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return buf, nil
+}
+
+// Unpack decodes a message from wire format
+func Unpack(msg []byte) (*Message, error) {
+	if len(msg) < 12 {
+		return nil, errShortMessage
+	}
+
+	flags := binary.BigEndian.Uint16(msg[2:])
+	m := &Message{
+		Header: Header{
+			ID:                 binary.BigEndian.Uint16(msg[0:]),
+			Response:           flags&(1<<15) != 0,
+			Opcode:             int(flags>>11) & 0xf,
+			Authoritative:      flags&(1<<10) != 0,
+			Truncated:          flags&(1<<9) != 0,
+			RecursionDesired:   flags&(1<<8) != 0,
+			RecursionAvailable: flags&(1<<7) != 0,
+			Rcode:              int(flags & 0xf),
+		},
+	}
+
+	qdcount := int(binary.BigEndian.Uint16(msg[4:]))
+	counts := []int{
+		int(binary.BigEndian.Uint16(msg[6:])),
+		int(binary.BigEndian.Uint16(msg[8:])),
+		int(binary.BigEndian.Uint16(msg[10:])),
+	}
+
+	off := 12
+	for i := 0; i < qdcount; i++ {
+		name, n, err := unpackName(msg, off)
+		if err != nil {
+			return nil, err
+		}
+		off = n
+		if off+4 > len(msg) {
+			return nil, errShortMessage
+		}
+		m.Questions = append(m.Questions, Question{
+			Name:  name,
+			Type:  binary.BigEndian.Uint16(msg[off:]),
+			Class: binary.BigEndian.Uint16(msg[off+2:]),
+		})
+		off += 4
+	}
+
+	sections := []*[]RR{&m.Answers, &m.Authority, &m.Additional}
+	for i, count := range counts {
+		for j := 0; j < count; j++ {
+			rr, n, err := unpackRR(msg, off)
+			if err != nil {
+				return nil, err
+			}
+			off = n
+			*sections[i] = append(*sections[i], rr)
+		}
+	}
+
+	// The extended rcode lives in the OPT record
+	for _, rr := range m.Additional {
+		if rr.Type == TypeOPT {
+			m.Rcode |= int(rr.TTL>>24) << 4
+		}
+	}
+
+	return m, nil
+}
+
+// packName appends a domain name without compression
+func packName(buf []byte, name string) ([]byte, error) {
+	name = CanonicalName(name)
+	if name == "." {
+		return append(buf, 0), nil
+	}
+
+	for _, label := range strings.Split(name, ".") {
+		if len(label) == 0 || len(label) > 63 {
+			return nil, fmt.Errorf("dns: invalid label in %q", name)
+		}
+		buf = append(buf, byte(len(label)))
+		buf = append(buf, label...)
+	}
+
+	return append(buf, 0), nil
+}
+
+// unpackName reads a possibly compressed domain name at off and returns it
+// with the offset following it
+func unpackName(msg []byte, off int) (string, int, error) {
+	var labels []string
+	end := -1
+	hops := 0
+
+	for {
+		if off >= len(msg) {
+			return "", 0, errShortMessage
+		}
+		c := int(msg[off])
+
+		switch c & 0xc0 {
+		case 0x00:
+			if c == 0 {
+				if end < 0 {
+					end = off + 1
+				}
+				if len(labels) == 0 {
+					return ".", end, nil
+				}
+				return strings.ToLower(strings.Join(labels, ".")), end, nil
+			}
+			if off+1+c > len(msg) {
+				return "", 0, errShortMessage
+			}
+			labels = append(labels, string(msg[off+1:off+1+c]))
+			off += 1 + c
+		case 0xc0:
+			if off+1 >= len(msg) {
+				return "", 0, errShortMessage
+			}
+			if end < 0 {
+				end = off + 2
+			}
+			hops++
+			if hops > 64 {
+				return "", 0, errors.New("dns: too many compression pointers")
+			}
+			off = int(binary.BigEndian.Uint16(msg[off:]) & 0x3fff)
+		default:
+			return "", 0, errors.New("dns: invalid label type")
+		}
+	}
+}
+
+func packRR(buf []byte, rr RR) ([]byte, error) {
+	var err error
+	if buf, err = packName(buf, rr.Name); err != nil {
+		return nil, err
+	}
+
+	class := rr.Class
+	if class == 0 {
+		class = ClassINET
+	}
+	buf = binary.BigEndian.AppendUint16(buf, rr.Type)
+	buf = binary.BigEndian.AppendUint16(buf, class)
+	buf = binary.BigEndian.AppendUint32(buf, rr.TTL)
+
+	lenOff := len(buf)
+	buf = append(buf, 0, 0)
+	if buf, err = packRData(buf, rr); err != nil {
+		return nil, err
+	}
+	binary.BigEndian.PutUint16(buf[lenOff:], uint16(len(buf)-lenOff-2))
+
+	return buf, nil
+}
+
+// packRData appends the record data parsed from its presentation format
+func packRData(buf []byte, rr RR) ([]byte, error) {
+	fields := strings.Fields(rr.Data)
+
+	switch rr.Type {
+	case TypeA:
+		ip := net.ParseIP(rr.Data).To4()
+		if ip == nil {
+			return nil, fmt.Errorf("dns: invalid A data %q", rr.Data)
+		}
+		return append(buf, ip...), nil
+	case TypeAAAA:
+		ip := net.ParseIP(rr.Data)
+		if ip == nil || ip.To4() != nil {
+			return nil, fmt.Errorf("dns: invalid AAAA data %q", rr.Data)
+		}
+		return append(buf, ip.To16()...), nil
+	case TypeNS, TypeCNAME, TypePTR:
+		return packName(buf, rr.Data)
+	case TypeMX:
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("dns: invalid MX data %q", rr.Data)
+		}
+		pref, err := strconv.ParseUint(fields[0], 10, 16)
+		if err != nil {
+			return nil, fmt.Errorf("dns: invalid MX data %q", rr.Data)
+		}
+		buf = binary.BigEndian.AppendUint16(buf, uint16(pref))
+		return packName(buf, fields[1])
+	case TypeTXT:
+		txt := rr.Data
+		if len(txt) == 0 {
+			return append(buf, 0), nil
+		}
+		for len(txt) > 0 {
+			n := len(txt)
+			if n > 255 {
+				n = 255
+			}
+			buf = append(buf, byte(n))
+			buf = append(buf, txt[:n]...)
+			txt = txt[n:]
+		}
+		return buf, nil
+	case TypeSOA:
+		if len(fields) != 7 {
+			return nil, fmt.Errorf("dns: invalid SOA data %q", rr.Data)
+		}
+		var err error
+		if buf, err = packName(buf, fields[0]); err != nil {
+			return nil, err
+		}
+		if buf, err = packName(buf, fields[1]); err != nil {
+			return nil, err
+		}
+		for _, f := range fields[2:] {
+			v, err := strconv.ParseUint(f, 10, 32)
+			if err != nil {
+				return nil, fmt.Errorf("dns: invalid SOA data %q", rr.Data)
+			}
+			buf = binary.BigEndian.AppendUint32(buf, uint32(v))
+		}
+		return buf, nil
+	case TypeSRV:
+		if len(fields) != 4 {
+			return nil, fmt.Errorf("dns: invalid SRV data %q", rr.Data)
+		}
+		for _, f := range fields[:3] {
+			v, err := strconv.ParseUint(f, 10, 16)
+			if err != nil {
+				return nil, fmt.Errorf("dns: invalid SRV data %q", rr.Data)
+			}
+			buf = binary.BigEndian.AppendUint16(buf, uint16(v))
+		}
+		return packName(buf, fields[3])
+	case TypeNSEC:
+		return packNSEC(buf, rr.Data)
+	case TypeNSEC3:
+		return packNSEC3(buf, rr.Data)
+	default:
+		raw, err := hex.DecodeString(rr.Data)
+		if err != nil {
+			return nil, fmt.Errorf("dns: invalid %s data %q", TypeString(rr.Type), rr.Data)
+		}
+		return append(buf, raw...), nil
+	}
+}
+
+func unpackRR(msg []byte, off int) (RR, int, error) {
+	name, off, err := unpackName(msg, off)
+	if err != nil {
+		return RR{}, 0, err
+	}
+	if off+10 > len(msg) {
+		return RR{}, 0, errShortMessage
+	}
+
+	rr := RR{
+		Name:  name,
+		Type:  binary.BigEndian.Uint16(msg[off:]),
+		Class: binary.BigEndian.Uint16(msg[off+2:]),
+		TTL:   binary.BigEndian.Uint32(msg[off+4:]),
+	}
+	rdlen := int(binary.BigEndian.Uint16(msg[off+8:]))
+	off += 10
+	if off+rdlen > len(msg) {
+		return RR{}, 0, errShortMessage
+	}
+
+	rr.Data, err = unpackRData(msg, off, rdlen, rr.Type)
+	if err != nil {
+		return RR{}, 0, err
+	}
+
+	return rr, off + rdlen, nil
+}
+
+// unpackRData renders record data in presentation format. Names inside the
+// data may point anywhere in the message, so the full message is needed.
+func unpackRData(msg []byte, off, rdlen int, rtype uint16) (string, error) {
+	rdata := msg[off : off+rdlen]
+
+	switch rtype {
+	case TypeA:
+		if rdlen != net.IPv4len {
+			return "", errors.New("dns: invalid A record")
+		}
+		return net.IP(rdata).String(), nil
+	case TypeAAAA:
+		if rdlen != net.IPv6len {
+			return "", errors.New("dns: invalid AAAA record")
+		}
+		return net.IP(rdata).String(), nil
+	case TypeNS, TypeCNAME, TypePTR:
+		name, _, err := unpackName(msg, off)
+		return name, err
+	case TypeMX:
+		if rdlen < 3 {
+			return "", errors.New("dns: invalid MX record")
+		}
+		name, _, err := unpackName(msg, off+2)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%d %s", binary.BigEndian.Uint16(rdata), name), nil
+	case TypeTXT:
+		var sb strings.Builder
+		for i := 0; i < len(rdata); {
+			n := int(rdata[i])
+			if i+1+n > len(rdata) {
+				return "", errors.New("dns: invalid TXT record")
+			}
+			sb.Write(rdata[i+1 : i+1+n])
+			i += 1 + n
+		}
+		return sb.String(), nil
+	case TypeSOA:
+		mname, n, err := unpackName(msg, off)
+		if err != nil {
+			return "", err
+		}
+		rname, n, err := unpackName(msg, n)
+		if err != nil {
+			return "", err
+		}
+		if n+20 > off+rdlen {
+			return "", errors.New("dns: invalid SOA record")
+		}
+		return fmt.Sprintf("%s %s %d %d %d %d %d", mname, rname,
+			binary.BigEndian.Uint32(msg[n:]), binary.BigEndian.Uint32(msg[n+4:]),
+			binary.BigEndian.Uint32(msg[n+8:]), binary.BigEndian.Uint32(msg[n+12:]),
+			binary.BigEndian.Uint32(msg[n+16:])), nil
+	case TypeSRV:
+		if rdlen < 7 {
+			return "", errors.New("dns: invalid SRV record")
+		}
+		name, _, err := unpackName(msg, off+6)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%d %d %d %s", binary.BigEndian.Uint16(rdata),
+			binary.BigEndian.Uint16(rdata[2:]), binary.BigEndian.Uint16(rdata[4:]), name), nil
+	case TypeNSEC:
+		return unpackNSEC(msg, off, rdlen)
+	case TypeNSEC3:
+		return unpackNSEC3(rdata)
+	default:
+		return hex.EncodeToString(rdata), nil
+	}
+}
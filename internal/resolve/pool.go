@@ -0,0 +1,261 @@
+package resolve
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// minSamples is the number of queries needed before a server's error
+	// rate is trusted enough to evict it
+	minSamples = 10
+	// maxErrorRate is the error rate above which a server is evicted
+	maxErrorRate = 0.5
+	// maxConsecutiveErrors evicts a server that fails this many times in a row
+	maxConsecutiveErrors = 5
+	// defaultEviction is how long a failing server is kept out of rotation
+	defaultEviction = 30 * time.Second
+	// latencyWeight is the EWMA weight given to the newest latency sample
+	latencyWeight = 0.2
+)
+
+// Pool distributes queries across DNS servers and tracks their health
+type Pool struct {
+	servers  []*poolServer
+	next     int
+	eviction time.Duration
+	mu       sync.Mutex
+	nowFunc  func() time.Time
+}
+
+type poolServer struct {
+	addr         string
+	queries      int
+	errors       int
+	consecutive  int
+	latency      time.Duration
+	evictedUntil time.Time
+	banned       bool
+}
+
+// ServerStats holds health information about a pooled server
+type ServerStats struct {
+	Addr    string
+	Queries int
+	Errors  int
+	Latency time.Duration
+	Evicted bool
+	Banned  bool
+}
+
+// NewPool creates a pool from a list of server addresses
+func NewPool(servers []string) *Pool {
+	p := &Pool{
+		servers:  make([]*poolServer, 0, len(servers)),
+		eviction: defaultEviction,
+		nowFunc:  time.Now,
+	}
+
+	seen := make(map[string]bool)
+	for _, addr := range servers {
+		addr = NormalizeServer(addr)
+		if addr == "" || seen[addr] {
+			continue
+		}
+		seen[addr] = true
+		p.servers = append(p.servers, &poolServer{addr: addr})
+	}
+
+	return p
+}
+
+// SetClock overrides the time source, used by tests
+func (p *Pool) SetClock(now func() time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.nowFunc = now
+}
+
+// SetEviction sets how long failing servers are kept out of rotation
+func (p *Pool) SetEviction(d time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.eviction = d
+}
+
+// Size returns the number of servers in the pool
+func (p *Pool) Size() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return len(p.servers)
+}
+
+// Next returns the next healthy server in round-robin order. If every server
+// is evicted, the one whose eviction expires first is returned so queries can
+// still make progress. It returns an empty string for an empty pool.
+func (p *Pool) Next() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if len(p.servers) == 0 {
+		return ""
+	}
+
+	now := p.nowFunc()
+	for i := 0; i < len(p.servers); i++ {
+		s := p.servers[p.next]
+		p.next = (p.next + 1) % len(p.servers)
+		if !s.banned && !now.Before(s.evictedUntil) {
+			return s.addr
+		}
+	}
+
+	// Everything is evicted, fall back to the server closest to recovery
+	var best *poolServer
+	for _, s := range p.servers {
+		if s.banned {
+			continue
+		}
+		if best == nil || s.evictedUntil.Before(best.evictedUntil) {
+			best = s
+		}
+	}
+	if best == nil {
+		return ""
+	}
+
+	return best.addr
+}
+
+// Report records the outcome of a query sent to addr
+func (p *Pool) Report(addr string, latency time.Duration, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	s := p.find(addr)
+	if s == nil {
+		return
+	}
+
+	s.queries++
+	if err != nil {
+		s.errors++
+		s.consecutive++
+	} else {
+		s.consecutive = 0
+		if s.latency == 0 {
+			s.latency = latency
+		} else {
+			s.latency = time.Duration(latencyWeight*float64(latency) + (1-latencyWeight)*float64(s.latency))
+		}
+	}
+
+	errorRate := float64(s.errors) / float64(s.queries)
+	if s.consecutive >= maxConsecutiveErrors || (s.queries >= minSamples && errorRate > maxErrorRate) {
+		s.evictedUntil = p.nowFunc().Add(p.eviction)
+		// Start over once the server comes back
+		s.queries, s.errors, s.consecutive = 0, 0, 0
+	}
+}
+
+// Ban permanently removes a server from rotation, e.g. because it lies
+func (p *Pool) Ban(addr string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if s := p.find(addr); s != nil {
+		s.banned = true
+	}
+}
+
+// Servers returns a copy of the addresses of all servers in the pool
+func (p *Pool) Servers() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	addrs := make([]string, len(p.servers))
+	for i, s := range p.servers {
+		addrs[i] = s.addr
+	}
+	return addrs
+}
+
+// Stats returns health information for every server in the pool
+func (p *Pool) Stats() []ServerStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := p.nowFunc()
+	stats := make([]ServerStats, len(p.servers))
+	for i, s := range p.servers {
+		stats[i] = ServerStats{
+			Addr:    s.addr,
+			Queries: s.queries,
+			Errors:  s.errors,
+			Latency: s.latency,
+			Evicted: now.Before(s.evictedUntil),
+			Banned:  s.banned,
+		}
+	}
+
+	return stats
+}
+
+func (p *Pool) find(addr string) *poolServer {
+	for _, s := range p.servers {
+		if s.addr == addr {
+			return s
+		}
+	}
+	return nil
+}
+
+// NormalizeServer returns the canonical form of a server entry, adding the
+// transport's default port where missing. Invalid entries are returned as is
+// and fail when queried.
+func NormalizeServer(addr string) string {
+	addr = strings.TrimSpace(addr)
+	if addr == "" {
+		return ""
+	}
+
+	ep, err := ParseServer(addr)
+	if err != nil {
+		return addr
+	}
+
+	return ep.String()
+}
+
+// LoadServers reads resolver addresses from a file, one per line
+func LoadServers(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open resolver list: %w", err)
+	}
+	defer file.Close()
+
+	servers := make([]string, 0)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if _, err := ParseServer(line); err != nil {
+			return nil, err
+		}
+		servers = append(servers, NormalizeServer(line))
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read resolver list: %w", err)
+	}
+
+	return servers, nil
+}
@@ -0,0 +1,60 @@
+package resolve
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// ReverseName returns the in-addr.arpa or ip6.arpa name of an address
+func ReverseName(ip string) (string, error) {
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return "", fmt.Errorf("invalid IP address %q", ip)
+	}
+
+	if v4 := addr.To4(); v4 != nil {
+		return fmt.Sprintf("%d.%d.%d.%d.in-addr.arpa", v4[3], v4[2], v4[1], v4[0]), nil
+	}
+
+	const hexDigits = "0123456789abcdef"
+	var sb strings.Builder
+	v6 := addr.To16()
+	for i := len(v6) - 1; i >= 0; i-- {
+		sb.WriteByte(hexDigits[v6[i]&0x0f])
+		sb.WriteByte('.')
+		sb.WriteByte(hexDigits[v6[i]>>4])
+		sb.WriteByte('.')
+	}
+	sb.WriteString("ip6.arpa")
+
+	return sb.String(), nil
+}
+
+// LookupPTR returns the names an address points back to. Lookups go through
+// the pool but are not cached.
+func (r *Resolver) LookupPTR(ctx context.Context, ip string) ([]string, error) {
+	name, err := ReverseName(ip)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := r.query(ctx, r.pool, name, TypePTR)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Rcode != RcodeSuccess && resp.Rcode != RcodeNXDomain {
+		return nil, fmt.Errorf("dns: %s", RcodeString(resp.Rcode))
+	}
+
+	// Classless delegations (RFC 2317) reach the PTR through a CNAME
+	names := make([]string, 0)
+	for _, rr := range resp.Answers {
+		if rr.Type == TypePTR {
+			names = append(names, rr.Data)
+		}
+	}
+
+	return names, nil
+}
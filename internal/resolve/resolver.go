@@ -5,24 +5,40 @@ import (
 	"crypto/rand"
 	"fmt"
 	"net"
+	"os"
 	"strings"
 	"sync"
 	"time"
+
+	"golang.org/x/time/rate"
 )
 
 // Resolver handles DNS resolution with caching and wildcard detection
 type Resolver struct {
-	servers   []string
-	timeout   time.Duration
-	cache     *sync.Map
-	wildcards map[string][]string // domain -> wildcard IPs
-	mu        sync.RWMutex
+	pool        *Pool
+	trusted     *Pool // servers used to validate positive answers
+	client      *Client
+	timeout     time.Duration
+	retries     int
+	recordTypes []uint16
+	cache       *Cache                // positive answers
+	negative    *Cache                // NXDOMAIN and NODATA answers, kept apart so misses never evict hits
+	zones       map[string]*zoneState // zone -> wildcard fingerprint
+	limiter     *rate.Limiter         // queries per second across every lookup
+	throttle    *Throttle             // adaptive bound on queries in flight
+	mu          sync.RWMutex
 }
 
 // Config holds resolver configuration
 type Config struct {
-	Servers []string
-	Timeout time.Duration
+	Servers        []string // empty means the servers from /etc/resolv.conf
+	TrustedServers []string // servers used to re-check positive answers
+	Timeout        time.Duration
+	Retries        int      // servers tried per query, defaults to 3
+	RecordTypes    []uint16 // record types to query, defaults to A and AAAA
+	RateLimit      int      // queries per second across every lookup, 0 means unlimited
+	MaxInFlight    int      // most queries in flight, adapted to the loss rate; 0 means unbounded
+	CacheSize      int      // hosts kept in each of the positive and negative caches
 }
 
 // NewResolver creates a new DNS resolver
@@ -34,199 +50,479 @@ func NewResolver(config *Config) *Resolver {
 		}
 	}
 	
-	return &Resolver{
-		servers:   config.Servers,
-		timeout:   config.Timeout,
-		cache:     &sync.Map{},
-		wildcards: make(map[string][]string),
+	servers := config.Servers
+	if len(servers) == 0 {
+		servers = SystemServers()
+	}
+	
+	retries := config.Retries
+	if retries <= 0 {
+		retries = 3
+	}
+	
+	r := &Resolver{
+		pool:        NewPool(servers),
+		trusted:     NewPool(config.TrustedServers),
+		client:      NewClient(config.Timeout),
+		timeout:     config.Timeout,
+		retries:     retries,
+		recordTypes: config.RecordTypes,
+		cache:       NewCache(config.CacheSize),
+		negative:    NewCache(config.CacheSize),
+		zones:       make(map[string]*zoneState),
+	}
+	
+	if len(r.recordTypes) == 0 {
+		r.recordTypes = []uint16{TypeA, TypeAAAA}
+	}
+	
+	if config.RateLimit > 0 {
+		r.limiter = rate.NewLimiter(rate.Limit(config.RateLimit), 1)
+	}
+	if config.MaxInFlight > 0 {
+		r.throttle = NewThrottle(config.MaxInFlight)
 	}
+	
+	return r
 }
 
 // Result holds DNS resolution result
 type Result struct {
-	Host   string
-	IPs    []string
-	Exists bool
-	Error  error
+	Host    string   `json:"host"`
+	IPs     []string `json:"ips,omitempty"`
+	Exists  bool     `json:"exists"`
+	Error   error    `json:"-"`
+	Rcode   int      `json:"rcode"`             // response code of the answer
+	Records []RR     `json:"records,omitempty"` // full answer section, including CNAME chains
+	
+	ttl time.Duration // how long the answer may be cached, 0 if it must not be
 }
 
 // Resolve resolves a subdomain to IP addresses
 func (r *Resolver) Resolve(ctx context.Context, subdomain string) (*Result, error) {
 	// Check cache
-	if cached, ok := r.cache.Load(subdomain); ok {
-		return cached.(*Result), nil
+	if cached, ok := r.cache.Get(subdomain, r.recordTypes); ok {
+		return cached, nil
+	}
+	if cached, ok := r.negative.Get(subdomain, r.recordTypes); ok {
+		return cached, nil
 	}
 	
-	// Create resolver with timeout
-	resolver := &net.Resolver{
-		PreferGo: true,
-		Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
-			d := net.Dialer{
-				Timeout: r.timeout,
-			}
-			// Use custom DNS servers if provided
-			if len(r.servers) > 0 {
-				return d.DialContext(ctx, network, r.servers[0])
-			}
-			return d.DialContext(ctx, network, address)
-		},
+	result, err := r.lookup(ctx, r.pool, subdomain)
+	if err != nil {
+		return nil, err
 	}
 	
-	// Resolve with retry
-	var ips []string
-	var lastErr error
+	// Cache result
+	if result.Exists {
+		r.cache.Put(result, r.recordTypes, result.ttl)
+	} else {
+		r.negative.Put(result, r.recordTypes, result.ttl)
+	}
 	
-	for attempt := 0; attempt < 3; attempt++ {
-		addrs, err := resolver.LookupHost(ctx, subdomain)
-		if err == nil {
-			ips = addrs
-			break
+	return result, nil
+}
+
+// lookup resolves a host against the given pool, bypassing the cache
+func (r *Resolver) lookup(ctx context.Context, pool *Pool, subdomain string) (*Result, error) {
+	result := &Result{
+		Host: subdomain,
+	}
+	
+	// Ask for every configured record type in parallel
+	qtypes := r.recordTypes
+	responses := make([]*Message, len(qtypes))
+	errs := make([]error, len(qtypes))
+	var wg sync.WaitGroup
+	for i, qtype := range qtypes {
+		wg.Add(1)
+		go func(i int, qtype uint16) {
+			defer wg.Done()
+			responses[i], errs[i] = r.query(ctx, pool, subdomain, qtype)
+		}(i, qtype)
+	}
+	wg.Wait()
+	
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	
+	answered := false
+	cacheable := true
+	var ttl uint32
+	seen := make(map[RR]bool)
+	for i, resp := range responses {
+		if resp == nil {
+			if result.Error == nil {
+				result.Error = errs[i]
+			}
+			cacheable = false
+			continue
 		}
 		
-		lastErr = err
+		// The answer may be cached for the lowest TTL of any response;
+		// timeouts and server failures are never cached
+		respTTL, ok := responseTTL(resp)
+		if !ok {
+			cacheable = false
+		} else if !answered || respTTL < ttl {
+			ttl = respTTL
+		}
 		
-		// Check if it's a timeout or temporary error
-		if netErr, ok := err.(net.Error); ok && netErr.Temporary() {
-			// Exponential backoff
-			if attempt < 2 {
-				select {
-				case <-ctx.Done():
-					return nil, ctx.Err()
-				case <-time.After(time.Duration(attempt+1) * time.Second):
-				}
+		// Success beats NXDOMAIN, which beats any failure, so the outcome
+		// doesn't depend on which type failed or answered first
+		if !answered || rcodeRank(resp.Rcode) < rcodeRank(result.Rcode) {
+			result.Rcode = resp.Rcode
+		}
+		answered = true
+		
+		for _, rr := range resp.Answers {
+			if seen[rr] {
 				continue
 			}
+			seen[rr] = true
+			result.Records = append(result.Records, rr)
+			if rr.Type == TypeA || rr.Type == TypeAAAA {
+				result.IPs = append(result.IPs, rr.Data)
+			}
 		}
-		
-		// Non-temporary error, don't retry
-		break
 	}
 	
-	result := &Result{
-		Host:   subdomain,
-		IPs:    ips,
-		Exists: len(ips) > 0,
-		Error:  lastErr,
+	if answered {
+		result.Error = nil
+		if result.Rcode != RcodeSuccess && result.Rcode != RcodeNXDomain {
+			result.Error = fmt.Errorf("dns: %s", RcodeString(result.Rcode))
+		}
+	}
+	if cacheable && result.Error == nil {
+		result.ttl = time.Duration(ttl) * time.Second
 	}
 	
-	// Cache result
-	r.cache.Store(subdomain, result)
+	// A host exists if it answered one of the requested types; a bare CNAME
+	// only counts when CNAME records were asked for
+	for _, rr := range result.Records {
+		if r.wantsType(rr.Type) {
+			result.Exists = true
+			break
+		}
+	}
 	
 	return result, nil
 }
 
-// DetectWildcard detects if a domain has wildcard DNS
-func (r *Resolver) DetectWildcard(ctx context.Context, domain string) (bool, []string, error) {
-	// Check if already detected
-	r.mu.RLock()
-	if ips, exists := r.wildcards[domain]; exists {
-		r.mu.RUnlock()
-		return true, ips, nil
-	}
-	r.mu.RUnlock()
-	
-	// Generate 3 random subdomains
-	randomSubdomains := make([]string, 3)
-	for i := 0; i < 3; i++ {
-		random := generateRandomString(16)
-		randomSubdomains[i] = fmt.Sprintf("%s.%s", random, domain)
-	}
-	
-	// Resolve random subdomains
-	var resolvedIPs [][]string
-	for _, subdomain := range randomSubdomains {
-		result, err := r.Resolve(ctx, subdomain)
-		if err == nil && result.Exists {
-			resolvedIPs = append(resolvedIPs, result.IPs)
+// rcodeRank orders rcodes for merging the responses for several types
+func rcodeRank(rcode int) int {
+	switch rcode {
+	case RcodeSuccess:
+		return 0
+	case RcodeNXDomain:
+		return 1
+	}
+	return 2
+}
+
+// responseTTL returns how long a response may be cached: the lowest answer
+// TTL, or the negative caching TTL for responses without answers
+func responseTTL(resp *Message) (uint32, bool) {
+	if resp.Rcode != RcodeSuccess && resp.Rcode != RcodeNXDomain {
+		return 0, false
+	}
+	if len(resp.Answers) == 0 {
+		return negativeTTL(resp)
+	}
+	
+	ttl := resp.Answers[0].TTL
+	for _, rr := range resp.Answers[1:] {
+		if rr.TTL < ttl {
+			ttl = rr.TTL
 		}
 	}
 	
-	// If 2 or more random subdomains resolve, it's likely a wildcard
-	if len(resolvedIPs) >= 2 {
-		// Find common IPs
-		commonIPs := findCommonIPs(resolvedIPs)
-		
-		if len(commonIPs) > 0 {
-			r.mu.Lock()
-			r.wildcards[domain] = commonIPs
-			r.mu.Unlock()
-			
-			return true, commonIPs, nil
+	return ttl, true
+}
+
+// wantsType reports whether qtype is one of the configured record types
+func (r *Resolver) wantsType(qtype uint16) bool {
+	for _, t := range r.recordTypes {
+		if t == qtype {
+			return true
 		}
 	}
+	return false
+}
+
+// RecordsByType groups the answer records by type mnemonic
+func (res *Result) RecordsByType() map[string][]RR {
+	records := make(map[string][]RR)
+	for _, rr := range res.Records {
+		name := TypeString(rr.Type)
+		records[name] = append(records[name], rr)
+	}
+	return records
+}
+
+// CNAMEChain returns the CNAME targets followed from the host, in order
+func (res *Result) CNAMEChain() []string {
+	var chain []string
+	name := CanonicalName(res.Host)
+	
+	for hops := 0; hops < 16; hops++ {
+		next := ""
+		for _, rr := range res.Records {
+			if rr.Type == TypeCNAME && rr.Name == name {
+				next = rr.Data
+				break
+			}
+		}
+		if next == "" {
+			break
+		}
+		chain = append(chain, next)
+		name = next
+	}
 	
-	return false, nil, nil
+	return chain
 }
 
-// IsWildcard checks if a subdomain resolves to wildcard IPs
-func (r *Resolver) IsWildcard(subdomain string, domain string) bool {
-	r.mu.RLock()
-	wildcardIPs, exists := r.wildcards[domain]
-	r.mu.RUnlock()
+// SplitIPs separates addresses into IPv4 and IPv6, dropping anything that
+// isn't an address
+func SplitIPs(ips []string) ([]string, []string) {
+	var v4, v6 []string
+	for _, ip := range ips {
+		addr := net.ParseIP(ip)
+		switch {
+		case addr == nil:
+		case addr.To4() != nil:
+			v4 = append(v4, ip)
+		default:
+			v6 = append(v6, ip)
+		}
+	}
+	
+	return v4, v6
+}
+
+// Query sends a single question through the resolver's pool, under the same
+// rate limit and server health tracking as every lookup, bypassing the cache
+func (r *Resolver) Query(ctx context.Context, name string, qtype uint16) (*Message, error) {
+	return r.query(ctx, r.pool, name, qtype)
+}
+
+// query sends a question to a pool, moving to another server whenever one
+// fails to answer or answers SERVFAIL/REFUSED. If every attempt got such an
+// answer, the last one is returned.
+func (r *Resolver) query(ctx context.Context, pool *Pool, name string, qtype uint16) (*Message, error) {
+	var lastResp *Message
+	var lastErr error
 	
-	if !exists {
-		return false
+	for attempt := 0; attempt < r.retries; attempt++ {
+		// Wait for the rate limit and a free slot before picking a server
+		if err := r.acquire(ctx); err != nil {
+			return nil, err
+		}
+		server := pool.Next()
+		
+		start := time.Now()
+		resp, err := r.client.Query(ctx, server, name, qtype)
+		r.release(ctx, resp, err)
+		if err == nil && (resp.Rcode == RcodeServFail || resp.Rcode == RcodeRefused) {
+			lastResp = resp
+			err = fmt.Errorf("dns: %s from %s", RcodeString(resp.Rcode), server)
+		}
+		pool.Report(server, time.Since(start), err)
+		
+		if err == nil {
+			return resp, nil
+		}
+		
+		lastErr = err
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
 	}
 	
-	// Check if subdomain IPs match wildcard IPs
-	result, err := r.Resolve(context.Background(), subdomain)
-	if err != nil || !result.Exists {
-		return false
+	if lastResp != nil {
+		return lastResp, nil
 	}
 	
-	// Check if any IP matches wildcard
-	for _, ip := range result.IPs {
-		for _, wildcardIP := range wildcardIPs {
-			if ip == wildcardIP {
-				return true
-			}
+	return nil, lastErr
+}
+
+// acquire waits until the rate limit and the throttle allow another query
+func (r *Resolver) acquire(ctx context.Context) error {
+	if r.limiter != nil {
+		if err := r.limiter.Wait(ctx); err != nil {
+			return err
 		}
 	}
+	if r.throttle != nil {
+		return r.throttle.Acquire(ctx)
+	}
+	return nil
+}
+
+// release hands the throttle slot back. Timeouts and SERVFAIL answers count
+// as losses; queries cut short by ctx don't count either way.
+func (r *Resolver) release(ctx context.Context, resp *Message, err error) {
+	if r.throttle != nil {
+		r.throttle.Release(ctx.Err() == nil && (err != nil || resp.Rcode == RcodeServFail))
+	}
+}
+
+// Throttle returns the adaptive concurrency bound, nil when disabled
+func (r *Resolver) Throttle() *Throttle {
+	return r.throttle
+}
+
+// Pool returns the server pool used by the resolver
+func (r *Resolver) Pool() *Pool {
+	return r.pool
+}
+
+// CheckServers queries every server for a random name that cannot exist and
+// bans the ones that answer it, since they rewrite NXDOMAIN responses
+func (r *Resolver) CheckServers(ctx context.Context) []string {
+	var (
+		banned []string
+		mu     sync.Mutex
+		wg     sync.WaitGroup
+	)
+	
+	for _, server := range r.pool.Servers() {
+		wg.Add(1)
+		go func(server string) {
+			defer wg.Done()
+			
+			// The check goes through the same limits as every other query,
+			// so a long server list isn't hit in one burst
+			if err := r.acquire(ctx); err != nil {
+				return
+			}
+			canary := generateRandomString(16) + ".invalid"
+			resp, err := r.client.Query(ctx, server, canary, TypeA)
+			r.release(ctx, resp, err)
+			if err == nil && resp.Rcode == RcodeSuccess && len(resp.Answers) > 0 {
+				r.pool.Ban(server)
+				mu.Lock()
+				banned = append(banned, server)
+				mu.Unlock()
+			}
+		}(server)
+	}
 	
-	return false
+	wg.Wait()
+	return banned
 }
 
-// ResolveMany resolves multiple subdomains concurrently
+// ResolveMany resolves multiple subdomains concurrently using a fixed pool
+// of workers. Results are returned in the order of the input.
 func (r *Resolver) ResolveMany(ctx context.Context, subdomains []string, workers int) ([]*Result, error) {
 	if workers <= 0 {
 		workers = 10
 	}
 	
+	resolved := make([]*Result, len(subdomains))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			
+			for i := range jobs {
+				result, _ := r.Resolve(ctx, subdomains[i])
+				resolved[i] = result
+			}
+		}()
+	}
+	
+	// Feed hosts to the workers until done or cancelled
+feed:
+	for i := range subdomains {
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	
+	// Collect results, skipping hosts that were never resolved
 	results := make([]*Result, 0, len(subdomains))
-	resultsChan := make(chan *Result, len(subdomains))
+	for _, result := range resolved {
+		if result != nil {
+			results = append(results, result)
+		}
+	}
+	
+	return results, ctx.Err()
+}
+
+// ResolveStream resolves hosts read from a channel using a fixed pool of
+// workers, so inputs of any size can be resolved without holding them in
+// memory. Results are sent in completion order; the returned channel is
+// closed once hosts is closed and drained or ctx is cancelled.
+func (r *Resolver) ResolveStream(ctx context.Context, hosts <-chan string, workers int) <-chan *Result {
+	if workers <= 0 {
+		workers = 10
+	}
 	
-	// Worker pool
-	sem := make(chan struct{}, workers)
+	results := make(chan *Result, workers)
 	var wg sync.WaitGroup
 	
-	for _, subdomain := range subdomains {
+	for w := 0; w < workers; w++ {
 		wg.Add(1)
-		go func(sub string) {
+		go func() {
 			defer wg.Done()
 			
-			// Acquire semaphore
-			sem <- struct{}{}
-			defer func() { <-sem }()
-			
-			result, _ := r.Resolve(ctx, sub)
-			if result != nil {
-				resultsChan <- result
+			for host := range hosts {
+				result, err := r.Resolve(ctx, host)
+				if err != nil {
+					if ctx.Err() != nil {
+						return
+					}
+					continue
+				}
+				
+				select {
+				case results <- result:
+				case <-ctx.Done():
+					return
+				}
 			}
-		}(subdomain)
+		}()
 	}
 	
-	// Wait for all goroutines
 	go func() {
 		wg.Wait()
-		close(resultsChan)
+		close(results)
 	}()
 	
-	// Collect results
-	for result := range resultsChan {
-		results = append(results, result)
+	return results
+}
+
+// SystemServers returns the nameservers listed in /etc/resolv.conf, falling
+// back to public resolvers when there are none
+func SystemServers() []string {
+	servers := make([]string, 0)
+	
+	data, err := os.ReadFile("/etc/resolv.conf")
+	if err == nil {
+		for _, line := range strings.Split(string(data), "\n") {
+			fields := strings.Fields(line)
+			if len(fields) >= 2 && fields[0] == "nameserver" {
+				servers = append(servers, NormalizeServer(fields[1]))
+			}
+		}
+	}
+	
+	if len(servers) == 0 {
+		servers = []string{"8.8.8.8:53", "1.1.1.1:53"}
 	}
 	
-	return results, nil
+	return servers
 }
 
 // generateRandomString generates a random string of specified length
@@ -243,54 +539,6 @@ func generateRandomString(length int) string {
 	return string(result)
 }
 
-// findCommonIPs finds IPs that appear in at least 2 lists
-func findCommonIPs(ipLists [][]string) []string {
-	ipCount := make(map[string]int)
-	
-	for _, ips := range ipLists {
-		seen := make(map[string]bool)
-		for _, ip := range ips {
-			if !seen[ip] {
-				ipCount[ip]++
-				seen[ip] = true
-			}
-		}
-	}
-	
-	// Find IPs that appear in at least 2 lists
-	var commonIPs []string
-	for ip, count := range ipCount {
-		if count >= 2 {
-			commonIPs = append(commonIPs, ip)
-		}
-	}
-	
-	return commonIPs
-}
-
-// FilterWildcard filters out subdomains that resolve to wildcard IPs
-func (r *Resolver) FilterWildcard(ctx context.Context, subdomains []string, domain string) ([]string, error) {
-	// First detect wildcard
-	isWildcard, _, err := r.DetectWildcard(ctx, domain)
-	if err != nil {
-		return subdomains, err // Return original list on error
-	}
-	
-	if !isWildcard {
-		return subdomains, nil // No wildcard, return all
-	}
-	
-	// Filter out wildcard matches
-	filtered := make([]string, 0, len(subdomains))
-	for _, subdomain := range subdomains {
-		if !r.IsWildcard(subdomain, domain) {
-			filtered = append(filtered, subdomain)
-		}
-	}
-	
-	return filtered, nil
-}
-
 // ValidateDomain validates domain format
 func ValidateDomain(domain string) error {
 	domain = strings.TrimSpace(domain)
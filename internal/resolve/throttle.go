@@ -0,0 +1,170 @@
+package resolve
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const (
+	// throttleHighLoss halves the concurrency when more than this share of
+	// a window timed out or got SERVFAIL
+	throttleHighLoss = 0.1
+	// throttleLowLoss grows the concurrency by one when a window lost less
+	throttleLowLoss = 0.02
+	// throttleMinWindow is the fewest queries a window is judged on
+	throttleMinWindow = 20
+)
+
+// Throttle bounds the number of queries in flight and adapts the bound to
+// how the pool copes: it is halved when timeouts and SERVFAILs climb and
+// raised by one while answers come back reliably, up to a fixed maximum.
+// Outcomes are judged in windows of at least as many queries as the current
+// bound, so every adjustment sees the effect of the previous one.
+type Throttle struct {
+	mu       sync.Mutex
+	limit    int
+	max      int
+	inFlight int
+	wake     chan struct{} // closed when a slot frees up
+	nowFunc  func() time.Time
+
+	// current window
+	sent    int
+	lost    int
+	started time.Time
+
+	// totals and the rates of the last completed window
+	queries  int
+	failures int
+	qps      float64
+	loss     float64
+}
+
+// ThrottleStats is a snapshot of a throttle
+type ThrottleStats struct {
+	Limit    int     // current bound on queries in flight
+	Max      int     // bound the throttle ramps up to
+	InFlight int     // queries waiting for an answer
+	Queries  int     // queries sent
+	Failures int     // queries that timed out or got SERVFAIL
+	QPS      float64 // queries per second over the last window
+	Loss     float64 // share of the last window that failed
+}
+
+// NewThrottle creates a throttle allowing up to max queries in flight. It
+// starts at half of that and ramps up while the pool is healthy.
+func NewThrottle(max int) *Throttle {
+	if max < 1 {
+		max = 1
+	}
+
+	limit := max / 2
+	if limit < 1 {
+		limit = 1
+	}
+
+	t := &Throttle{
+		limit:   limit,
+		max:     max,
+		wake:    make(chan struct{}),
+		nowFunc: time.Now,
+	}
+	t.started = t.nowFunc()
+
+	return t
+}
+
+// SetClock overrides the time source, used by tests
+func (t *Throttle) SetClock(now func() time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.nowFunc = now
+	t.started = now()
+}
+
+// Acquire waits for a free slot. Every successful Acquire must be followed
+// by a Release.
+func (t *Throttle) Acquire(ctx context.Context) error {
+	for {
+		t.mu.Lock()
+		if t.inFlight < t.limit {
+			t.inFlight++
+			t.mu.Unlock()
+			return nil
+		}
+		wake := t.wake
+		t.mu.Unlock()
+
+		select {
+		case <-wake:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Release frees a slot and records whether the query failed
+func (t *Throttle) Release(failed bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.inFlight--
+	t.sent++
+	t.queries++
+	if failed {
+		t.lost++
+		t.failures++
+	}
+
+	window := t.limit
+	if window < throttleMinWindow {
+		window = throttleMinWindow
+	}
+	if t.sent >= window {
+		t.adjust()
+	}
+
+	if t.inFlight < t.limit {
+		close(t.wake)
+		t.wake = make(chan struct{})
+	}
+}
+
+// adjust judges the completed window and starts the next one
+func (t *Throttle) adjust() {
+	now := t.nowFunc()
+	t.loss = float64(t.lost) / float64(t.sent)
+	if elapsed := now.Sub(t.started).Seconds(); elapsed > 0 {
+		t.qps = float64(t.sent) / elapsed
+	}
+
+	switch {
+	case t.loss > throttleHighLoss:
+		t.limit /= 2
+		if t.limit < 1 {
+			t.limit = 1
+		}
+	case t.loss < throttleLowLoss && t.limit < t.max:
+		t.limit++
+	}
+
+	t.sent, t.lost = 0, 0
+	t.started = now
+}
+
+// Stats returns a snapshot of the throttle
+func (t *Throttle) Stats() ThrottleStats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return ThrottleStats{
+		Limit:    t.limit,
+		Max:      t.max,
+		InFlight: t.inFlight,
+		Queries:  t.queries,
+		Failures: t.failures,
+		QPS:      t.qps,
+		Loss:     t.loss,
+	}
+}
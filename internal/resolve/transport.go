@@ -0,0 +1,199 @@
+package resolve
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Transport schemes accepted in server entries
+const (
+	SchemeUDP   = "udp"
+	SchemeTCP   = "tcp"
+	SchemeTLS   = "tls"
+	SchemeHTTPS = "https"
+)
+
+// dohMediaType is the content type of DNS messages over HTTPS (RFC 8484)
+const dohMediaType = "application/dns-message"
+
+// Endpoint is a parsed server entry
+type Endpoint struct {
+	Scheme     string
+	Addr       string // host:port for udp, tcp and tls
+	URL        string // query URL for https
+	ServerName string // TLS server name for tls
+	Method     string // HTTP method for https, GET or POST
+}
+
+// ParseServer parses a server entry. Plain "host[:port]" entries use UDP
+// with TCP fallback; URL-style entries select the transport:
+//
+//	udp://8.8.8.8:53
+//	tcp://8.8.8.8:53
+//	tls://1.1.1.1:853                       (DoT, #name overrides the TLS server name)
+//	https://dns.example/dns-query           (DoH using POST)
+//	https://dns.example/dns-query#get       (DoH using GET)
+func ParseServer(server string) (*Endpoint, error) {
+	server = strings.TrimSpace(server)
+	if server == "" {
+		return nil, fmt.Errorf("empty DNS server")
+	}
+
+	if !strings.Contains(server, "://") {
+		return &Endpoint{Scheme: SchemeUDP, Addr: withDefaultPort(server, "53")}, nil
+	}
+
+	u, err := url.Parse(server)
+	if err != nil {
+		return nil, fmt.Errorf("invalid DNS server %q: %w", server, err)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("invalid DNS server %q: missing host", server)
+	}
+
+	scheme := strings.ToLower(u.Scheme)
+	switch scheme {
+	case SchemeUDP, SchemeTCP:
+		return &Endpoint{Scheme: scheme, Addr: withDefaultPort(u.Host, "53")}, nil
+	case SchemeTLS:
+		ep := &Endpoint{
+			Scheme:     SchemeTLS,
+			Addr:       withDefaultPort(u.Host, "853"),
+			ServerName: u.Fragment,
+		}
+		if ep.ServerName == "" {
+			ep.ServerName = u.Hostname()
+		}
+		return ep, nil
+	case SchemeHTTPS:
+		method := http.MethodPost
+		if strings.EqualFold(u.Fragment, "get") {
+			method = http.MethodGet
+		}
+		u.Fragment = ""
+		return &Endpoint{Scheme: SchemeHTTPS, URL: u.String(), Method: method}, nil
+	default:
+		return nil, fmt.Errorf("unsupported DNS transport %q", u.Scheme)
+	}
+}
+
+// String returns the canonical form of the entry
+func (ep *Endpoint) String() string {
+	switch ep.Scheme {
+	case SchemeTCP:
+		return "tcp://" + ep.Addr
+	case SchemeTLS:
+		host, _, _ := net.SplitHostPort(ep.Addr)
+		if ep.ServerName != "" && ep.ServerName != host {
+			return "tls://" + ep.Addr + "#" + ep.ServerName
+		}
+		return "tls://" + ep.Addr
+	case SchemeHTTPS:
+		if ep.Method == http.MethodGet {
+			return ep.URL + "#get"
+		}
+		return ep.URL
+	default:
+		return ep.Addr
+	}
+}
+
+// withDefaultPort adds port to an address without one
+func withDefaultPort(addr, port string) string {
+	if _, _, err := net.SplitHostPort(addr); err == nil {
+		return addr
+	}
+	return net.JoinHostPort(strings.Trim(addr, "[]"), port)
+}
+
+// exchangeTLS sends a query over DNS-over-TLS (RFC 7858)
+func (c *Client) exchangeTLS(ctx context.Context, ep *Endpoint, req []byte, id uint16) (*Message, error) {
+	cfg := &tls.Config{}
+	if c.TLSConfig != nil {
+		cfg = c.TLSConfig.Clone()
+	}
+	if cfg.ServerName == "" {
+		cfg.ServerName = ep.ServerName
+	}
+
+	d := tls.Dialer{
+		NetDialer: &net.Dialer{Timeout: c.Timeout},
+		Config:    cfg,
+	}
+	conn, err := d.DialContext(ctx, "tcp", ep.Addr)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	deadline := time.Now().Add(c.Timeout)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+	conn.SetDeadline(deadline)
+
+	return exchangeStream(conn, req, id)
+}
+
+// exchangeHTTPS sends a query over DNS-over-HTTPS (RFC 8484)
+func (c *Client) exchangeHTTPS(ctx context.Context, ep *Endpoint, req []byte, id uint16) (*Message, error) {
+	var httpReq *http.Request
+	var err error
+
+	if ep.Method == http.MethodGet {
+		sep := "?"
+		if strings.Contains(ep.URL, "?") {
+			sep = "&"
+		}
+		queryURL := ep.URL + sep + "dns=" + base64.RawURLEncoding.EncodeToString(req)
+		httpReq, err = http.NewRequestWithContext(ctx, http.MethodGet, queryURL, nil)
+	} else {
+		httpReq, err = http.NewRequestWithContext(ctx, http.MethodPost, ep.URL, bytes.NewReader(req))
+		if err == nil {
+			httpReq.Header.Set("Content-Type", dohMediaType)
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create DoH request: %w", err)
+	}
+	httpReq.Header.Set("Accept", dohMediaType)
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	resp, err := httpClient.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("DoH server returned status %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 65535))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read DoH response: %w", err)
+	}
+
+	msg, err := Unpack(body)
+	if err != nil {
+		return nil, err
+	}
+	if msg.ID != id {
+		return nil, fmt.Errorf("dns: response ID mismatch")
+	}
+
+	return msg, nil
+}
@@ -0,0 +1,127 @@
+package resolve
+
+import (
+	"context"
+	"sync"
+)
+
+// Verdicts of the trusted-resolver validation pass
+const (
+	VerdictConfirmed  = "confirmed"  // trusted resolvers returned an overlapping answer
+	VerdictMismatch   = "mismatch"   // host exists but the answers have nothing in common
+	VerdictNotFound   = "not_found"  // trusted resolvers say the host has no such records
+	VerdictUnverified = "unverified" // trusted resolvers failed to answer
+)
+
+// Validation is the outcome of re-checking a positive answer
+type Validation struct {
+	Host    string
+	Verdict string
+	Trusted *Result // answer from the trusted resolvers, nil when unverified
+}
+
+// Disagrees reports whether the trusted resolvers contradicted the pool
+func (v *Validation) Disagrees() bool {
+	return v.Verdict == VerdictMismatch || v.Verdict == VerdictNotFound
+}
+
+// Refuted reports whether the trusted resolvers say the host doesn't exist.
+// A mismatch alone doesn't refute a host: geo-DNS and CDNs routinely hand
+// different resolvers different addresses.
+func (v *Validation) Refuted() bool {
+	return v.Verdict == VerdictNotFound
+}
+
+// HasTrusted reports whether trusted servers are configured
+func (r *Resolver) HasTrusted() bool {
+	return r.trusted.Size() > 0
+}
+
+// TrustedPool returns the pool of trusted servers
+func (r *Resolver) TrustedPool() *Pool {
+	return r.trusted
+}
+
+// Validate re-resolves a positive answer through the trusted servers,
+// bypassing the cache, and compares the two. The answers agree when they
+// share at least one record of the same type and value, so hosts whose
+// addresses rotate behind a common CNAME are still confirmed.
+func (r *Resolver) Validate(ctx context.Context, result *Result) *Validation {
+	v := &Validation{Host: result.Host, Verdict: VerdictUnverified}
+	if !r.HasTrusted() {
+		return v
+	}
+
+	trusted, err := r.lookup(ctx, r.trusted, result.Host)
+	if err != nil || trusted.Error != nil {
+		return v
+	}
+	v.Trusted = trusted
+
+	if !trusted.Exists {
+		v.Verdict = VerdictNotFound
+		return v
+	}
+
+	type record struct {
+		Type uint16
+		Data string
+	}
+	seen := make(map[record]bool, len(result.Records))
+	for _, rr := range result.Records {
+		seen[record{rr.Type, rr.Data}] = true
+	}
+
+	v.Verdict = VerdictMismatch
+	for _, rr := range trusted.Records {
+		if seen[record{rr.Type, rr.Data}] {
+			v.Verdict = VerdictConfirmed
+			break
+		}
+	}
+
+	return v
+}
+
+// ValidateMany validates results concurrently using a fixed pool of workers.
+// Validations are returned in the order of the input; hosts left unchecked
+// because ctx was cancelled are reported as unverified.
+func (r *Resolver) ValidateMany(ctx context.Context, results []*Result, workers int) []*Validation {
+	if workers <= 0 {
+		workers = 10
+	}
+
+	validations := make([]*Validation, len(results))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for i := range jobs {
+				validations[i] = r.Validate(ctx, results[i])
+			}
+		}()
+	}
+
+feed:
+	for i := range results {
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	for i, v := range validations {
+		if v == nil {
+			validations[i] = &Validation{Host: results[i].Host, Verdict: VerdictUnverified}
+		}
+	}
+
+	return validations
+}
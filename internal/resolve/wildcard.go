@@ -0,0 +1,215 @@
+package resolve
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	// wildcardProbes is the number of random names resolved per zone
+	wildcardProbes = 3
+	// wildcardThreshold is how many probes must resolve to call it a wildcard
+	wildcardThreshold = 2
+)
+
+// Fingerprint describes what random names under a zone resolve to
+type Fingerprint struct {
+	Zone     string
+	Wildcard bool
+	IPs      map[string]bool // every address returned for a probe
+	CNAMEs   map[string]bool // first CNAME target of every probe
+}
+
+// zoneState caches the fingerprint of a zone, probing it at most once
+type zoneState struct {
+	mu   sync.Mutex
+	done bool
+	fp   *Fingerprint
+}
+
+// Fingerprint probes zone with random labels and returns its wildcard
+// fingerprint. Results are cached per zone; concurrent callers for the same
+// zone wait for a single probe. Probes bypass the cache, so their random
+// names never take the place of real hosts or end up in a saved cache.
+func (r *Resolver) Fingerprint(ctx context.Context, zone string) *Fingerprint {
+	zone = CanonicalName(zone)
+
+	r.mu.Lock()
+	state, ok := r.zones[zone]
+	if !ok {
+		state = &zoneState{}
+		r.zones[zone] = state
+	}
+	r.mu.Unlock()
+
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	if state.done {
+		return state.fp
+	}
+
+	fp := &Fingerprint{
+		Zone:   zone,
+		IPs:    make(map[string]bool),
+		CNAMEs: make(map[string]bool),
+	}
+
+	resolved := 0
+	for i := 0; i < wildcardProbes; i++ {
+		probe := fmt.Sprintf("%s.%s", generateRandomString(16), zone)
+		result, err := r.lookup(ctx, r.pool, probe)
+		if err != nil || !result.Exists {
+			continue
+		}
+
+		resolved++
+		for _, ip := range result.IPs {
+			fp.IPs[ip] = true
+		}
+		if chain := result.CNAMEChain(); len(chain) > 0 {
+			fp.CNAMEs[chain[0]] = true
+		}
+	}
+	fp.Wildcard = resolved >= wildcardThreshold
+
+	// Don't remember a probe that was cut short
+	if ctx.Err() == nil {
+		state.done = true
+		state.fp = fp
+	}
+
+	return fp
+}
+
+// Matches reports whether a resolved host looks like it was answered by this
+// wildcard. A host CNAMEd to the wildcard's target matches; otherwise every
+// address of the host must be one the wildcard hands out, so a legitimate host
+// that merely shares one address with the wildcard is kept.
+func (fp *Fingerprint) Matches(result *Result) bool {
+	if fp == nil || !fp.Wildcard || result == nil || !result.Exists {
+		return false
+	}
+
+	if chain := result.CNAMEChain(); len(chain) > 0 && len(fp.CNAMEs) > 0 {
+		return fp.CNAMEs[chain[0]]
+	}
+
+	if len(result.IPs) == 0 {
+		return false
+	}
+	for _, ip := range result.IPs {
+		if !fp.IPs[ip] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// DetectWildcard detects if a domain has wildcard DNS directly under it
+func (r *Resolver) DetectWildcard(ctx context.Context, domain string) (bool, []string, error) {
+	fp := r.Fingerprint(ctx, domain)
+	if ctx.Err() != nil {
+		return false, nil, ctx.Err()
+	}
+	if !fp.Wildcard {
+		return false, nil, nil
+	}
+
+	ips := make([]string, 0, len(fp.IPs))
+	for ip := range fp.IPs {
+		ips = append(ips, ip)
+	}
+	sort.Strings(ips)
+
+	return true, ips, nil
+}
+
+// IsWildcard checks if a subdomain is answered by a wildcard in any zone
+// between it and domain
+func (r *Resolver) IsWildcard(subdomain string, domain string) bool {
+	ctx := context.Background()
+
+	result, err := r.Resolve(ctx, subdomain)
+	if err != nil {
+		return false
+	}
+
+	return r.IsWildcardResult(ctx, result, domain)
+}
+
+// IsWildcardResult checks an already resolved subdomain against the wildcard
+// fingerprint of every parent zone up to and including domain, probing each
+// zone the first time it is seen
+func (r *Resolver) IsWildcardResult(ctx context.Context, result *Result, domain string) bool {
+	if result == nil || !result.Exists {
+		return false
+	}
+
+	for _, zone := range parentZones(result.Host, domain) {
+		if r.Fingerprint(ctx, zone).Matches(result) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// WildcardZones returns the zones found to have wildcard DNS so far
+func (r *Resolver) WildcardZones() []string {
+	r.mu.Lock()
+	states := make([]*zoneState, 0, len(r.zones))
+	for _, state := range r.zones {
+		states = append(states, state)
+	}
+	r.mu.Unlock()
+
+	zones := make([]string, 0)
+	for _, state := range states {
+		state.mu.Lock()
+		if state.done && state.fp.Wildcard {
+			zones = append(zones, state.fp.Zone)
+		}
+		state.mu.Unlock()
+	}
+	sort.Strings(zones)
+
+	return zones
+}
+
+// FilterWildcard filters out subdomains that are answered by a wildcard
+func (r *Resolver) FilterWildcard(ctx context.Context, subdomains []string, domain string) ([]string, error) {
+	filtered := make([]string, 0, len(subdomains))
+	for _, subdomain := range subdomains {
+		result, err := r.Resolve(ctx, subdomain)
+		if err != nil {
+			return subdomains, err // Return original list on error
+		}
+		if !r.IsWildcardResult(ctx, result, domain) {
+			filtered = append(filtered, subdomain)
+		}
+	}
+
+	return filtered, nil
+}
+
+// parentZones returns the zones enclosing host from the closest one up to
+// and including apex. Hosts that are not below apex have none.
+func parentZones(host, apex string) []string {
+	host = CanonicalName(host)
+	apex = CanonicalName(apex)
+	if !strings.HasSuffix(host, "."+apex) {
+		return nil
+	}
+
+	var zones []string
+	for name := host; name != apex; {
+		name = name[strings.Index(name, ".")+1:]
+		zones = append(zones, name)
+	}
+
+	return zones
+}
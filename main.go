@@ -5,19 +5,36 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"sort"
 	"strings"
+	"text/tabwriter"
+	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/yourusername/subrecon/internal/resolve"
+	"github.com/yourusername/subrecon/pkg/bruteforce"
+	"github.com/yourusername/subrecon/pkg/cdn"
 	"github.com/yourusername/subrecon/pkg/config"
 	"github.com/yourusername/subrecon/pkg/filter"
+	"github.com/yourusername/subrecon/pkg/geoip"
 	"github.com/yourusername/subrecon/pkg/output"
+	"github.com/yourusername/subrecon/pkg/permute"
+	"github.com/yourusername/subrecon/pkg/portscan"
+	"github.com/yourusername/subrecon/pkg/probe"
+	"github.com/yourusername/subrecon/pkg/ptr"
+	"github.com/yourusername/subrecon/pkg/quota"
 	"github.com/yourusername/subrecon/pkg/runner"
+	"github.com/yourusername/subrecon/pkg/scope"
 	"github.com/yourusername/subrecon/pkg/sources"
+	"github.com/yourusername/subrecon/pkg/takeover"
 )
 
 const version = "1.0.0"
 
+// maxHarvestRounds bounds how many times names found in certificates are fed
+// back through resolution and probing
+const maxHarvestRounds = 3
+
 const banner = `
    _____       __   ____                      
   / ___/__  __/ /_ / __ \___  _________  ____ 
@@ -44,8 +61,39 @@ var (
 	workers        int
 	configPath     string
 	activeMode     bool
+	resolverList   string
+	dnsThreads     int
+	dnsRateLimit   int
+	dnsInFlight    int
+	ipv4Only       bool
+	ipv6Only       bool
+	recordTypeList string
+	trustedList    string
+	validationMode string
+	dnsCacheFile   string
+	wordlistPath   string
+	permuteMode    bool
+	permuteWords   string
+	ptrSweep       bool
+	ptrPrefix      int
+	takeoverMode   bool
+	takeoverFile   string
+	probeMode      bool
+	probePorts     string
+	probeThreads   int
+	probeRate      int
+	probeFollow    bool
+	geoipASN       string
+	geoipCity      string
+	cdnRanges      string
+	portList       string
+	portThreads    int
+	portRate       int
+	excludeCDN     bool
+	scopeFile      string
 	matchPattern   string
 	filterPattern  string
+	whereExpr      string
 	rateLimit      int
 	proxyURL       string
 	verbose        bool
@@ -69,6 +117,24 @@ Features:
 	RunE: run,
 }
 
+var quotaCmd = &cobra.Command{
+	Use:   "quota",
+	Short: "Show remaining request budget per source",
+	Long: `Show how many requests each source has used in the current quota period
+and how much of its budget remains. Budgets are configured with the quota
+field of each source in provider-config.yaml.`,
+	RunE: runQuota,
+}
+
+var bruteCmd = &cobra.Command{
+	Use:   "brute",
+	Short: "Brute-force subdomains from a wordlist",
+	Long: `Resolve word.domain for every word in a wordlist through the resolver pool
+and report the names that exist, skipping wildcard answers. The wordlist is
+streamed, so lists with millions of words can be used.`,
+	RunE: runBrute,
+}
+
 func init() {
 	rootCmd.Flags().StringVarP(&domain, "domain", "d", "", "Target domain (e.g., example.com)")
 	rootCmd.Flags().StringVar(&domainList, "domain-list", "", "File containing list of domains")
@@ -82,12 +148,73 @@ func init() {
 	rootCmd.Flags().IntVarP(&workers, "threads", "t", 10, "Number of concurrent workers")
 	rootCmd.Flags().StringVarP(&configPath, "config", "c", "config.yaml", "Path to config file")
 	rootCmd.Flags().BoolVar(&activeMode, "active", false, "Enable DNS verification")
-	rootCmd.Flags().StringVarP(&matchPattern, "match", "m", "", "Match patterns (regex or comma-separated)")
-	rootCmd.Flags().StringVarP(&filterPattern, "filter", "f", "", "Filter patterns (exclude matches)")
+	rootCmd.Flags().StringVar(&resolverList, "resolvers", "", "File containing list of DNS resolvers")
+	rootCmd.Flags().IntVar(&dnsThreads, "dns-threads", 0, "Number of concurrent DNS lookups (default from config)")
+	rootCmd.Flags().IntVar(&dnsRateLimit, "dns-rate-limit", 0, "DNS queries per second across all lookups, 0 for unlimited (default from config)")
+	rootCmd.Flags().IntVar(&dnsInFlight, "dns-max-in-flight", 0, "Most DNS queries in flight, lowered automatically on timeouts (default from config)")
+	rootCmd.Flags().StringVar(&recordTypeList, "record-types", "", "Comma-separated DNS record types to query (default A,AAAA)")
+	rootCmd.Flags().BoolVar(&ipv4Only, "ipv4-only", false, "Only report hosts with IPv4 addresses and no IPv6 (requires --active)")
+	rootCmd.Flags().BoolVar(&ipv6Only, "ipv6-only", false, "Only report hosts with IPv6 addresses and no IPv4 (requires --active)")
+	rootCmd.Flags().StringVar(&trustedList, "trusted-resolvers", "", "Comma-separated resolvers used to re-check every verified host")
+	rootCmd.Flags().StringVar(&dnsCacheFile, "dns-cache", "", "File to persist DNS answers in between runs (default from config)")
+	rootCmd.Flags().StringVar(&validationMode, "validation", "", "What to do with hosts the trusted resolvers don't find: discard or flag; mismatched answers are only flagged (default from config)")
+	rootCmd.Flags().StringVar(&wordlistPath, "bruteforce", "", "Wordlist to brute-force subdomains with")
+	rootCmd.Flags().BoolVar(&permuteMode, "permute", false, "Resolve alterations of the hosts found")
+	rootCmd.Flags().StringVar(&permuteWords, "permute-words", "", "File with words used for permutations (default from config)")
+	rootCmd.Flags().BoolVar(&ptrSweep, "ptr-sweep", false, "Reverse-resolve the address ranges of verified hosts (requires --active)")
+	rootCmd.Flags().IntVar(&ptrPrefix, "ptr-prefix", 0, "IPv4 prefix length of the ranges swept, e.g. 24 (default from config)")
+	rootCmd.Flags().BoolVar(&takeoverMode, "takeover", false, "Flag hosts whose CNAME points at a claimable service")
+	rootCmd.Flags().StringVar(&takeoverFile, "takeover-fingerprints", "", "YAML file with takeover fingerprints (default built-in)")
+	rootCmd.Flags().BoolVar(&probeMode, "probe", false, "Probe verified hosts over HTTP and HTTPS (requires --active)")
+	rootCmd.Flags().StringVar(&probePorts, "probe-ports", "", "Comma-separated ports to probe (default 80,443)")
+	rootCmd.Flags().IntVar(&probeThreads, "probe-threads", 0, "Concurrent HTTP probes (default from config)")
+	rootCmd.Flags().IntVar(&probeRate, "probe-rate-limit", 0, "HTTP probes per second, 0 for unlimited (default from config)")
+	rootCmd.Flags().BoolVar(&probeFollow, "probe-follow-redirects", false, "Also follow redirects to other hosts when probing")
+	rootCmd.Flags().StringVar(&portList, "ports", "", "TCP ports to check on resolved addresses: top-100 or a list such as 80,443,8443 (requires --active)")
+	rootCmd.Flags().IntVar(&portThreads, "port-threads", 0, "Concurrent TCP connection attempts (default from config)")
+	rootCmd.Flags().IntVar(&portRate, "port-rate-limit", 0, "TCP connection attempts per second, 0 for unlimited (default from config)")
+	rootCmd.Flags().StringVar(&geoipASN, "geoip-asn-db", "", "GeoLite2 ASN database to look resolved addresses up in (requires --active)")
+	rootCmd.Flags().StringVar(&geoipCity, "geoip-city-db", "", "GeoLite2 City database to look resolved addresses up in (requires --active)")
+	rootCmd.Flags().StringVar(&cdnRanges, "cdn-ranges", "", "YAML file with CDN and hosting provider ranges (default built-in)")
+	rootCmd.Flags().BoolVar(&excludeCDN, "exclude-cdn", false, "Drop hosts served from shared CDN edges (requires --active)")
+	rootCmd.Flags().StringVar(&scopeFile, "scope", "", "YAML scope file; hosts and addresses outside it are never touched or reported")
+	rootCmd.Flags().StringVarP(&matchPattern, "match", "m", "", "Match patterns: globs like *.dev.example.com, or re:, suffix:, label: forms (comma-separated or @file)")
+	rootCmd.Flags().StringVarP(&filterPattern, "filter", "f", "", "Filter patterns, same syntax as --match (exclude matches)")
+	rootCmd.Flags().StringVar(&whereExpr, "where", "", "Only report results satisfying an expression, e.g. 'ip in 10.0.0.0/8 and status >= 400'")
 	rootCmd.Flags().IntVar(&rateLimit, "rate-limit", 5, "Rate limit (requests/second)")
 	rootCmd.Flags().StringVar(&proxyURL, "proxy", "", "HTTP proxy URL")
 	rootCmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "Verbose output")
 	rootCmd.Flags().BoolVar(&showVersion, "version", false, "Show version information")
+	
+	quotaCmd.Flags().StringVarP(&configPath, "config", "c", "config.yaml", "Path to config file")
+	rootCmd.AddCommand(quotaCmd)
+	
+	bruteCmd.Flags().StringVarP(&domain, "domain", "d", "", "Target domain (e.g., example.com)")
+	bruteCmd.Flags().StringVar(&domainList, "domain-list", "", "File containing list of domains")
+	bruteCmd.Flags().StringVarP(&wordlistPath, "wordlist", "w", "", "Wordlist with one word per line")
+	bruteCmd.Flags().StringVarP(&outputFile, "output", "o", "", "Output file path (default: stdout)")
+	bruteCmd.Flags().BoolVar(&jsonOutput, "json", false, "Output in JSON format")
+	bruteCmd.Flags().BoolVar(&silentMode, "silent", false, "Suppress progress and error messages")
+	bruteCmd.Flags().StringVarP(&configPath, "config", "c", "config.yaml", "Path to config file")
+	bruteCmd.Flags().StringVar(&resolverList, "resolvers", "", "File containing list of DNS resolvers")
+	bruteCmd.Flags().IntVar(&dnsThreads, "dns-threads", 0, "Number of concurrent DNS lookups (default from config)")
+	bruteCmd.Flags().IntVar(&dnsRateLimit, "dns-rate-limit", 0, "DNS queries per second across all lookups, 0 for unlimited (default from config)")
+	bruteCmd.Flags().IntVar(&dnsInFlight, "dns-max-in-flight", 0, "Most DNS queries in flight, lowered automatically on timeouts (default from config)")
+	bruteCmd.Flags().StringVar(&recordTypeList, "record-types", "", "Comma-separated DNS record types to query (default A,AAAA)")
+	bruteCmd.Flags().BoolVar(&ipv4Only, "ipv4-only", false, "Only report hosts with IPv4 addresses and no IPv6")
+	bruteCmd.Flags().BoolVar(&ipv6Only, "ipv6-only", false, "Only report hosts with IPv6 addresses and no IPv4")
+	bruteCmd.Flags().StringVar(&trustedList, "trusted-resolvers", "", "Comma-separated resolvers used to re-check every hit")
+	bruteCmd.Flags().StringVar(&dnsCacheFile, "dns-cache", "", "File to persist DNS answers in between runs (default from config)")
+	bruteCmd.Flags().StringVar(&validationMode, "validation", "", "What to do with hits the trusted resolvers don't find: discard or flag; mismatched answers are only flagged (default from config)")
+	bruteCmd.Flags().StringVar(&geoipASN, "geoip-asn-db", "", "GeoLite2 ASN database to look hits up in")
+	bruteCmd.Flags().StringVar(&geoipCity, "geoip-city-db", "", "GeoLite2 City database to look hits up in")
+	bruteCmd.Flags().StringVar(&scopeFile, "scope", "", "YAML scope file; hits outside it are dropped")
+	bruteCmd.Flags().StringVar(&cdnRanges, "cdn-ranges", "", "YAML file with CDN and hosting provider ranges (default built-in)")
+	bruteCmd.Flags().BoolVar(&excludeCDN, "exclude-cdn", false, "Drop hits served from shared CDN edges")
+	bruteCmd.Flags().StringVar(&whereExpr, "where", "", "Only report hits satisfying an expression, e.g. 'asn == 16509 or cname ~ cloudfront.net'")
+	bruteCmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "Verbose output")
+	bruteCmd.MarkFlagRequired("wordlist")
+	rootCmd.AddCommand(bruteCmd)
 }
 
 func main() {
@@ -100,7 +227,7 @@ func main() {
 func run(cmd *cobra.Command, args []string) error {
 	// Show banner in verbose mode
 	if verbose && !silentMode {
-		fmt.Println(banner)
+		fmt.Print(banner)
 	}
 	
 	// Show version
@@ -137,18 +264,207 @@ func run(cmd *cobra.Command, args []string) error {
 	if jsonOutput {
 		cfg.Output.Format = "json"
 	}
+	if err := applyDNSFlags(cfg); err != nil {
+		return err
+	}
+	if permuteMode {
+		cfg.Permutation.Enabled = true
+	}
+	if permuteWords != "" {
+		cfg.Permutation.WordsFile = permuteWords
+	}
+	if ptrSweep {
+		cfg.PTRSweep.Enabled = true
+	}
+	if ptrPrefix > 0 {
+		cfg.PTRSweep.IPv4Prefix = ptrPrefix
+	}
+	if takeoverMode {
+		cfg.Takeover.Enabled = true
+	}
+	if takeoverFile != "" {
+		cfg.Takeover.Fingerprints = takeoverFile
+	}
+	if ptrSweep && !activeMode {
+		return fmt.Errorf("--ptr-sweep requires --active")
+	}
+	if cfg.PTRSweep.Enabled && !activeMode {
+		// Set in the config file, which passive runs share
+		if verbose && !silentMode {
+			fmt.Println("[-] Skipping PTR sweep: ptr_sweep.enabled needs --active")
+		}
+		cfg.PTRSweep.Enabled = false
+	}
+	if probeMode {
+		cfg.Probe.Enabled = true
+	}
+	if probePorts != "" {
+		if cfg.Probe.Ports, err = probe.ParsePorts(probePorts); err != nil {
+			return err
+		}
+	}
+	if probeThreads > 0 {
+		cfg.Probe.Threads = probeThreads
+	}
+	if probeRate > 0 {
+		cfg.Probe.RateLimit = probeRate
+	}
+	if probeFollow {
+		cfg.Probe.FollowRedirects = true
+	}
+	if probeMode && !activeMode {
+		return fmt.Errorf("--probe requires --active")
+	}
+	if cfg.Probe.Enabled && !activeMode {
+		// Set in the config file, which passive runs share
+		if verbose && !silentMode {
+			fmt.Println("[-] Skipping HTTP probing: probe.enabled needs --active")
+		}
+		cfg.Probe.Enabled = false
+	}
+	if (ipv4Only || ipv6Only) && !activeMode {
+		return fmt.Errorf("--ipv4-only and --ipv6-only require --active")
+	}
+	if (geoipASN != "" || geoipCity != "") && !activeMode {
+		return fmt.Errorf("--geoip-asn-db and --geoip-city-db require --active")
+	}
+	if excludeCDN && !activeMode {
+		return fmt.Errorf("--exclude-cdn requires --active")
+	}
+	if portList != "" {
+		cfg.PortScan.Ports = portList
+	}
+	if portThreads > 0 {
+		cfg.PortScan.Threads = portThreads
+	}
+	if portRate > 0 {
+		cfg.PortScan.RateLimit = portRate
+	}
+	if portList != "" && !activeMode {
+		return fmt.Errorf("--ports requires --active")
+	}
+	if cfg.PortScan.Ports != "" && !activeMode {
+		// Set in the config file, which passive runs share
+		if verbose && !silentMode {
+			fmt.Println("[-] Skipping port checks: port_scan.ports needs --active")
+		}
+		cfg.PortScan.Ports = ""
+	}
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
 	
-	// Get domains to process
-	domains := make([]string, 0)
-	if domain != "" {
-		domains = append(domains, domain)
+	// Set up the permutation engine
+	var permuter *permute.Generator
+	if cfg.Permutation.Enabled {
+		words := cfg.Permutation.Words
+		if cfg.Permutation.WordsFile != "" {
+			words, err = permute.LoadWords(cfg.Permutation.WordsFile)
+			if err != nil {
+				return err
+			}
+		}
+		permuter = permute.New(words)
 	}
-	if domainList != "" {
-		fileDomains, err := readDomainsFromFile(domainList)
+	
+	// Set up takeover detection
+	var checker *takeover.Checker
+	if cfg.Takeover.Enabled {
+		var fingerprints []takeover.Fingerprint
+		if cfg.Takeover.Fingerprints != "" {
+			fingerprints, err = takeover.LoadFingerprints(cfg.Takeover.Fingerprints)
+			if err != nil {
+				return err
+			}
+		}
+		checker = takeover.New(fingerprints, time.Duration(cfg.HTTP.Timeout)*time.Second)
+		checker.UserAgent = cfg.HTTP.UserAgent
+	}
+	
+	sc, err := loadScope(cfg)
+	if err != nil {
+		return err
+	}
+	
+	// Set up the HTTP prober
+	var prober *probe.Prober
+	if cfg.Probe.Enabled {
+		opts := probe.Options{
+			Ports:           cfg.Probe.Ports,
+			Threads:         cfg.Probe.Threads,
+			RateLimit:       cfg.Probe.RateLimit,
+			Timeout:         time.Duration(cfg.Probe.Timeout) * time.Second,
+			UserAgent:       cfg.HTTP.UserAgent,
+			FollowRedirects: cfg.Probe.FollowRedirects,
+		}
+		// Redirects may lead anywhere, so the prober checks the scope itself
+		if sc != nil {
+			opts.Scope = sc
+		}
+		prober = probe.New(opts)
+	}
+	
+	// Set up the TCP port check
+	var scanner *portscan.Scanner
+	if cfg.PortScan.Ports != "" {
+		ports, err := portscan.ParsePorts(cfg.PortScan.Ports)
 		if err != nil {
-			return fmt.Errorf("failed to read domain list: %w", err)
+			return err
 		}
-		domains = append(domains, fileDomains...)
+		scanner = portscan.New(portscan.Options{
+			Ports:     ports,
+			Threads:   cfg.PortScan.Threads,
+			RateLimit: cfg.PortScan.RateLimit,
+			Timeout:   time.Duration(cfg.PortScan.Timeout) * time.Second,
+		})
+	}
+	
+	// Open the GeoIP databases
+	enricher, err := openEnricher(cfg)
+	if err != nil {
+		return err
+	}
+	
+	classifier, err := newClassifier(cfg)
+	if err != nil {
+		return err
+	}
+	
+	where, err := newWhereFilter()
+	if err != nil {
+		return err
+	}
+	
+	// screen drops the hosts nothing may touch and tags the rest; every
+	// verified host goes through it before it is probed
+	screen := func(results []runner.SubdomainResult) []runner.SubdomainResult {
+		results = screenScope(sc, selectFamily(results))
+		if activeMode {
+			results = classifyResults(classifier, results, cfg.CDN.Exclude)
+		}
+		return results
+	}
+	
+	// Open usage ledger for quota tracking
+	ledger, err := openLedger(cfg, providerCfg)
+	if err != nil {
+		return err
+	}
+	
+	// Set up the resolver once so pool health carries across domains
+	var resolver *resolve.Resolver
+	if activeMode || wordlistPath != "" || permuter != nil || checker != nil || selectsActiveSources(sourceList) {
+		resolver, err = newResolver(cfg)
+		if err != nil {
+			return err
+		}
+		defer reportThroughput(resolver)()
+	}
+	
+	// Get domains to process
+	domains, err := collectDomains()
+	if err != nil {
+		return err
 	}
 	
 	// Process each domain
@@ -173,7 +489,7 @@ func run(cmd *cobra.Command, args []string) error {
 		}
 		
 		// Initialize sources
-		srcs, err := initializeSources(providerCfg, sourceList, excludeSources)
+		srcs, err := initializeSources(providerCfg, sourceList, excludeSources, ledger, resolver, sc)
 		if err != nil {
 			return err
 		}
@@ -186,6 +502,7 @@ func run(cmd *cobra.Command, args []string) error {
 			Silent:  silentMode,
 		}
 		r := runner.NewRunner(srcs, runnerCfg)
+		r.SetLedger(ledger)
 		
 		// Set rate limits
 		for _, src := range srcs {
@@ -198,6 +515,9 @@ func run(cmd *cobra.Command, args []string) error {
 		// Run enumeration
 		ctx := context.Background()
 		results, err := r.RunWithMetadata(ctx, dom)
+		if saveErr := ledger.Save(); saveErr != nil && !silentMode {
+			fmt.Fprintf(os.Stderr, "[!] Warning: %v\n", saveErr)
+		}
 		if err != nil {
 			if !silentMode {
 				fmt.Fprintf(os.Stderr, "[-] Error processing %s: %v\n", dom, err)
@@ -209,89 +529,206 @@ func run(cmd *cobra.Command, args []string) error {
 			fmt.Printf("[+] Found %d subdomains for %s\n", len(results), dom)
 		}
 		
-		// Apply filtering
-		if matchPattern != "" || filterPattern != "" {
-			f := filter.NewFilter()
-			
-			if matchPattern != "" {
-				if err := f.AddMatchPattern(matchPattern); err != nil {
-					return fmt.Errorf("invalid match pattern: %w", err)
-				}
-			}
-			
-			if filterPattern != "" {
-				if err := f.AddExcludePattern(filterPattern); err != nil {
-					return fmt.Errorf("invalid filter pattern: %w", err)
-				}
+		// Brute-force names the passive sources don't know about
+		if wordlistPath != "" {
+			hits, err := bruteforceDomain(ctx, resolver, dom, cfg.DNS.Threads)
+			if err != nil {
+				return err
 			}
-			
-			// Extract hosts for filtering
-			hosts := make([]string, len(results))
-			for i, r := range results {
-				hosts[i] = r.Host
-			}
-			
-			// Apply filter
-			filteredHosts := f.Apply(hosts)
-			
-			// Rebuild results
-			filteredResults := make([]runner.SubdomainResult, 0, len(filteredHosts))
-			hostMap := make(map[string]runner.SubdomainResult)
-			for _, r := range results {
-				hostMap[r.Host] = r
+			results = mergeResults(results, hits)
+		}
+		
+		// Resolve alterations of everything found so far
+		if permuter != nil {
+			hits, err := permuteDomain(ctx, resolver, permuter, results, dom, cfg.DNS.Threads)
+			if err != nil {
+				return err
 			}
-			for _, host := range filteredHosts {
-				if r, ok := hostMap[host]; ok {
-					filteredResults = append(filteredResults, r)
-				}
+			results = mergeResults(results, hits)
+		}
+		
+		// Apply filtering
+		if matchPattern != "" || filterPattern != "" {
+			results, err = filterResults(results)
+			if err != nil {
+				return err
 			}
-			results = filteredResults
 			
 			if verbose && !silentMode {
 				fmt.Printf("[+] %d subdomains after filtering\n", len(results))
 			}
 		}
 		
+		// Drop out-of-scope names before anything resolves or fetches them
+		results = screenScope(sc, results)
+		
+		// Look for dangling CNAMEs before verification drops hosts that
+		// no longer resolve
+		if checker != nil {
+			results = checkTakeovers(ctx, resolver, checker, sc, results, cfg.DNS.Threads)
+		}
+		
 		// DNS verification
 		if activeMode {
 			if verbose && !silentMode {
 				fmt.Printf("[*] Performing DNS verification...\n")
 			}
 			
-			resolver := resolve.NewResolver(&resolve.Config{
-				Servers: cfg.DNS.Servers,
-				Timeout: cfg.GetDNSTimeout(),
-			})
-			
-			// Detect wildcard
-			isWildcard, wildcardIPs, _ := resolver.DetectWildcard(ctx, dom)
-			if isWildcard && verbose && !silentMode {
-				fmt.Printf("[!] Wildcard DNS detected for %s: %v\n", dom, wildcardIPs)
-			}
-			
-			// Resolve all subdomains
-			verifiedResults := make([]runner.SubdomainResult, 0)
-			for _, result := range results {
-				res, err := resolver.Resolve(ctx, result.Host)
-				if err == nil && res.Exists {
-					// Check if it's not a wildcard
-					if !isWildcard || !resolver.IsWildcard(result.Host, dom) {
-						result.IPs = res.IPs
-						verifiedResults = append(verifiedResults, result)
-					}
-				}
-			}
-			
-			results = verifiedResults
+			results = verifyResults(ctx, resolver, results, dom, cfg.DNS.Threads, cfg.DNS.Validation == "flag")
 			
 			if verbose && !silentMode {
 				fmt.Printf("[+] %d subdomains verified via DNS\n", len(results))
+				printPoolStats(resolver.Pool())
+				printThrottleStats(resolver.Throttle())
+			}
+		}
+		
+		// Look for neighbours of the verified hosts in reverse DNS
+		if activeMode && cfg.PTRSweep.Enabled {
+			hits, err := sweepDomain(ctx, resolver, cfg, results, dom)
+			if err != nil {
+				return err
+			}
+			results = mergeResults(results, hits)
+		}
+		
+		results = screen(results)
+		
+		// See what the hosts serve over HTTP, then chase the names their
+		// certificates list
+		if prober != nil {
+			results = probeResults(ctx, prober, results)
+			results, err = harvestCertificates(ctx, resolver, prober, screen, results, dom, cfg)
+			if err != nil {
+				return err
+			}
+		}
+		
+		if scanner != nil {
+			results = scanResults(ctx, scanner, results)
+		}
+		
+		if enricher != nil {
+			results = enrichResults(enricher, results)
+		}
+		
+		// Conditions on addresses, status and the like can only be
+		// checked once everything is known
+		results = whereResults(where, results)
+		
+		allResults = append(allResults, results...)
+	}
+	
+	if resolver != nil && cfg.DNS.CacheFile != "" {
+		if err := resolver.SaveCache(cfg.DNS.CacheFile); err != nil && !silentMode {
+			fmt.Fprintf(os.Stderr, "[!] Warning: %v\n", err)
+		}
+	}
+	
+	return writeResults(cfg, allResults)
+}
+
+func runBrute(cmd *cobra.Command, args []string) error {
+	if domain == "" && domainList == "" {
+		return fmt.Errorf("either -d or --domain-list flag is required")
+	}
+	
+	cfg, err := config.Load(configPath)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	if jsonOutput {
+		cfg.Output.Format = "json"
+	}
+	if err := applyDNSFlags(cfg); err != nil {
+		return err
+	}
+	
+	resolver, err := newResolver(cfg)
+	if err != nil {
+		return err
+	}
+	defer reportThroughput(resolver)()
+	
+	enricher, err := openEnricher(cfg)
+	if err != nil {
+		return err
+	}
+	
+	classifier, err := newClassifier(cfg)
+	if err != nil {
+		return err
+	}
+	
+	sc, err := loadScope(cfg)
+	if err != nil {
+		return err
+	}
+	
+	where, err := newWhereFilter()
+	if err != nil {
+		return err
+	}
+	
+	domains, err := collectDomains()
+	if err != nil {
+		return err
+	}
+	
+	ctx := context.Background()
+	allResults := make([]runner.SubdomainResult, 0)
+	for _, dom := range domains {
+		if err := resolve.ValidateDomain(dom); err != nil {
+			if !silentMode {
+				fmt.Fprintf(os.Stderr, "[-] Invalid domain %s: %v\n", dom, err)
 			}
+			continue
 		}
 		
+		results, err := bruteforceDomain(ctx, resolver, dom, cfg.DNS.Threads)
+		if err != nil {
+			return err
+		}
+		if resolver.HasTrusted() {
+			results = verifyResults(ctx, resolver, results, dom, cfg.DNS.Threads, cfg.DNS.Validation == "flag")
+		}
+		results = screenScope(sc, selectFamily(results))
+		results = classifyResults(classifier, results, cfg.CDN.Exclude)
+		if enricher != nil {
+			results = enrichResults(enricher, results)
+		}
+		results = whereResults(where, results)
 		allResults = append(allResults, results...)
 	}
 	
+	if cfg.DNS.CacheFile != "" {
+		if err := resolver.SaveCache(cfg.DNS.CacheFile); err != nil && !silentMode {
+			fmt.Fprintf(os.Stderr, "[!] Warning: %v\n", err)
+		}
+	}
+	
+	return writeResults(cfg, allResults)
+}
+
+// collectDomains returns the target domains given with -d and --domain-list
+func collectDomains() ([]string, error) {
+	domains := make([]string, 0)
+	if domain != "" {
+		domains = append(domains, domain)
+	}
+	if domainList != "" {
+		fileDomains, err := readDomainsFromFile(domainList)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read domain list: %w", err)
+		}
+		domains = append(domains, fileDomains...)
+	}
+	
+	return domains, nil
+}
+
+// writeResults writes the results in the configured format
+func writeResults(cfg *config.Config, allResults []runner.SubdomainResult) error {
 	// Write output
 	if len(allResults) == 0 {
 		if !silentMode {
@@ -320,26 +757,965 @@ func run(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
-func initializeSources(cfg *config.ProviderConfig, sourceList, excludeSources string) ([]sources.Source, error) {
-	allSources := map[string]func(*sources.SourceConfig) sources.Source{
-		"crtsh":       func(c *sources.SourceConfig) sources.Source { return sources.NewCrtSh(c) },
-		"hackertarget": func(c *sources.SourceConfig) sources.Source { return sources.NewHackerTarget(c) },
-		"threatcrowd": func(c *sources.SourceConfig) sources.Source { return sources.NewThreatCrowd(c) },
-		"alienvault":  func(c *sources.SourceConfig) sources.Source { return sources.NewAlienVault(c) },
-		"urlscan":     func(c *sources.SourceConfig) sources.Source { return sources.NewURLScan(c) },
+func runQuota(cmd *cobra.Command, args []string) error {
+	cfg, err := config.Load(configPath)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to load config: %w", err)
 	}
 	
-	// Determine which sources to use
-	var sourcesToUse []string
-	if sourceList != "" {
-		sourcesToUse = strings.Split(sourceList, ",")
-		for i := range sourcesToUse {
-			sourcesToUse[i] = strings.TrimSpace(sourcesToUse[i])
+	providerCfg, err := config.LoadProviderConfig("provider-config.yaml")
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to load provider config: %w", err)
+	}
+	
+	ledger, err := openLedger(cfg, providerCfg)
+	if err != nil {
+		return err
+	}
+	
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "SOURCE\tKEY\tPERIOD\tUSED\tLIMIT\tREMAINING\tRESETS")
+	for _, name := range ledger.Sources() {
+		st := ledger.Status(name)
+		
+		key, limit, remaining := "-", "unlimited", "unlimited"
+		if st.Key != "" {
+			key = strings.TrimPrefix(st.Key, name+":")
 		}
-	} else {
-		// Use all sources
-		for name := range allSources {
-			sourcesToUse = append(sourcesToUse, name)
+		if st.Limit > 0 {
+			limit = fmt.Sprintf("%d", st.Limit)
+			remaining = fmt.Sprintf("%d", st.Remaining)
+		}
+		
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\t%s\t%s\n",
+			name, key, st.Period, st.Used, limit, remaining, st.Resets.Format(time.RFC3339))
+	}
+	
+	return w.Flush()
+}
+
+// openEnricher opens the GeoIP databases given by flags or config, or
+// returns nil when there are none
+func openEnricher(cfg *config.Config) (*geoip.Enricher, error) {
+	if geoipASN != "" {
+		cfg.GeoIP.ASNDatabase = geoipASN
+	}
+	if geoipCity != "" {
+		cfg.GeoIP.CityDatabase = geoipCity
+	}
+	if cfg.GeoIP.ASNDatabase == "" && cfg.GeoIP.CityDatabase == "" {
+		return nil, nil
+	}
+	
+	return geoip.Open(cfg.GeoIP.ASNDatabase, cfg.GeoIP.CityDatabase)
+}
+
+// enrichResults records what the GeoIP databases know about every address
+// of the results
+func enrichResults(enricher *geoip.Enricher, results []runner.SubdomainResult) []runner.SubdomainResult {
+	known := 0
+	for i := range results {
+		result := &results[i]
+		for _, ip := range result.IPs {
+			info := enricher.Lookup(ip)
+			if info.Empty() {
+				continue
+			}
+			result.Geo = append(result.Geo, runner.GeoInfo{
+				IP:      ip,
+				ASN:     info.ASN,
+				Org:     info.Org,
+				Country: info.Country,
+				City:    info.City,
+			})
+			known++
+		}
+	}
+	
+	if verbose && !silentMode {
+		fmt.Printf("[+] Found GeoIP data for %d addresses\n", known)
+	}
+	
+	return results
+}
+
+// loadScope loads the scope file given by flag or config, or returns nil
+// when there is none
+func loadScope(cfg *config.Config) (*scope.Scope, error) {
+	if scopeFile != "" {
+		cfg.ScopeFile = scopeFile
+	}
+	if cfg.ScopeFile == "" {
+		return nil, nil
+	}
+	
+	return scope.Load(cfg.ScopeFile)
+}
+
+// screenScope drops the results whose host or addresses are out of scope.
+// Results not resolved yet are judged by name only.
+func screenScope(sc *scope.Scope, results []runner.SubdomainResult) []runner.SubdomainResult {
+	if sc == nil {
+		return results
+	}
+	
+	kept := make([]runner.SubdomainResult, 0, len(results))
+	for _, result := range results {
+		if ok, _ := sc.Allowed(result.Host, result.IPs); ok {
+			kept = append(kept, result)
+		}
+	}
+	
+	if dropped := len(results) - len(kept); dropped > 0 && verbose && !silentMode {
+		fmt.Printf("[*] Dropped %d out-of-scope hosts\n", dropped)
+	}
+	
+	return kept
+}
+
+// newClassifier creates the provider classifier from the ranges file given
+// by flag or config, or the built-in ranges
+func newClassifier(cfg *config.Config) (*cdn.Classifier, error) {
+	if cdnRanges != "" {
+		cfg.CDN.RangesFile = cdnRanges
+	}
+	if excludeCDN {
+		cfg.CDN.Exclude = true
+	}
+	
+	var providers []cdn.Provider
+	if cfg.CDN.RangesFile != "" {
+		var err error
+		providers, err = cdn.LoadProviders(cfg.CDN.RangesFile)
+		if err != nil {
+			return nil, err
+		}
+	}
+	
+	return cdn.New(providers), nil
+}
+
+// classifyResults records the provider serving each result and, with
+// exclude, drops the results served from shared CDN edges
+func classifyResults(classifier *cdn.Classifier, results []runner.SubdomainResult, exclude bool) []runner.SubdomainResult {
+	kept := make([]runner.SubdomainResult, 0, len(results))
+	behindCDN := 0
+	for _, result := range results {
+		var chain []string
+		if result.DNS != nil {
+			chain = result.DNS.CNAMEChain
+		}
+		
+		if match := classifier.Classify(chain, result.IPs); match != nil {
+			result.Provider = match.Provider
+			result.CDN = match.CDN
+		}
+		if result.CDN {
+			behindCDN++
+			if exclude {
+				continue
+			}
+		}
+		kept = append(kept, result)
+	}
+	
+	if verbose && !silentMode && behindCDN > 0 {
+		if exclude {
+			fmt.Printf("[*] Excluded %d hosts behind CDNs\n", behindCDN)
+		} else {
+			fmt.Printf("[*] %d hosts are behind CDNs\n", behindCDN)
+		}
+	}
+	
+	return kept
+}
+
+// openLedger opens the usage ledger and registers the configured source quotas
+func openLedger(cfg *config.Config, providerCfg *config.ProviderConfig) (*quota.Ledger, error) {
+	path := cfg.QuotaFile
+	if path == "" {
+		path = quota.DefaultPath()
+	}
+	
+	ledger, err := quota.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	
+	for name, srcCfg := range providerCfg.Sources {
+		if srcCfg.Quota == nil || srcCfg.Quota.Limit <= 0 {
+			continue
+		}
+		
+		period, err := quota.ParsePeriod(srcCfg.Quota.Period)
+		if err != nil {
+			return nil, fmt.Errorf("invalid quota for %s: %w", name, err)
+		}
+		ledger.SetLimit(name, srcCfg.APIKey, srcCfg.Quota.Limit, period)
+	}
+	
+	return ledger, nil
+}
+
+// applyDNSFlags overrides the DNS settings in cfg with the CLI flags
+func applyDNSFlags(cfg *config.Config) error {
+	if dnsThreads > 0 {
+		cfg.DNS.Threads = dnsThreads
+	}
+	if dnsRateLimit > 0 {
+		cfg.DNS.RateLimit = dnsRateLimit
+	}
+	if dnsInFlight > 0 {
+		cfg.DNS.MaxInFlight = dnsInFlight
+	}
+	if recordTypeList != "" {
+		cfg.DNS.RecordTypes = strings.Split(recordTypeList, ",")
+	}
+	if ipv4Only && ipv6Only {
+		return fmt.Errorf("--ipv4-only and --ipv6-only are mutually exclusive")
+	}
+	if ipv4Only || ipv6Only {
+		// Telling single-stack hosts apart needs both families
+		cfg.DNS.RecordTypes = withRecordTypes(cfg.DNS.RecordTypes, "A", "AAAA")
+	}
+	if resolverList != "" {
+		servers, err := resolve.LoadServers(resolverList)
+		if err != nil {
+			return err
+		}
+		cfg.DNS.Servers = servers
+	}
+	if trustedList != "" {
+		cfg.DNS.TrustedServers = strings.Split(trustedList, ",")
+	}
+	if dnsCacheFile != "" {
+		cfg.DNS.CacheFile = dnsCacheFile
+	}
+	if validationMode != "" {
+		cfg.DNS.Validation = validationMode
+	}
+	if cfg.DNS.Validation != "discard" && cfg.DNS.Validation != "flag" {
+		return fmt.Errorf("invalid validation mode %q: must be discard or flag", cfg.DNS.Validation)
+	}
+	
+	return nil
+}
+
+// withRecordTypes adds the record types missing from types
+func withRecordTypes(types []string, required ...string) []string {
+	for _, want := range required {
+		found := false
+		for _, t := range types {
+			if strings.EqualFold(strings.TrimSpace(t), want) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			types = append(types, want)
+		}
+	}
+	
+	return types
+}
+
+// newResolver creates the resolver described by the DNS settings in cfg,
+// restoring its persisted cache and dropping servers that lie about NXDOMAIN
+func newResolver(cfg *config.Config) (*resolve.Resolver, error) {
+	recordTypes, err := parseRecordTypes(cfg.DNS.RecordTypes)
+	if err != nil {
+		return nil, err
+	}
+	
+	for _, server := range append(cfg.DNS.Servers, cfg.DNS.TrustedServers...) {
+		if _, err := resolve.ParseServer(server); err != nil {
+			return nil, err
+		}
+	}
+	
+	resolver := resolve.NewResolver(&resolve.Config{
+		Servers:        cfg.DNS.Servers,
+		TrustedServers: cfg.DNS.TrustedServers,
+		Timeout:        cfg.GetDNSTimeout(),
+		Retries:        cfg.DNS.Retry,
+		RecordTypes:    recordTypes,
+		RateLimit:      cfg.DNS.RateLimit,
+		MaxInFlight:    cfg.DNS.MaxInFlight,
+		CacheSize:      cfg.DNS.CacheSize,
+	})
+	
+	if cfg.DNS.CacheFile != "" {
+		if err := resolver.LoadCache(cfg.DNS.CacheFile); err != nil {
+			return nil, err
+		}
+	}
+	
+	// Drop resolvers that answer for names that cannot exist
+	banned := resolver.CheckServers(context.Background())
+	if len(banned) > 0 && verbose && !silentMode {
+		fmt.Printf("[!] Ignoring %d resolvers that rewrite NXDOMAIN: %v\n", len(banned), banned)
+	}
+	
+	return resolver, nil
+}
+
+// verifyResults resolves every result concurrently and keeps the hosts that
+// exist and don't merely match the domain's wildcard
+func verifyResults(ctx context.Context, resolver *resolve.Resolver, results []runner.SubdomainResult, dom string, threads int, flagOnly bool) []runner.SubdomainResult {
+	// Probe the apex up front; deeper zones are probed as hosts need them
+	isWildcard, wildcardIPs, _ := resolver.DetectWildcard(ctx, dom)
+	if isWildcard && verbose && !silentMode {
+		fmt.Printf("[!] Wildcard DNS detected for %s: %v\n", dom, wildcardIPs)
+	}
+	
+	hosts := make([]string, len(results))
+	for i, result := range results {
+		hosts[i] = result.Host
+	}
+	
+	resolved, err := resolver.ResolveMany(ctx, hosts, threads)
+	if err != nil && !silentMode {
+		fmt.Fprintf(os.Stderr, "[-] DNS verification interrupted: %v\n", err)
+	}
+	
+	byHost := make(map[string]*resolve.Result, len(resolved))
+	for _, res := range resolved {
+		byHost[res.Host] = res
+	}
+	
+	verifiedResults := make([]runner.SubdomainResult, 0)
+	positives := make([]*resolve.Result, 0)
+	dangling := make([]runner.SubdomainResult, 0)
+	for _, result := range results {
+		res, ok := byHost[result.Host]
+		if !ok {
+			continue
+		}
+		
+		// Keep takeover candidates whose CNAME target is gone
+		if !res.Exists {
+			if result.TakeoverCandidate {
+				result.DNS = dnsInfo(res)
+				dangling = append(dangling, result)
+			}
+			continue
+		}
+		
+		// Reuse the answer for the wildcard check instead of resolving again
+		if resolver.IsWildcardResult(ctx, res, dom) {
+			continue
+		}
+		
+		setIPs(&result, res.IPs)
+		result.DNS = dnsInfo(res)
+		verifiedResults = append(verifiedResults, result)
+		positives = append(positives, res)
+	}
+	
+	if verbose && !silentMode {
+		if zones := resolver.WildcardZones(); len(zones) > 0 {
+			fmt.Printf("[!] Wildcard zones filtered: %v\n", zones)
+		}
+	}
+	
+	if resolver.HasTrusted() {
+		verifiedResults = validateResults(ctx, resolver, verifiedResults, positives, threads, flagOnly)
+	}
+	
+	return append(verifiedResults, dangling...)
+}
+
+// validateResults re-checks verified hosts against the trusted resolvers and
+// records the verdict on each result. Hosts the trusted resolvers don't find
+// are dropped unless flagOnly is set; hosts whose answers merely differ are
+// always kept, as geo-DNS and CDNs answer different resolvers differently.
+func validateResults(ctx context.Context, resolver *resolve.Resolver, results []runner.SubdomainResult, positives []*resolve.Result, threads int, flagOnly bool) []runner.SubdomainResult {
+	if verbose && !silentMode {
+		fmt.Printf("[*] Validating %d hosts against trusted resolvers...\n", len(results))
+	}
+	
+	validations := resolver.ValidateMany(ctx, positives, threads)
+	
+	validated := make([]runner.SubdomainResult, 0, len(results))
+	discarded, flagged := 0, 0
+	for i, result := range results {
+		v := validations[i]
+		result.DNS.Validation = v.Verdict
+		if v.Refuted() && !flagOnly {
+			discarded++
+			continue
+		}
+		if v.Disagrees() {
+			flagged++
+		}
+		validated = append(validated, result)
+	}
+	
+	if verbose && !silentMode {
+		if discarded > 0 {
+			fmt.Printf("[!] %d hosts discarded after trusted resolvers found no such records\n", discarded)
+		}
+		if flagged > 0 {
+			fmt.Printf("[!] %d hosts flagged after trusted resolvers disagreed\n", flagged)
+		}
+	}
+	
+	return validated
+}
+
+// checkTakeovers resolves every result and marks the hosts whose CNAME chain
+// points at a resource that can be claimed
+func checkTakeovers(ctx context.Context, resolver *resolve.Resolver, checker *takeover.Checker, sc *scope.Scope, results []runner.SubdomainResult, threads int) []runner.SubdomainResult {
+	hosts := make([]string, len(results))
+	for i, result := range results {
+		hosts[i] = result.Host
+	}
+	
+	resolved, err := resolver.ResolveMany(ctx, hosts, threads)
+	if err != nil && !silentMode {
+		fmt.Fprintf(os.Stderr, "[-] Takeover check interrupted: %v\n", err)
+	}
+	
+	// Body checks fetch from the host, so leave out hosts whose addresses
+	// are out of scope
+	if sc != nil {
+		inScope := make([]*resolve.Result, 0, len(resolved))
+		for _, res := range resolved {
+			if ok, _ := sc.Allowed(res.Host, res.IPs); ok {
+				inScope = append(inScope, res)
+			}
+		}
+		resolved = inScope
+	}
+	
+	candidates := make(map[string]*takeover.Finding)
+	for _, finding := range checker.CheckMany(ctx, resolved, threads) {
+		if finding == nil {
+			continue
+		}
+		candidates[finding.Host] = finding
+		if !silentMode {
+			fmt.Fprintf(os.Stderr, "[!] Possible takeover: %s -> %s (%s, %s)\n",
+				finding.Host, finding.Target, finding.Service, finding.Evidence)
+		}
+	}
+	
+	for i := range results {
+		if finding, ok := candidates[results[i].Host]; ok {
+			results[i].TakeoverCandidate = true
+			results[i].TakeoverService = finding.Service
+		}
+	}
+	
+	return results
+}
+
+// probeResults probes every result that has addresses over HTTP and records
+// what answered
+func probeResults(ctx context.Context, prober *probe.Prober, results []runner.SubdomainResult) []runner.SubdomainResult {
+	if verbose && !silentMode {
+		fmt.Printf("[*] Probing HTTP services...\n")
+	}
+	
+	indexes := make([]int, 0, len(results))
+	hosts := make([]string, 0, len(results))
+	for i, result := range results {
+		if len(result.IPs) > 0 {
+			indexes = append(indexes, i)
+			hosts = append(hosts, result.Host)
+		}
+	}
+	
+	live := 0
+	for j, responses := range prober.ProbeMany(ctx, hosts) {
+		if len(responses) == 0 {
+			continue
+		}
+		live++
+		
+		result := &results[indexes[j]]
+		for _, resp := range responses {
+			info := runner.HTTPInfo{
+				URL:           resp.URL,
+				StatusCode:    resp.StatusCode,
+				Title:         resp.Title,
+				ContentLength: resp.ContentLength,
+				Redirects:     resp.Redirects,
+				Server:        resp.Server,
+				TLSVersion:    resp.TLSVersion,
+			}
+			if resp.FinalURL != resp.URL {
+				info.FinalURL = resp.FinalURL
+			}
+			if cert := resp.Certificate; cert != nil {
+				info.Certificate = &runner.CertInfo{
+					Subject:     cert.Subject,
+					Issuer:      cert.Issuer,
+					Expires:     cert.NotAfter,
+					Fingerprint: cert.Fingerprint,
+					Names:       cert.Names,
+				}
+			}
+			result.HTTP = append(result.HTTP, info)
+		}
+	}
+	
+	if verbose && !silentMode {
+		fmt.Printf("[+] %d of %d hosts serve HTTP\n", live, len(hosts))
+	}
+	
+	return results
+}
+
+// scanResults checks the TCP ports of every address of the results and
+// records the open ones on each host
+func scanResults(ctx context.Context, scanner *portscan.Scanner, results []runner.SubdomainResult) []runner.SubdomainResult {
+	if verbose && !silentMode {
+		fmt.Printf("[*] Checking TCP ports...\n")
+	}
+	
+	ips := make([]string, 0)
+	for _, result := range results {
+		ips = append(ips, result.IPs...)
+	}
+	open, stats := scanner.Scan(ctx, ips)
+	
+	for i := range results {
+		result := &results[i]
+		seen := make(map[int]bool)
+		for _, ip := range result.IPs {
+			for _, port := range open[ip] {
+				if !seen[port] {
+					seen[port] = true
+					result.Ports = append(result.Ports, port)
+				}
+			}
+		}
+		sort.Ints(result.Ports)
+	}
+	
+	if verbose && !silentMode {
+		fmt.Printf("[+] %d open ports on %d addresses (%d connection attempts)\n", stats.Open, stats.Addresses, stats.Attempts)
+	}
+	
+	return results
+}
+
+// harvestCertificates resolves and probes the names under dom found in the
+// certificates of probed hosts, repeating for the certificates of the new
+// hosts until no new names turn up or maxHarvestRounds is reached
+func harvestCertificates(ctx context.Context, resolver *resolve.Resolver, prober *probe.Prober, screen func([]runner.SubdomainResult) []runner.SubdomainResult, results []runner.SubdomainResult, dom string, cfg *config.Config) ([]runner.SubdomainResult, error) {
+	tried := make(map[string]bool, len(results))
+	for _, result := range results {
+		tried[result.Host] = true
+	}
+	
+	fresh := results
+	for round := 0; round < maxHarvestRounds; round++ {
+		candidates := make([]runner.SubdomainResult, 0)
+		for _, name := range certificateNames(fresh, dom) {
+			if tried[name] {
+				continue
+			}
+			tried[name] = true
+			candidates = append(candidates, runner.SubdomainResult{
+				Host:      name,
+				Source:    probe.CertSourceName,
+				Timestamp: time.Now(),
+			})
+		}
+		if len(candidates) == 0 {
+			break
+		}
+		
+		candidates, err := filterResults(candidates)
+		if err != nil {
+			return nil, err
+		}
+		if verbose && !silentMode {
+			fmt.Printf("[*] Resolving %d names found in certificates...\n", len(candidates))
+		}
+		
+		fresh = verifyResults(ctx, resolver, candidates, dom, cfg.DNS.Threads, cfg.DNS.Validation == "flag")
+		fresh = probeResults(ctx, prober, screen(fresh))
+		results = mergeResults(results, fresh)
+	}
+	
+	return results, nil
+}
+
+// certificateNames returns the names under dom listed in the certificates
+// the results presented
+func certificateNames(results []runner.SubdomainResult, dom string) []string {
+	names := make([]string, 0)
+	for _, result := range results {
+		for _, info := range result.HTTP {
+			if info.Certificate != nil {
+				names = append(names, info.Certificate.Names...)
+			}
+		}
+	}
+	
+	return probe.NamesUnder(names, dom)
+}
+
+// bruteforceDomain resolves every word in the wordlist under dom and returns
+// the hits as results
+func bruteforceDomain(ctx context.Context, resolver *resolve.Resolver, dom string, threads int) ([]runner.SubdomainResult, error) {
+	file, err := os.Open(wordlistPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open wordlist: %w", err)
+	}
+	defer file.Close()
+	
+	if verbose && !silentMode {
+		fmt.Printf("[*] Brute-forcing %s with %s...\n", dom, wordlistPath)
+	}
+	
+	hits, stats, err := bruteforce.Run(ctx, resolver, file, dom, threads)
+	if err != nil {
+		return nil, err
+	}
+	
+	if verbose && !silentMode {
+		fmt.Printf("[+] Brute force tried %d names: %d resolved, %d wildcard answers dropped\n",
+			stats.Candidates, stats.Resolved, stats.Wildcards)
+	}
+	
+	return hitResults(hits, bruteforce.SourceName), nil
+}
+
+// permuteDomain resolves alterations of the hosts found for dom and returns
+// the hits as results
+func permuteDomain(ctx context.Context, resolver *resolve.Resolver, permuter *permute.Generator, results []runner.SubdomainResult, dom string, threads int) ([]runner.SubdomainResult, error) {
+	hosts := make([]string, len(results))
+	for i, result := range results {
+		hosts[i] = result.Host
+	}
+	
+	hits, stats, err := permuter.Run(ctx, resolver, hosts, dom, threads)
+	if err != nil {
+		return nil, err
+	}
+	
+	if verbose && !silentMode {
+		fmt.Printf("[+] Permutations tried %d names: %d resolved, %d wildcard answers dropped\n",
+			stats.Candidates, stats.Resolved, stats.Wildcards)
+	}
+	
+	return hitResults(hits, permute.SourceName), nil
+}
+
+// filterResults keeps the results whose host passes the match and filter
+// patterns
+func filterResults(results []runner.SubdomainResult) ([]runner.SubdomainResult, error) {
+	if matchPattern == "" && filterPattern == "" {
+		return results, nil
+	}
+	
+	f := filter.NewFilter()
+	
+	if matchPattern != "" {
+		if err := f.AddMatchPattern(matchPattern); err != nil {
+			return nil, fmt.Errorf("invalid match pattern: %w", err)
+		}
+	}
+	
+	if filterPattern != "" {
+		if err := f.AddExcludePattern(filterPattern); err != nil {
+			return nil, fmt.Errorf("invalid filter pattern: %w", err)
+		}
+	}
+	
+	return f.Apply(results), nil
+}
+
+// newWhereFilter parses the --where expression, returning nil if there is
+// none
+func newWhereFilter() (*filter.Filter, error) {
+	if whereExpr == "" {
+		return nil, nil
+	}
+	
+	f := filter.NewFilter()
+	if err := f.AddExpr(whereExpr); err != nil {
+		return nil, fmt.Errorf("invalid --where: %w", err)
+	}
+	
+	return f, nil
+}
+
+// whereResults keeps the results satisfying the --where expression
+func whereResults(where *filter.Filter, results []runner.SubdomainResult) []runner.SubdomainResult {
+	if where == nil {
+		return results
+	}
+	
+	kept := where.Apply(results)
+	if verbose && !silentMode {
+		fmt.Printf("[+] %d of %d subdomains match --where\n", len(kept), len(results))
+	}
+	
+	return kept
+}
+
+// sweepDomain reverse-resolves the ranges around the addresses of results and
+// returns the names under dom that aren't known yet and check out. PTR
+// records are easily stale or made up, so a name goes through the same
+// verification as any other candidate and is only kept if it resolves back
+// to an address it was found on.
+func sweepDomain(ctx context.Context, resolver *resolve.Resolver, cfg *config.Config, results []runner.SubdomainResult, dom string) ([]runner.SubdomainResult, error) {
+	ips := make([]string, 0)
+	known := make(map[string]bool, len(results))
+	for _, result := range results {
+		ips = append(ips, result.IPs...)
+		known[result.Host] = true
+	}
+	
+	hits, stats, err := ptr.Run(ctx, resolver, ips, dom, ptr.Options{
+		IPv4Prefix: cfg.PTRSweep.IPv4Prefix,
+		IPv6Prefix: cfg.PTRSweep.IPv6Prefix,
+		Threads:    cfg.DNS.Threads,
+	})
+	if err != nil {
+		return nil, err
+	}
+	
+	if verbose && !silentMode {
+		fmt.Printf("[+] PTR sweep queried %d addresses in %d ranges: %d answered, %d failed, %d names under %s\n",
+			stats.Addresses, stats.Ranges, stats.Answered, stats.Failed, len(hits), dom)
+	}
+	
+	// A host can point back from several addresses
+	swept := make(map[string]map[string]bool)
+	candidates := make([]runner.SubdomainResult, 0, len(hits))
+	for _, hit := range hits {
+		if known[hit.Host] {
+			continue
+		}
+		if swept[hit.Host] == nil {
+			swept[hit.Host] = make(map[string]bool)
+			candidates = append(candidates, runner.SubdomainResult{
+				Host:      hit.Host,
+				Source:    ptr.SourceName,
+				Timestamp: time.Now(),
+			})
+		}
+		swept[hit.Host][hit.IP] = true
+	}
+	if len(candidates) == 0 {
+		return candidates, nil
+	}
+	
+	candidates, err = filterResults(candidates)
+	if err != nil {
+		return nil, err
+	}
+	
+	confirmed := make([]runner.SubdomainResult, 0, len(candidates))
+	for _, result := range verifyResults(ctx, resolver, candidates, dom, cfg.DNS.Threads, cfg.DNS.Validation == "flag") {
+		for _, ip := range result.IPs {
+			if swept[result.Host][ip] {
+				confirmed = append(confirmed, result)
+				break
+			}
+		}
+	}
+	
+	if verbose && !silentMode {
+		fmt.Printf("[+] %d of %d names from reverse DNS resolve back to their addresses\n", len(confirmed), len(candidates))
+	}
+	
+	return confirmed, nil
+}
+
+// hitResults converts resolved hits into results from source
+func hitResults(hits []*resolve.Result, source string) []runner.SubdomainResult {
+	results := make([]runner.SubdomainResult, 0, len(hits))
+	for _, hit := range hits {
+		result := runner.SubdomainResult{
+			Host:      hit.Host,
+			Source:    source,
+			Timestamp: time.Now(),
+			DNS:       dnsInfo(hit),
+		}
+		setIPs(&result, hit.IPs)
+		results = append(results, result)
+	}
+	
+	return results
+}
+
+// setIPs records the addresses of a result, split by family
+func setIPs(result *runner.SubdomainResult, ips []string) {
+	result.IPs = ips
+	result.IPv4, result.IPv6 = resolve.SplitIPs(ips)
+}
+
+// selectFamily keeps the results that only have addresses of the family
+// chosen with --ipv4-only or --ipv6-only
+func selectFamily(results []runner.SubdomainResult) []runner.SubdomainResult {
+	if !ipv4Only && !ipv6Only {
+		return results
+	}
+	
+	selected := make([]runner.SubdomainResult, 0, len(results))
+	for _, result := range results {
+		if ipv4Only && len(result.IPv4) > 0 && len(result.IPv6) == 0 ||
+			ipv6Only && len(result.IPv6) > 0 && len(result.IPv4) == 0 {
+			selected = append(selected, result)
+		}
+	}
+	
+	return selected
+}
+
+// mergeResults appends the extra results whose host isn't already known
+func mergeResults(results, extra []runner.SubdomainResult) []runner.SubdomainResult {
+	seen := make(map[string]bool, len(results))
+	for _, result := range results {
+		seen[result.Host] = true
+	}
+	
+	for _, result := range extra {
+		if !seen[result.Host] {
+			seen[result.Host] = true
+			results = append(results, result)
+		}
+	}
+	
+	return results
+}
+
+// dnsInfo converts a resolver answer into the DNS block of a result
+func dnsInfo(res *resolve.Result) *runner.DNSInfo {
+	info := &runner.DNSInfo{
+		Rcode:      resolve.RcodeString(res.Rcode),
+		Records:    make(map[string][]runner.DNSRecord),
+		CNAMEChain: res.CNAMEChain(),
+	}
+	
+	for rtype, records := range res.RecordsByType() {
+		for _, rr := range records {
+			info.Records[rtype] = append(info.Records[rtype], runner.DNSRecord{
+				Name:  rr.Name,
+				Value: rr.Data,
+				TTL:   rr.TTL,
+			})
+		}
+	}
+	
+	return info
+}
+
+// parseRecordTypes parses record type names such as "A,AAAA,MX"
+func parseRecordTypes(names []string) ([]uint16, error) {
+	types := make([]uint16, 0, len(names))
+	for _, name := range names {
+		if strings.TrimSpace(name) == "" {
+			continue
+		}
+		t, err := resolve.ParseType(name)
+		if err != nil {
+			return nil, err
+		}
+		types = append(types, t)
+	}
+	return types, nil
+}
+
+// printPoolStats prints per-resolver query statistics
+func printPoolStats(pool *resolve.Pool) {
+	for _, st := range pool.Stats() {
+		state := "ok"
+		switch {
+		case st.Banned:
+			state = "banned"
+		case st.Evicted:
+			state = "evicted"
+		}
+		fmt.Printf("    %-24s queries=%d errors=%d latency=%v %s\n",
+			st.Addr, st.Queries, st.Errors, st.Latency.Round(time.Millisecond), state)
+	}
+}
+
+// printThrottleStats prints the query rate, loss and concurrency of the
+// resolver's throttle
+func printThrottleStats(throttle *resolve.Throttle) {
+	if throttle == nil {
+		return
+	}
+	
+	st := throttle.Stats()
+	fmt.Printf("[*] DNS: %.0f queries/s, %.1f%% loss, %d/%d in flight (%d queries, %d failed)\n",
+		st.QPS, st.Loss*100, st.Limit, st.Max, st.Queries, st.Failures)
+}
+
+// reportThroughput prints the throttle statistics periodically in verbose
+// mode until the returned function is called
+func reportThroughput(resolver *resolve.Resolver) func() {
+	if !verbose || silentMode || resolver.Throttle() == nil {
+		return func() {}
+	}
+	
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(10 * time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				printThrottleStats(resolver.Throttle())
+			case <-done:
+				return
+			}
+		}
+	}()
+	
+	return func() { close(done) }
+}
+
+// activeSources contact the target's own servers, so they only run when asked for
+var activeSources = map[string]bool{"axfr": true, "nsec": true}
+
+// selectsActiveSources reports whether sourceList names an active source
+func selectsActiveSources(sourceList string) bool {
+	for _, name := range strings.Split(sourceList, ",") {
+		if activeSources[strings.TrimSpace(name)] {
+			return true
+		}
+	}
+	return false
+}
+
+// initializeSources builds the selected sources, charging the requests they
+// send to budget. Sources that look up DNS records do so through resolver,
+// or the system's servers when it is nil, and only contact nameservers sc
+// allows.
+func initializeSources(cfg *config.ProviderConfig, sourceList, excludeSources string, budget sources.Budget, resolver *resolve.Resolver, sc *scope.Scope) ([]sources.Source, error) {
+	allSources := map[string]func(*sources.SourceConfig) sources.Source{
+		"crtsh":       func(c *sources.SourceConfig) sources.Source { return sources.NewCrtSh(c) },
+		"hackertarget": func(c *sources.SourceConfig) sources.Source { return sources.NewHackerTarget(c) },
+		"threatcrowd": func(c *sources.SourceConfig) sources.Source { return sources.NewThreatCrowd(c) },
+		"alienvault":  func(c *sources.SourceConfig) sources.Source { return sources.NewAlienVault(c) },
+		"urlscan":     func(c *sources.SourceConfig) sources.Source { return sources.NewURLScan(c) },
+		"axfr":        func(c *sources.SourceConfig) sources.Source { return sources.NewAXFR(c) },
+		"nsec":        func(c *sources.SourceConfig) sources.Source { return sources.NewNSECWalk(c) },
+	}
+	
+	// Determine which sources to use
+	var sourcesToUse []string
+	if sourceList != "" {
+		sourcesToUse = strings.Split(sourceList, ",")
+		for i := range sourcesToUse {
+			sourcesToUse[i] = strings.TrimSpace(sourcesToUse[i])
+		}
+	} else {
+		// Use all sources
+		for name := range allSources {
+			if !activeSources[name] {
+				sourcesToUse = append(sourcesToUse, name)
+			}
 		}
 	}
 	
@@ -377,6 +1753,9 @@ func initializeSources(cfg *config.ProviderConfig, sourceList, excludeSources st
 		}
 		
 		srcCfg := cfg.GetSourceConfig(name)
+		srcCfg.Budget = budget
+		srcCfg.Resolver = resolver
+		srcCfg.Scope = sc
 		src := factory(srcCfg)
 		
 		// Check if source needs API key
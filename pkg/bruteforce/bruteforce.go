@@ -0,0 +1,126 @@
+package bruteforce
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/yourusername/subrecon/internal/resolve"
+)
+
+// SourceName is the source recorded for hosts found by brute force
+const SourceName = "bruteforce"
+
+// Stats summarizes a brute-force run
+type Stats struct {
+	Candidates int // names generated from the wordlist
+	Resolved   int // names that exist
+	Wildcards  int // existing names dropped as wildcard answers
+}
+
+// Candidates streams "word.domain" names built from a wordlist, one word per
+// line. Blank lines, comments and words that aren't valid DNS labels are
+// skipped. The channel is closed at the end of the input or when ctx is
+// cancelled; the returned function reports any read error once the channel
+// has been drained.
+func Candidates(ctx context.Context, wordlist io.Reader, domain string) (<-chan string, func() error) {
+	names := make(chan string)
+	domain = resolve.CanonicalName(domain)
+	var scanErr error
+
+	go func() {
+		defer close(names)
+
+		scanner := bufio.NewScanner(wordlist)
+		for scanner.Scan() {
+			word := strings.ToLower(strings.TrimSpace(scanner.Text()))
+			word = strings.Trim(word, ".")
+			if word == "" || strings.HasPrefix(word, "#") || !validWord(word) {
+				continue
+			}
+
+			select {
+			case names <- word + "." + domain:
+			case <-ctx.Done():
+				return
+			}
+		}
+
+		if err := scanner.Err(); err != nil {
+			scanErr = fmt.Errorf("failed to read wordlist: %w", err)
+		}
+	}()
+
+	return names, func() error { return scanErr }
+}
+
+// Run resolves a candidate for every word through the resolver pool and
+// returns the hosts that exist and aren't answered by a wildcard, sorted by
+// name. The wordlist is read as it is resolved, so it can be arbitrarily
+// large.
+func Run(ctx context.Context, resolver *resolve.Resolver, wordlist io.Reader, domain string, threads int) ([]*resolve.Result, *Stats, error) {
+	stats := &Stats{}
+
+	// Probe the apex before the flood of lookups starts
+	resolver.Fingerprint(ctx, domain)
+
+	names, readErr := Candidates(ctx, wordlist, domain)
+
+	// Count candidates on their way to the resolver
+	counted := make(chan string)
+	go func() {
+		defer close(counted)
+		for name := range names {
+			stats.Candidates++
+			counted <- name
+		}
+	}()
+
+	hits := make([]*resolve.Result, 0)
+	for result := range resolver.ResolveStream(ctx, counted, threads) {
+		if !result.Exists {
+			continue
+		}
+		stats.Resolved++
+
+		if resolver.IsWildcardResult(ctx, result, domain) {
+			stats.Wildcards++
+			continue
+		}
+		hits = append(hits, result)
+	}
+
+	// Let the candidate generator finish so its error is visible
+	for range counted {
+	}
+
+	sort.Slice(hits, func(i, j int) bool {
+		return hits[i].Host < hits[j].Host
+	})
+
+	if err := ctx.Err(); err != nil {
+		return hits, stats, err
+	}
+
+	return hits, stats, readErr()
+}
+
+// validWord reports whether word can be prepended to a domain as one or more
+// DNS labels
+func validWord(word string) bool {
+	for _, label := range strings.Split(word, ".") {
+		if label == "" || len(label) > 63 {
+			return false
+		}
+		for _, c := range label {
+			if !(c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '-' || c == '_') {
+				return false
+			}
+		}
+	}
+
+	return true
+}
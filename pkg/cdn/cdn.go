@@ -0,0 +1,162 @@
+package cdn
+
+import (
+	_ "embed"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+
+	"github.com/yourusername/subrecon/internal/resolve"
+	"gopkg.in/yaml.v3"
+)
+
+// Evidence recorded on matches
+const (
+	EvidenceCNAME = "cname" // a CNAME target belongs to the provider
+	EvidenceIP    = "ip"    // an address lies in the provider's ranges
+)
+
+//go:embed providers.yaml
+var defaultProviders []byte
+
+// Provider is a CDN or hosting provider and how to recognize it
+type Provider struct {
+	Name   string   `yaml:"provider"`
+	CDN    bool     `yaml:"cdn"`    // addresses are edges shared between customers
+	CNAMEs []string `yaml:"cname"`  // suffixes of CNAME targets belonging to the provider
+	Ranges []string `yaml:"ranges"` // CIDR ranges announced by the provider
+}
+
+// Match is the provider a host was attributed to
+type Match struct {
+	Provider string
+	CDN      bool
+	Evidence string // EvidenceCNAME or EvidenceIP
+}
+
+// Classifier attributes hosts to providers
+type Classifier struct {
+	providers []Provider
+	ranges    []providerRange
+}
+
+// providerRange is one parsed range of a provider
+type providerRange struct {
+	network  *net.IPNet
+	provider int // index into providers
+}
+
+// DefaultProviders returns the built-in provider list
+func DefaultProviders() []Provider {
+	providers, err := ParseProviders(defaultProviders)
+	if err != nil {
+		panic(fmt.Sprintf("cdn: invalid built-in providers: %v", err))
+	}
+	return providers
+}
+
+// LoadProviders reads a provider list from a YAML file
+func LoadProviders(path string) ([]Provider, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read provider ranges: %w", err)
+	}
+
+	providers, err := ParseProviders(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse provider ranges %s: %w", path, err)
+	}
+
+	return providers, nil
+}
+
+// ParseProviders parses a YAML list of providers. Every entry needs a name
+// and at least one CNAME suffix or range.
+func ParseProviders(data []byte) ([]Provider, error) {
+	var providers []Provider
+	if err := yaml.Unmarshal(data, &providers); err != nil {
+		return nil, err
+	}
+
+	for i := range providers {
+		p := &providers[i]
+		if p.Name == "" {
+			return nil, fmt.Errorf("provider %d has no name", i+1)
+		}
+		if len(p.CNAMEs) == 0 && len(p.Ranges) == 0 {
+			return nil, fmt.Errorf("provider %s has no cname suffixes or ranges", p.Name)
+		}
+		for j, suffix := range p.CNAMEs {
+			p.CNAMEs[j] = resolve.CanonicalName(strings.TrimPrefix(suffix, "."))
+		}
+		for _, cidr := range p.Ranges {
+			if _, _, err := net.ParseCIDR(cidr); err != nil {
+				return nil, fmt.Errorf("provider %s: invalid range %q", p.Name, cidr)
+			}
+		}
+	}
+
+	return providers, nil
+}
+
+// New creates a classifier using providers, or the built-in list when nil.
+// The ranges must be valid, as ParseProviders ensures.
+func New(providers []Provider) *Classifier {
+	if providers == nil {
+		providers = DefaultProviders()
+	}
+
+	c := &Classifier{providers: providers}
+	for i, p := range providers {
+		for _, cidr := range p.Ranges {
+			_, network, err := net.ParseCIDR(cidr)
+			if err != nil {
+				continue
+			}
+			c.ranges = append(c.ranges, providerRange{network: network, provider: i})
+		}
+	}
+
+	return c
+}
+
+// Classify attributes a host to a provider from its CNAME chain and
+// addresses. CNAME targets are tried first, from the end of the chain, as
+// they name the service the owner signed up for; otherwise the most
+// specific range containing any of the addresses decides. Nil means no
+// provider matched.
+func (c *Classifier) Classify(chain []string, ips []string) *Match {
+	for i := len(chain) - 1; i >= 0; i-- {
+		target := resolve.CanonicalName(chain[i])
+		for _, p := range c.providers {
+			for _, suffix := range p.CNAMEs {
+				if target == suffix || strings.HasSuffix(target, "."+suffix) {
+					return &Match{Provider: p.Name, CDN: p.CDN, Evidence: EvidenceCNAME}
+				}
+			}
+		}
+	}
+
+	best, bestOnes := -1, -1
+	for _, s := range ips {
+		ip := net.ParseIP(s)
+		if ip == nil {
+			continue
+		}
+		for _, r := range c.ranges {
+			if !r.network.Contains(ip) {
+				continue
+			}
+			if ones, _ := r.network.Mask.Size(); ones > bestOnes {
+				best, bestOnes = r.provider, ones
+			}
+		}
+	}
+	if best < 0 {
+		return nil
+	}
+
+	p := c.providers[best]
+	return &Match{Provider: p.Name, CDN: p.CDN, Evidence: EvidenceIP}
+}
@@ -5,7 +5,7 @@ import (
 	"os"
 	"time"
 
-	"github.com/yourusername/subfinder-pro/pkg/sources"
+	"github.com/yourusername/subrecon/pkg/sources"
 	"gopkg.in/yaml.v3"
 )
 
@@ -17,14 +17,81 @@ type Config struct {
 	DNS        DNSConfig `yaml:"dns"`
 	Output     OutputConfig `yaml:"output"`
 	HTTP       HTTPConfig `yaml:"http"`
+	QuotaFile  string   `yaml:"quota_file"` // usage ledger path, empty for default
+	ScopeFile  string   `yaml:"scope_file"` // hosts and ranges that may be touched, empty for no limits
+	Permutation PermutationConfig `yaml:"permutation"`
+	PTRSweep   PTRSweepConfig `yaml:"ptr_sweep"`
+	Takeover   TakeoverConfig `yaml:"takeover"`
+	Probe      ProbeConfig `yaml:"probe"`
+	GeoIP      GeoIPConfig `yaml:"geoip"`
+	CDN        CDNConfig `yaml:"cdn"`
+	PortScan   PortScanConfig `yaml:"port_scan"`
 }
 
 // DNSConfig holds DNS resolver configuration
 type DNSConfig struct {
-	Enabled bool     `yaml:"enabled"`
-	Servers []string `yaml:"servers"`
-	Timeout int      `yaml:"timeout"`
-	Retry   int      `yaml:"retry"`
+	Enabled        bool     `yaml:"enabled"`
+	Servers        []string `yaml:"servers"`
+	Timeout        int      `yaml:"timeout"`
+	Retry          int      `yaml:"retry"`
+	Threads        int      `yaml:"threads"`         // concurrent lookups during verification
+	RateLimit      int      `yaml:"rate_limit"`      // queries per second across all lookups, 0 means unlimited
+	MaxInFlight    int      `yaml:"max_in_flight"`   // most queries in flight, lowered while timeouts climb; 0 means unbounded
+	RecordTypes    []string `yaml:"record_types"`    // e.g. A, AAAA, CNAME, MX, TXT, NS
+	TrustedServers []string `yaml:"trusted_servers"` // re-check positive answers against these
+	Validation     string   `yaml:"validation"`      // discard or flag hosts the trusted servers don't find; mismatches are only flagged
+	CacheSize      int      `yaml:"cache_size"`      // hosts kept in the positive and negative caches
+	CacheFile      string   `yaml:"cache_file"`      // persist answers between runs, empty to disable
+}
+
+// PermutationConfig holds settings for generating alterations of found hosts
+type PermutationConfig struct {
+	Enabled   bool     `yaml:"enabled"`
+	Words     []string `yaml:"words"`      // words to insert and join, defaults to common environment names
+	WordsFile string   `yaml:"words_file"` // file with one word per line, replaces words
+}
+
+// PTRSweepConfig holds settings for reverse DNS sweeps around resolved addresses
+type PTRSweepConfig struct {
+	Enabled    bool `yaml:"enabled"`
+	IPv4Prefix int  `yaml:"ipv4_prefix"` // IPv4 addresses are swept in ranges of this size
+	IPv6Prefix int  `yaml:"ipv6_prefix"` // IPv6 addresses are swept in ranges of this size
+}
+
+// TakeoverConfig holds settings for subdomain takeover detection
+type TakeoverConfig struct {
+	Enabled      bool   `yaml:"enabled"`
+	Fingerprints string `yaml:"fingerprints"` // YAML fingerprint file, empty for the built-in set
+}
+
+// ProbeConfig holds settings for probing resolved hosts over HTTP
+type ProbeConfig struct {
+	Enabled         bool  `yaml:"enabled"`
+	Ports           []int `yaml:"ports"`            // tried over https and/or http, see the README
+	Threads         int   `yaml:"threads"`          // concurrent requests
+	RateLimit       int   `yaml:"rate_limit"`       // requests per second, 0 means unlimited
+	Timeout         int   `yaml:"timeout"`          // per request in seconds, including redirects
+	FollowRedirects bool  `yaml:"follow_redirects"` // also follow redirects to other hosts
+}
+
+// PortScanConfig holds settings for TCP connect checks on resolved addresses
+type PortScanConfig struct {
+	Ports     string `yaml:"ports"`      // top-100 or a list such as 80,443,8000-8100; empty disables the scan
+	Threads   int    `yaml:"threads"`    // concurrent connection attempts
+	RateLimit int    `yaml:"rate_limit"` // connection attempts per second, 0 means unlimited
+	Timeout   int    `yaml:"timeout"`    // per connection attempt in seconds
+}
+
+// GeoIPConfig holds the MaxMind databases resolved addresses are looked up in
+type GeoIPConfig struct {
+	ASNDatabase  string `yaml:"asn_database"`  // GeoLite2-ASN.mmdb, empty to skip
+	CityDatabase string `yaml:"city_database"` // GeoLite2-City.mmdb, empty to skip
+}
+
+// CDNConfig holds settings for attributing hosts to CDN and hosting providers
+type CDNConfig struct {
+	RangesFile string `yaml:"ranges_file"` // YAML provider list, empty for the built-in one
+	Exclude    bool   `yaml:"exclude"`     // drop hosts served from shared CDN edges
 }
 
 // OutputConfig holds output configuration
@@ -54,10 +121,29 @@ func Load(path string) (*Config, error) {
 		Workers:   10,
 		RateLimit: 5,
 		DNS: DNSConfig{
-			Enabled: false,
-			Servers: []string{"8.8.8.8:53", "1.1.1.1:53"},
-			Timeout: 5,
-			Retry:   3,
+			Enabled:     false,
+			Servers:     []string{"8.8.8.8:53", "1.1.1.1:53"},
+			Timeout:     5,
+			Retry:       3,
+			Threads:     50,
+			RecordTypes: []string{"A", "AAAA"},
+			Validation:  "discard",
+			CacheSize:   100000,
+			MaxInFlight: 100,
+		},
+		Probe: ProbeConfig{
+			Ports:   []int{80, 443},
+			Threads: 25,
+			Timeout: 10,
+		},
+		PortScan: PortScanConfig{
+			Threads:   100,
+			RateLimit: 100,
+			Timeout:   2,
+		},
+		PTRSweep: PTRSweepConfig{
+			IPv4Prefix: 24,
+			IPv6Prefix: 120,
 		},
 		Output: OutputConfig{
 			Format: "text",
@@ -145,6 +231,60 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("rate_limit cannot be negative")
 	}
 	
+	if c.DNS.Threads <= 0 {
+		return fmt.Errorf("dns.threads must be greater than 0")
+	}
+	
+	if c.DNS.RateLimit < 0 {
+		return fmt.Errorf("dns.rate_limit cannot be negative")
+	}
+	
+	if c.DNS.MaxInFlight < 0 {
+		return fmt.Errorf("dns.max_in_flight cannot be negative")
+	}
+	
+	if c.DNS.CacheSize < 0 {
+		return fmt.Errorf("dns.cache_size cannot be negative")
+	}
+	
+	if c.DNS.Validation != "discard" && c.DNS.Validation != "flag" {
+		return fmt.Errorf("dns.validation must be 'discard' or 'flag'")
+	}
+	
+	if c.PTRSweep.IPv4Prefix < 16 || c.PTRSweep.IPv4Prefix > 32 {
+		return fmt.Errorf("ptr_sweep.ipv4_prefix must be between 16 and 32")
+	}
+	
+	if c.PTRSweep.IPv6Prefix < 112 || c.PTRSweep.IPv6Prefix > 128 {
+		return fmt.Errorf("ptr_sweep.ipv6_prefix must be between 112 and 128")
+	}
+	
+	if c.Probe.Threads <= 0 {
+		return fmt.Errorf("probe.threads must be greater than 0")
+	}
+	
+	if c.Probe.RateLimit < 0 {
+		return fmt.Errorf("probe.rate_limit cannot be negative")
+	}
+	
+	for _, port := range c.Probe.Ports {
+		if port < 1 || port > 65535 {
+			return fmt.Errorf("probe.ports contains invalid port %d", port)
+		}
+	}
+	
+	if c.PortScan.Threads <= 0 {
+		return fmt.Errorf("port_scan.threads must be greater than 0")
+	}
+	
+	if c.PortScan.RateLimit < 0 {
+		return fmt.Errorf("port_scan.rate_limit cannot be negative")
+	}
+	
+	if c.PortScan.Timeout <= 0 {
+		return fmt.Errorf("port_scan.timeout must be greater than 0")
+	}
+	
 	if c.Output.Format != "text" && c.Output.Format != "json" {
 		return fmt.Errorf("output format must be 'text' or 'json'")
 	}
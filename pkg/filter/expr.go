@@ -0,0 +1,470 @@
+package filter
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/yourusername/subrecon/pkg/runner"
+)
+
+// Expr is a condition on a result
+type Expr interface {
+	Eval(result *runner.SubdomainResult) bool
+}
+
+// fieldKind decides which operators a field supports and how values compare
+type fieldKind int
+
+const (
+	kindString fieldKind = iota
+	kindNumber
+	kindIP
+	kindBool
+)
+
+// field extracts the values of one attribute of a result. Fields with
+// several values, such as ip or status, satisfy a comparison when any of
+// their values does.
+type field struct {
+	kind   fieldKind
+	values func(r *runner.SubdomainResult) []string
+}
+
+var fields = map[string]field{
+	"host":   {kindString, func(r *runner.SubdomainResult) []string { return []string{r.Host} }},
+	"source": {kindString, func(r *runner.SubdomainResult) []string { return []string{r.Source} }},
+	"ip":     {kindIP, func(r *runner.SubdomainResult) []string { return r.IPs }},
+	"cname": {kindString, func(r *runner.SubdomainResult) []string {
+		if r.DNS == nil {
+			return nil
+		}
+		return r.DNS.CNAMEChain
+	}},
+	"asn": {kindNumber, func(r *runner.SubdomainResult) []string {
+		return geoValues(r, func(g runner.GeoInfo) string {
+			if g.ASN == 0 {
+				return ""
+			}
+			return strconv.FormatUint(uint64(g.ASN), 10)
+		})
+	}},
+	"org": {kindString, func(r *runner.SubdomainResult) []string {
+		return geoValues(r, func(g runner.GeoInfo) string { return g.Org })
+	}},
+	"country": {kindString, func(r *runner.SubdomainResult) []string {
+		return geoValues(r, func(g runner.GeoInfo) string { return g.Country })
+	}},
+	"city": {kindString, func(r *runner.SubdomainResult) []string {
+		return geoValues(r, func(g runner.GeoInfo) string { return g.City })
+	}},
+	"status": {kindNumber, func(r *runner.SubdomainResult) []string {
+		return httpValues(r, func(h runner.HTTPInfo) string { return strconv.Itoa(h.StatusCode) })
+	}},
+	"title": {kindString, func(r *runner.SubdomainResult) []string {
+		return httpValues(r, func(h runner.HTTPInfo) string { return h.Title })
+	}},
+	"server": {kindString, func(r *runner.SubdomainResult) []string {
+		return httpValues(r, func(h runner.HTTPInfo) string { return h.Server })
+	}},
+	"port": {kindNumber, func(r *runner.SubdomainResult) []string {
+		ports := make([]string, len(r.Ports))
+		for i, port := range r.Ports {
+			ports[i] = strconv.Itoa(port)
+		}
+		return ports
+	}},
+	"provider": {kindString, func(r *runner.SubdomainResult) []string { return nonEmpty(r.Provider) }},
+	"cdn":      {kindBool, func(r *runner.SubdomainResult) []string { return []string{strconv.FormatBool(r.CDN)} }},
+	"takeover": {kindBool, func(r *runner.SubdomainResult) []string { return []string{strconv.FormatBool(r.TakeoverCandidate)} }},
+}
+
+func geoValues(r *runner.SubdomainResult, get func(runner.GeoInfo) string) []string {
+	values := make([]string, 0, len(r.Geo))
+	for _, g := range r.Geo {
+		values = append(values, nonEmpty(get(g))...)
+	}
+	return values
+}
+
+func httpValues(r *runner.SubdomainResult, get func(runner.HTTPInfo) string) []string {
+	values := make([]string, 0, len(r.HTTP))
+	for _, h := range r.HTTP {
+		values = append(values, nonEmpty(get(h))...)
+	}
+	return values
+}
+
+func nonEmpty(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return []string{s}
+}
+
+// ParseExpr parses a condition such as
+//
+//	ip in 10.0.0.0/8 and not (cname ~ cloudfront.net or asn == 16509)
+//
+// Predicates compare a field with a value: == and != for equality, <, <=,
+// > and >= for numeric fields, ~ and !~ for a case-insensitive substring or,
+// with a prefix, a pattern (see ParsePattern), and in for an address range.
+// Boolean fields such as cdn can stand alone. Predicates combine with and,
+// or, not and parentheses; and binds tighter than or. Values containing
+// spaces, parentheses or operator characters must be quoted.
+func ParseExpr(s string) (Expr, error) {
+	tokens, err := tokenize(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("empty expression")
+	}
+
+	p := &parser{tokens: tokens}
+	expr, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if !p.done() {
+		return nil, fmt.Errorf("unexpected %q", p.peek().text)
+	}
+
+	return expr, nil
+}
+
+// token is a lexical element of an expression
+type token struct {
+	text   string
+	quoted bool // a quoted string, never an operator or keyword
+}
+
+// operators, longest first so >= wins over >
+var operators = []string{"==", "!=", ">=", "<=", "!~", ">", "<", "~"}
+
+// tokenize splits an expression into words, quoted strings, operators and
+// parentheses
+func tokenize(s string) ([]token, error) {
+	tokens := make([]token, 0)
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '(' || c == ')':
+			tokens = append(tokens, token{text: string(c)})
+			i++
+		case c == '"' || c == '\'':
+			var b strings.Builder
+			j := i + 1
+			for ; j < len(s) && s[j] != c; j++ {
+				if s[j] == '\\' && j+1 < len(s) {
+					j++
+				}
+				b.WriteByte(s[j])
+			}
+			if j >= len(s) {
+				return nil, fmt.Errorf("unterminated string starting at %d", i)
+			}
+			tokens = append(tokens, token{text: b.String(), quoted: true})
+			i = j + 1
+		default:
+			if op := operatorAt(s[i:]); op != "" {
+				tokens = append(tokens, token{text: op})
+				i += len(op)
+				continue
+			}
+			j := i
+			for j < len(s) && !strings.ContainsRune(" \t\n\r()\"'", rune(s[j])) && operatorAt(s[j:]) == "" {
+				j++
+			}
+			tokens = append(tokens, token{text: s[i:j]})
+			i = j
+		}
+	}
+	return tokens, nil
+}
+
+// operatorAt returns the operator s starts with, if any
+func operatorAt(s string) string {
+	for _, op := range operators {
+		if strings.HasPrefix(s, op) {
+			return op
+		}
+	}
+	return ""
+}
+
+// parser is a recursive descent parser over tokens
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) done() bool {
+	return p.pos >= len(p.tokens)
+}
+
+func (p *parser) peek() token {
+	if p.done() {
+		return token{}
+	}
+	return p.tokens[p.pos]
+}
+
+// keyword reports whether the next token is the unquoted word kw, and
+// consumes it if so
+func (p *parser) keyword(kw string) bool {
+	t := p.peek()
+	if p.done() || t.quoted || !strings.EqualFold(t.text, kw) {
+		return false
+	}
+	p.pos++
+	return true
+}
+
+func (p *parser) parseOr() (Expr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("or") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &orExpr{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (Expr, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("and") {
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &andExpr{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseUnary() (Expr, error) {
+	if p.keyword("not") {
+		inner, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &notExpr{inner}, nil
+	}
+
+	if t := p.peek(); !t.quoted && t.text == "(" {
+		p.pos++
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if t := p.peek(); t.quoted || t.text != ")" {
+			return nil, fmt.Errorf("missing )")
+		}
+		p.pos++
+		return inner, nil
+	}
+
+	return p.parsePredicate()
+}
+
+func (p *parser) parsePredicate() (Expr, error) {
+	if p.done() {
+		return nil, fmt.Errorf("unexpected end of expression")
+	}
+	name := p.peek()
+	if name.quoted || name.text == ")" || operatorAt(name.text) != "" {
+		return nil, fmt.Errorf("expected a field, got %q", name.text)
+	}
+	p.pos++
+
+	f, ok := fields[strings.ToLower(name.text)]
+	if !ok {
+		return nil, fmt.Errorf("unknown field %q", name.text)
+	}
+
+	op := p.peek()
+	if p.done() || op.quoted || (operatorAt(op.text) == "" && !strings.EqualFold(op.text, "in")) {
+		if f.kind == kindBool {
+			// A boolean field on its own is true when set
+			return &predicate{field: f, op: "==", value: "true"}, nil
+		}
+		return nil, fmt.Errorf("expected an operator after %s", name.text)
+	}
+	p.pos++
+
+	if p.done() {
+		return nil, fmt.Errorf("expected a value after %s %s", name.text, op.text)
+	}
+	value := p.peek()
+	if !value.quoted && (value.text == "(" || value.text == ")" || operatorAt(value.text) != "") {
+		return nil, fmt.Errorf("expected a value after %s %s, got %q", name.text, op.text, value.text)
+	}
+	p.pos++
+
+	return newPredicate(name.text, f, strings.ToLower(op.text), value.text)
+}
+
+// predicate compares a field with a value
+type predicate struct {
+	field   field
+	op      string
+	value   string
+	number  int64
+	network *net.IPNet
+	pattern Pattern
+}
+
+// newPredicate checks that op suits the field and prepares the value
+func newPredicate(name string, f field, op, value string) (Expr, error) {
+	p := &predicate{field: f, op: op, value: value}
+
+	switch op {
+	case "in":
+		if f.kind != kindIP {
+			return nil, fmt.Errorf("in only applies to addresses, not %s", name)
+		}
+		_, network, err := net.ParseCIDR(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid range %q", value)
+		}
+		p.network = network
+
+	case "<", "<=", ">", ">=":
+		if f.kind != kindNumber {
+			return nil, fmt.Errorf("%s only applies to numeric fields, not %s", op, name)
+		}
+
+	case "~", "!~":
+		if f.kind == kindBool {
+			return nil, fmt.Errorf("%s doesn't apply to %s", op, name)
+		}
+		if strings.HasPrefix(value, PrefixGlob) || strings.HasPrefix(value, PrefixRegex) ||
+			strings.HasPrefix(value, PrefixSuffix) || strings.HasPrefix(value, PrefixLabel) {
+			pattern, err := ParsePattern(value)
+			if err != nil {
+				return nil, err
+			}
+			p.pattern = pattern
+		}
+		p.value = strings.ToLower(value)
+		return p, nil
+	}
+
+	switch f.kind {
+	case kindNumber:
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s needs a number, got %q", name, value)
+		}
+		p.number = n
+	case kindIP:
+		if op != "in" {
+			ip := net.ParseIP(value)
+			if ip == nil {
+				return nil, fmt.Errorf("%s needs an address, got %q", name, value)
+			}
+			p.value = ip.String()
+		}
+	case kindBool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s needs true or false, got %q", name, value)
+		}
+		p.value = strconv.FormatBool(b)
+	}
+
+	return p, nil
+}
+
+// Eval reports whether any value of the field satisfies the predicate. The
+// negated operators != and !~ hold when no value satisfies == or ~.
+func (p *predicate) Eval(r *runner.SubdomainResult) bool {
+	values := p.field.values(r)
+
+	switch p.op {
+	case "!=":
+		return !p.any(values, "==")
+	case "!~":
+		return !p.any(values, "~")
+	}
+	return p.any(values, p.op)
+}
+
+func (p *predicate) any(values []string, op string) bool {
+	for _, v := range values {
+		if p.holds(v, op) {
+			return true
+		}
+	}
+	return false
+}
+
+// holds compares a single value
+func (p *predicate) holds(v, op string) bool {
+	switch op {
+	case "~":
+		if p.pattern != nil {
+			return p.pattern.Match(v)
+		}
+		return strings.Contains(strings.ToLower(v), p.value)
+	case "in":
+		ip := net.ParseIP(v)
+		return ip != nil && p.network.Contains(ip)
+	}
+
+	switch p.field.kind {
+	case kindNumber:
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return false
+		}
+		switch op {
+		case "==":
+			return n == p.number
+		case "<":
+			return n < p.number
+		case "<=":
+			return n <= p.number
+		case ">":
+			return n > p.number
+		case ">=":
+			return n >= p.number
+		}
+	case kindIP:
+		ip := net.ParseIP(v)
+		return ip != nil && ip.String() == p.value
+	case kindString:
+		return strings.EqualFold(strings.TrimSuffix(v, "."), strings.TrimSuffix(p.value, "."))
+	}
+	return v == p.value
+}
+
+type andExpr struct{ left, right Expr }
+
+func (e *andExpr) Eval(r *runner.SubdomainResult) bool {
+	return e.left.Eval(r) && e.right.Eval(r)
+}
+
+type orExpr struct{ left, right Expr }
+
+func (e *orExpr) Eval(r *runner.SubdomainResult) bool {
+	return e.left.Eval(r) || e.right.Eval(r)
+}
+
+type notExpr struct{ inner Expr }
+
+func (e *notExpr) Eval(r *runner.SubdomainResult) bool {
+	return !e.inner.Eval(r)
+}
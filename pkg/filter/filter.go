@@ -4,25 +4,30 @@ import (
 	"bufio"
 	"fmt"
 	"os"
-	"regexp"
 	"strings"
+
+	"github.com/yourusername/subrecon/pkg/runner"
 )
 
-// Filter represents a subdomain filter
+// Filter represents a filter over results: hostname patterns plus
+// conditions on what resolution and probing found
 type Filter struct {
-	matchPatterns   []*regexp.Regexp
-	excludePatterns []*regexp.Regexp
+	matchPatterns   []Pattern
+	excludePatterns []Pattern
+	exprs           []Expr
 }
 
 // NewFilter creates a new filter
 func NewFilter() *Filter {
 	return &Filter{
-		matchPatterns:   make([]*regexp.Regexp, 0),
-		excludePatterns: make([]*regexp.Regexp, 0),
+		matchPatterns:   make([]Pattern, 0),
+		excludePatterns: make([]Pattern, 0),
+		exprs:           make([]Expr, 0),
 	}
 }
 
-// AddMatchPattern adds a match pattern (regex)
+// AddMatchPattern adds comma-separated match patterns, or the patterns in
+// a file given as @path. See ParsePattern for the syntax.
 func (f *Filter) AddMatchPattern(pattern string) error {
 	// Check if it's a file reference
 	if strings.HasPrefix(pattern, "@") {
@@ -37,17 +42,18 @@ func (f *Filter) AddMatchPattern(pattern string) error {
 			continue
 		}
 		
-		regex, err := regexp.Compile(p)
+		parsed, err := ParsePattern(p)
 		if err != nil {
 			return fmt.Errorf("invalid match pattern '%s': %w", p, err)
 		}
-		f.matchPatterns = append(f.matchPatterns, regex)
+		f.matchPatterns = append(f.matchPatterns, parsed)
 	}
 	
 	return nil
 }
 
-// AddExcludePattern adds an exclude pattern (regex)
+// AddExcludePattern adds comma-separated exclude patterns, or the patterns
+// in a file given as @path. See ParsePattern for the syntax.
 func (f *Filter) AddExcludePattern(pattern string) error {
 	// Check if it's a file reference
 	if strings.HasPrefix(pattern, "@") {
@@ -62,17 +68,17 @@ func (f *Filter) AddExcludePattern(pattern string) error {
 			continue
 		}
 		
-		regex, err := regexp.Compile(p)
+		parsed, err := ParsePattern(p)
 		if err != nil {
 			return fmt.Errorf("invalid exclude pattern '%s': %w", p, err)
 		}
-		f.excludePatterns = append(f.excludePatterns, regex)
+		f.excludePatterns = append(f.excludePatterns, parsed)
 	}
 	
 	return nil
 }
 
-// loadPatternsFromFile loads patterns from a file
+// loadPatternsFromFile loads patterns from a file, one per line
 func (f *Filter) loadPatternsFromFile(path string, isMatch bool) error {
 	file, err := os.Open(path)
 	if err != nil {
@@ -87,15 +93,15 @@ func (f *Filter) loadPatternsFromFile(path string, isMatch bool) error {
 			continue
 		}
 		
-		regex, err := regexp.Compile(pattern)
+		parsed, err := ParsePattern(pattern)
 		if err != nil {
 			return fmt.Errorf("invalid pattern in file '%s': %w", pattern, err)
 		}
 		
 		if isMatch {
-			f.matchPatterns = append(f.matchPatterns, regex)
+			f.matchPatterns = append(f.matchPatterns, parsed)
 		} else {
-			f.excludePatterns = append(f.excludePatterns, regex)
+			f.excludePatterns = append(f.excludePatterns, parsed)
 		}
 	}
 	
@@ -106,45 +112,67 @@ func (f *Filter) loadPatternsFromFile(path string, isMatch bool) error {
 	return nil
 }
 
-// Apply applies the filter to a list of subdomains
-func (f *Filter) Apply(subdomains []string) []string {
-	if len(f.matchPatterns) == 0 && len(f.excludePatterns) == 0 {
-		return subdomains
+// AddExpr adds a condition results must satisfy, e.g.
+// "ip in 10.0.0.0/8 and status >= 400". See ParseExpr for the syntax.
+// Conditions added separately must all hold.
+func (f *Filter) AddExpr(expr string) error {
+	parsed, err := ParseExpr(expr)
+	if err != nil {
+		return fmt.Errorf("invalid expression '%s': %w", expr, err)
 	}
+	f.exprs = append(f.exprs, parsed)
 	
-	filtered := make([]string, 0, len(subdomains))
+	return nil
+}
+
+// Apply returns the results that pass the filter
+func (f *Filter) Apply(results []runner.SubdomainResult) []runner.SubdomainResult {
+	if len(f.matchPatterns) == 0 && len(f.excludePatterns) == 0 && len(f.exprs) == 0 {
+		return results
+	}
 	
-	for _, subdomain := range subdomains {
-		// Check match patterns (must match at least one if any are specified)
-		if len(f.matchPatterns) > 0 {
-			matched := false
-			for _, pattern := range f.matchPatterns {
-				if pattern.MatchString(subdomain) {
-					matched = true
-					break
-				}
-			}
-			if !matched {
-				continue
-			}
+	filtered := make([]runner.SubdomainResult, 0, len(results))
+	
+	for i := range results {
+		if f.Match(&results[i]) {
+			filtered = append(filtered, results[i])
 		}
-		
-		// Check exclude patterns (must not match any)
-		excluded := false
-		for _, pattern := range f.excludePatterns {
-			if pattern.MatchString(subdomain) {
-				excluded = true
+	}
+	
+	return filtered
+}
+
+// Match reports whether a single result passes the filter
+func (f *Filter) Match(result *runner.SubdomainResult) bool {
+	// Check match patterns (must match at least one if any are specified)
+	if len(f.matchPatterns) > 0 {
+		matched := false
+		for _, pattern := range f.matchPatterns {
+			if pattern.Match(result.Host) {
+				matched = true
 				break
 			}
 		}
-		if excluded {
-			continue
+		if !matched {
+			return false
 		}
-		
-		filtered = append(filtered, subdomain)
 	}
 	
-	return filtered
+	// Check exclude patterns (must not match any)
+	for _, pattern := range f.excludePatterns {
+		if pattern.Match(result.Host) {
+			return false
+		}
+	}
+	
+	// Check expressions (must satisfy all)
+	for _, expr := range f.exprs {
+		if !expr.Eval(result) {
+			return false
+		}
+	}
+	
+	return true
 }
 
 // Deduplicate removes duplicate subdomains
@@ -0,0 +1,182 @@
+package filter
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Pattern syntax prefixes. Patterns without one are globs.
+const (
+	PrefixGlob   = "glob:"   // whole host, * matches anything including dots
+	PrefixRegex  = "re:"     // regular expression, matched anywhere in the host
+	PrefixSuffix = "suffix:" // the host is the name or a subdomain of it
+	PrefixLabel  = "label:"  // some label of the host matches a glob, * stays within the label
+)
+
+// Pattern matches hostnames
+type Pattern interface {
+	Match(host string) bool
+	String() string
+}
+
+// ParsePattern parses a pattern in one of the prefixed forms, e.g.
+// "re:^api[0-9]+\.", "suffix:dev.example.com" or "label:staging". Plain
+// patterns such as "*.dev.example.com" or "api-*" are globs. Globs, suffixes
+// and labels ignore case; regular expressions match as written.
+func ParsePattern(s string) (Pattern, error) {
+	s = strings.TrimSpace(s)
+
+	switch {
+	case strings.HasPrefix(s, PrefixRegex):
+		expr := strings.TrimPrefix(s, PrefixRegex)
+		if expr == "" {
+			return nil, fmt.Errorf("empty pattern %q", s)
+		}
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regex %q: %w", expr, err)
+		}
+		return &regexPattern{re: re, src: s}, nil
+
+	case strings.HasPrefix(s, PrefixSuffix):
+		suffix := normalizeHost(strings.TrimPrefix(strings.TrimPrefix(s, PrefixSuffix), "."))
+		if suffix == "" {
+			return nil, fmt.Errorf("empty pattern %q", s)
+		}
+		return &suffixPattern{suffix: suffix, src: s}, nil
+
+	case strings.HasPrefix(s, PrefixLabel):
+		label := strings.TrimPrefix(s, PrefixLabel)
+		if label == "" || strings.Contains(label, ".") {
+			return nil, fmt.Errorf("label pattern %q must be a single label", s)
+		}
+		re, err := compileGlob(label, true)
+		if err != nil {
+			return nil, err
+		}
+		return &labelPattern{re: re, src: s}, nil
+	}
+
+	glob := strings.TrimPrefix(s, PrefixGlob)
+	if glob == "" {
+		return nil, fmt.Errorf("empty pattern %q", s)
+	}
+	re, err := compileGlob(normalizeHost(glob), false)
+	if err != nil {
+		return nil, err
+	}
+	return &globPattern{re: re, src: s}, nil
+}
+
+// compileGlob translates a glob into an anchored, case-insensitive
+// expression. * matches any run of characters and ? any single character,
+// excluding dots when inLabel is set; [...] is a character class, negated
+// with [!...].
+func compileGlob(glob string, inLabel bool) (*regexp.Regexp, error) {
+	many, one := ".*", "."
+	if inLabel {
+		many, one = "[^.]*", "[^.]"
+	}
+
+	var b strings.Builder
+	b.WriteString("(?i)^")
+	for i := 0; i < len(glob); i++ {
+		switch c := glob[i]; c {
+		case '*':
+			b.WriteString(many)
+		case '?':
+			b.WriteString(one)
+		case '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("invalid glob %q: unterminated [", glob)
+			}
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			if class == "" || class == "^" {
+				return nil, fmt.Errorf("invalid glob %q: empty []", glob)
+			}
+			b.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end + 1
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("$")
+
+	re, err := regexp.Compile(b.String())
+	if err != nil {
+		return nil, fmt.Errorf("invalid glob %q: %w", glob, err)
+	}
+	return re, nil
+}
+
+// normalizeHost lowercases a name and drops the trailing dot
+func normalizeHost(host string) string {
+	return strings.ToLower(strings.TrimSuffix(strings.TrimSpace(host), "."))
+}
+
+// globPattern matches the whole host against a glob
+type globPattern struct {
+	re  *regexp.Regexp
+	src string
+}
+
+func (p *globPattern) Match(host string) bool {
+	return p.re.MatchString(normalizeHost(host))
+}
+
+func (p *globPattern) String() string {
+	return p.src
+}
+
+// regexPattern matches a regular expression anywhere in the host
+type regexPattern struct {
+	re  *regexp.Regexp
+	src string
+}
+
+func (p *regexPattern) Match(host string) bool {
+	return p.re.MatchString(host)
+}
+
+func (p *regexPattern) String() string {
+	return p.src
+}
+
+// suffixPattern matches a name and its subdomains
+type suffixPattern struct {
+	suffix string
+	src    string
+}
+
+func (p *suffixPattern) Match(host string) bool {
+	host = normalizeHost(host)
+	return host == p.suffix || strings.HasSuffix(host, "."+p.suffix)
+}
+
+func (p *suffixPattern) String() string {
+	return p.src
+}
+
+// labelPattern matches hosts with a label matching a glob
+type labelPattern struct {
+	re  *regexp.Regexp
+	src string
+}
+
+func (p *labelPattern) Match(host string) bool {
+	for _, label := range strings.Split(normalizeHost(host), ".") {
+		if p.re.MatchString(label) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *labelPattern) String() string {
+	return p.src
+}
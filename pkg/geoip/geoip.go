@@ -0,0 +1,100 @@
+// Package geoip attaches network ownership and location to addresses using
+// local GeoLite2 / GeoIP2 databases. Nothing is fetched over the network.
+package geoip
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/yourusername/subrecon/internal/mmdb"
+)
+
+// Info is what the databases know about an address
+type Info struct {
+	ASN     uint   // autonomous system number, 0 if unknown
+	Org     string // organization the AS is registered to
+	Country string // ISO 3166-1 alpha-2 code
+	City    string // English city name
+}
+
+// Empty reports whether nothing is known
+func (i Info) Empty() bool {
+	return i == Info{}
+}
+
+// Enricher looks addresses up in an ASN and a City database. Either may be
+// missing, in which case its fields are left empty.
+type Enricher struct {
+	asn  *mmdb.Reader
+	city *mmdb.Reader
+}
+
+// Open loads the databases at asnPath and cityPath. An empty path skips
+// that database; at least one is required.
+func Open(asnPath, cityPath string) (*Enricher, error) {
+	if asnPath == "" && cityPath == "" {
+		return nil, fmt.Errorf("no GeoIP database configured")
+	}
+
+	e := &Enricher{}
+	var err error
+	if asnPath != "" {
+		if e.asn, err = mmdb.Open(asnPath); err != nil {
+			return nil, fmt.Errorf("failed to open ASN database: %w", err)
+		}
+	}
+	if cityPath != "" {
+		if e.city, err = mmdb.Open(cityPath); err != nil {
+			return nil, fmt.Errorf("failed to open City database: %w", err)
+		}
+	}
+
+	return e, nil
+}
+
+// Lookup returns what the databases know about ip. Addresses that fail to
+// parse or aren't covered give an empty Info.
+func (e *Enricher) Lookup(ip string) Info {
+	var info Info
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return info
+	}
+
+	if e.asn != nil {
+		if record, err := e.asn.Lookup(addr); err == nil {
+			m, _ := record.(map[string]interface{})
+			asn, _ := m["autonomous_system_number"].(uint64)
+			info.ASN = uint(asn)
+			info.Org, _ = m["autonomous_system_organization"].(string)
+		}
+	}
+
+	if e.city != nil {
+		if record, err := e.city.Lookup(addr); err == nil {
+			m, _ := record.(map[string]interface{})
+			info.Country, _ = field(m, "country", "iso_code").(string)
+			if info.Country == "" {
+				// Anycast and satellite ranges often only carry the
+				// registered country
+				info.Country, _ = field(m, "registered_country", "iso_code").(string)
+			}
+			info.City, _ = field(m, "city", "names", "en").(string)
+		}
+	}
+
+	return info
+}
+
+// field walks nested maps along path
+func field(m map[string]interface{}, path ...string) interface{} {
+	var value interface{} = m
+	for _, key := range path {
+		inner, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		value = inner[key]
+	}
+	return value
+}
@@ -7,7 +7,7 @@ import (
 	"os"
 	"sort"
 
-	"github.com/yourusername/subfinder-pro/pkg/runner"
+	"github.com/yourusername/subrecon/pkg/runner"
 )
 
 // Formatter interface for output formatting
@@ -0,0 +1,237 @@
+package permute
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/yourusername/subrecon/internal/resolve"
+)
+
+// SourceName is the source recorded for hosts found by permutation
+const SourceName = "permutation"
+
+// numberSteps is how far numbers in a label are moved up and down
+const numberSteps = 2
+
+// DefaultWords are the environment and role words used when none are configured
+var DefaultWords = []string{
+	"admin", "api", "beta", "dev", "int", "internal", "old", "prod",
+	"qa", "stage", "staging", "test", "uat", "v1", "v2",
+}
+
+// Generator builds alterations of known hosts
+type Generator struct {
+	words []string
+}
+
+// Stats summarizes a permutation run
+type Stats struct {
+	Candidates int // alterations generated
+	Resolved   int // alterations that exist
+	Wildcards  int // existing alterations dropped as wildcard answers
+}
+
+// New creates a generator using words, or DefaultWords when empty
+func New(words []string) *Generator {
+	if len(words) == 0 {
+		words = DefaultWords
+	}
+
+	seen := make(map[string]bool)
+	g := &Generator{words: make([]string, 0, len(words))}
+	for _, word := range words {
+		word = strings.ToLower(strings.TrimSpace(word))
+		if word == "" || seen[word] || !validLabel(word) {
+			continue
+		}
+		seen[word] = true
+		g.words = append(g.words, word)
+	}
+	sort.Strings(g.words)
+
+	return g
+}
+
+// LoadWords reads permutation words from a file, one per line
+func LoadWords(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open permutation words: %w", err)
+	}
+	defer file.Close()
+
+	words := make([]string, 0)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		word := strings.TrimSpace(scanner.Text())
+		if word != "" && !strings.HasPrefix(word, "#") {
+			words = append(words, word)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read permutation words: %w", err)
+	}
+
+	return words, nil
+}
+
+// Generate returns the alterations of every host below domain, sorted and
+// without duplicates or the hosts themselves. For a host like api2.eu.example.com
+// and the word dev it produces:
+//
+//	dev.api2.eu.example.com, api2.dev.eu.example.com,
+//	api2.eu.dev.example.com                            (word inserted as a label)
+//	dev-api2.eu.example.com, api2-dev.eu.example.com   (dash joins)
+//	devapi2.eu.example.com, api2dev.eu.example.com     (plain joins)
+//	api0/api1/api3/api4.eu.example.com                 (numbers swapped)
+//
+// Wildcard entries such as *.dev.example.com seed from dev.example.com.
+func (g *Generator) Generate(hosts []string, domain string) []string {
+	domain = resolve.CanonicalName(domain)
+
+	known := make(map[string]bool, len(hosts))
+	for _, host := range hosts {
+		known[strings.TrimPrefix(resolve.CanonicalName(host), "*.")] = true
+	}
+
+	candidates := make(map[string]bool)
+	add := func(labels []string) {
+		name := strings.Join(labels, ".") + "." + domain
+		if !known[name] {
+			candidates[name] = true
+		}
+	}
+
+	for host := range known {
+		if !strings.HasSuffix(host, "."+domain) {
+			continue
+		}
+		labels := strings.Split(strings.TrimSuffix(host, "."+domain), ".")
+		if !validLabels(labels) {
+			continue
+		}
+		first, rest := labels[0], labels[1:]
+
+		for _, word := range g.words {
+			// Insert the word as a new label in every position
+			for i := 0; i <= len(labels); i++ {
+				inserted := make([]string, 0, len(labels)+1)
+				inserted = append(inserted, labels[:i]...)
+				inserted = append(inserted, word)
+				inserted = append(inserted, labels[i:]...)
+				add(inserted)
+			}
+
+			// Join the word onto the leftmost label
+			for _, joined := range []string{word + "-" + first, first + "-" + word, word + first, first + word} {
+				if validLabel(joined) {
+					add(append([]string{joined}, rest...))
+				}
+			}
+		}
+
+		for _, swapped := range swapNumbers(first) {
+			add(append([]string{swapped}, rest...))
+		}
+	}
+
+	result := make([]string, 0, len(candidates))
+	for name := range candidates {
+		result = append(result, name)
+	}
+	sort.Strings(result)
+
+	return result
+}
+
+// Run resolves the alterations of hosts and returns the ones that exist and
+// aren't answered by a wildcard, sorted by name
+func (g *Generator) Run(ctx context.Context, resolver *resolve.Resolver, hosts []string, domain string, threads int) ([]*resolve.Result, *Stats, error) {
+	candidates := g.Generate(hosts, domain)
+	stats := &Stats{Candidates: len(candidates)}
+
+	resolved, err := resolver.ResolveMany(ctx, candidates, threads)
+
+	hits := make([]*resolve.Result, 0)
+	for _, result := range resolved {
+		if !result.Exists {
+			continue
+		}
+		stats.Resolved++
+
+		if resolver.IsWildcardResult(ctx, result, domain) {
+			stats.Wildcards++
+			continue
+		}
+		hits = append(hits, result)
+	}
+
+	return hits, stats, err
+}
+
+// swapNumbers returns label with each run of digits moved up and down by up
+// to numberSteps, keeping zero padding, e.g. web02 -> web00, web01, web03, web04
+func swapNumbers(label string) []string {
+	var swapped []string
+
+	for start := 0; start < len(label); {
+		if !isDigit(label[start]) {
+			start++
+			continue
+		}
+		end := start
+		for end < len(label) && isDigit(label[end]) {
+			end++
+		}
+
+		digits := label[start:end]
+		n, err := strconv.Atoi(digits)
+		if err == nil {
+			for delta := -numberSteps; delta <= numberSteps; delta++ {
+				if delta == 0 || n+delta < 0 {
+					continue
+				}
+				number := fmt.Sprintf("%0*d", len(digits), n+delta)
+				swapped = append(swapped, label[:start]+number+label[end:])
+			}
+		}
+		start = end
+	}
+
+	return swapped
+}
+
+// validLabels reports whether every label is usable
+func validLabels(labels []string) bool {
+	for _, label := range labels {
+		if !validLabel(label) {
+			return false
+		}
+	}
+	return true
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// validLabel reports whether s is a usable DNS label
+func validLabel(s string) bool {
+	if s == "" || len(s) > 63 || s[0] == '-' || s[len(s)-1] == '-' {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if !(c >= 'a' && c <= 'z' || isDigit(c) || c == '-' || c == '_') {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,212 @@
+package portscan
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// TopPortsName selects Top100 in a port list
+const TopPortsName = "top-100"
+
+// Top100 are the 100 most commonly open TCP ports, as ranked by nmap
+var Top100 = []int{
+	7, 9, 13, 21, 22, 23, 25, 26, 37, 53, 79, 80, 81, 88, 106, 110, 111, 113,
+	119, 135, 139, 143, 144, 179, 199, 389, 427, 443, 444, 445, 465, 513, 514,
+	515, 543, 544, 548, 554, 587, 631, 646, 873, 990, 993, 995, 1025, 1026,
+	1027, 1028, 1029, 1110, 1433, 1720, 1723, 1755, 1900, 2000, 2001, 2049,
+	2121, 2717, 3000, 3128, 3306, 3389, 3986, 4899, 5000, 5009, 5051, 5060,
+	5101, 5190, 5357, 5432, 5631, 5666, 5800, 5900, 6000, 6001, 6646, 7070,
+	8000, 8008, 8009, 8080, 8081, 8443, 8888, 9100, 9999, 10000, 32768, 49152,
+	49153, 49154, 49155, 49156, 49157,
+}
+
+// Options configures a scanner
+type Options struct {
+	Ports     []int
+	Threads   int           // concurrent connection attempts
+	RateLimit int           // connection attempts per second across all addresses, 0 means unlimited
+	Timeout   time.Duration // per connection attempt
+}
+
+// Stats summarizes a scan
+type Stats struct {
+	Addresses int // distinct addresses scanned
+	Attempts  int // connection attempts made
+	Open      int // ports found open
+}
+
+// Scanner checks which TCP ports accept connections
+type Scanner struct {
+	opts    Options
+	limiter *rate.Limiter
+}
+
+// New creates a scanner
+func New(opts Options) *Scanner {
+	if len(opts.Ports) == 0 {
+		opts.Ports = Top100
+	}
+	if opts.Threads <= 0 {
+		opts.Threads = 100
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 2 * time.Second
+	}
+
+	s := &Scanner{opts: opts}
+	if opts.RateLimit > 0 {
+		s.limiter = rate.NewLimiter(rate.Limit(opts.RateLimit), 1)
+	}
+
+	return s
+}
+
+// ParsePorts parses a port list: "top-100", or comma-separated ports and
+// ranges such as "80,443,8000-8100". Duplicates are dropped.
+func ParsePorts(list string) ([]int, error) {
+	ports := make([]int, 0)
+	seen := make(map[int]bool)
+	add := func(port int) {
+		if !seen[port] {
+			seen[port] = true
+			ports = append(ports, port)
+		}
+	}
+
+	for _, field := range strings.Split(list, ",") {
+		field = strings.TrimSpace(field)
+		switch {
+		case field == "":
+			continue
+		case strings.EqualFold(field, TopPortsName):
+			for _, port := range Top100 {
+				add(port)
+			}
+			continue
+		}
+
+		first, last := field, field
+		if i := strings.Index(field, "-"); i >= 0 {
+			first, last = field[:i], field[i+1:]
+		}
+		from, err := parsePort(first)
+		if err != nil {
+			return nil, fmt.Errorf("invalid port %q", field)
+		}
+		to, err := parsePort(last)
+		if err != nil || to < from {
+			return nil, fmt.Errorf("invalid port range %q", field)
+		}
+		for port := from; port <= to; port++ {
+			add(port)
+		}
+	}
+
+	if len(ports) == 0 {
+		return nil, fmt.Errorf("no ports given")
+	}
+	return ports, nil
+}
+
+// parsePort parses a single port number
+func parsePort(s string) (int, error) {
+	port, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil || port < 1 || port > 65535 {
+		return 0, fmt.Errorf("invalid port %q", s)
+	}
+	return port, nil
+}
+
+// Scan tries every configured port on every address and returns the open
+// ports of each address that has any, sorted. Addresses are scanned once
+// however often they appear.
+func (s *Scanner) Scan(ctx context.Context, ips []string) (map[string][]int, *Stats) {
+	type job struct {
+		ip   string
+		port int
+	}
+
+	unique := make([]string, 0, len(ips))
+	seen := make(map[string]bool, len(ips))
+	for _, ip := range ips {
+		if !seen[ip] && net.ParseIP(ip) != nil {
+			seen[ip] = true
+			unique = append(unique, ip)
+		}
+	}
+
+	stats := &Stats{Addresses: len(unique)}
+	open := make(map[string][]int)
+	var mu sync.Mutex
+
+	jobs := make(chan job)
+	var wg sync.WaitGroup
+	for w := 0; w < s.opts.Threads; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for j := range jobs {
+				isOpen, attempted := s.check(ctx, j.ip, j.port)
+
+				mu.Lock()
+				if attempted {
+					stats.Attempts++
+				}
+				if isOpen {
+					stats.Open++
+					open[j.ip] = append(open[j.ip], j.port)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	// Walk ports in the outer loop so consecutive attempts hit different
+	// addresses rather than hammering one host
+feed:
+	for _, port := range s.opts.Ports {
+		for _, ip := range unique {
+			select {
+			case jobs <- job{ip: ip, port: port}:
+			case <-ctx.Done():
+				break feed
+			}
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	for _, ports := range open {
+		sort.Ints(ports)
+	}
+
+	return open, stats
+}
+
+// check reports whether ip accepts connections on port, and whether a
+// connection was attempted at all
+func (s *Scanner) check(ctx context.Context, ip string, port int) (bool, bool) {
+	if s.limiter != nil {
+		if err := s.limiter.Wait(ctx); err != nil {
+			return false, false
+		}
+	}
+
+	dialer := net.Dialer{Timeout: s.opts.Timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(ip, strconv.Itoa(port)))
+	if err != nil {
+		return false, true
+	}
+	conn.Close()
+
+	return true, true
+}
@@ -0,0 +1,74 @@
+package probe
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// CertSourceName is the source recorded for hosts found in certificates
+const CertSourceName = "tls"
+
+// Certificate describes the leaf certificate a host presented
+type Certificate struct {
+	Subject     string
+	Issuer      string
+	NotAfter    time.Time
+	Fingerprint string   // SHA-256 of the DER encoding, hex
+	Names       []string // DNS subject alternative names
+}
+
+// newCertificate summarizes a parsed certificate
+func newCertificate(cert *x509.Certificate) *Certificate {
+	sum := sha256.Sum256(cert.Raw)
+
+	return &Certificate{
+		Subject:     cert.Subject.String(),
+		Issuer:      cert.Issuer.String(),
+		NotAfter:    cert.NotAfter,
+		Fingerprint: hex.EncodeToString(sum[:]),
+		Names:       cert.DNSNames,
+	}
+}
+
+// peerCertificate returns the certificate presented on the first request of
+// a redirect chain, i.e. by the host that was probed rather than by wherever
+// it redirected to
+func peerCertificate(resp *http.Response) *Certificate {
+	first := resp
+	for first.Request != nil && first.Request.Response != nil {
+		first = first.Request.Response
+	}
+	if first.TLS == nil || len(first.TLS.PeerCertificates) == 0 {
+		return nil
+	}
+
+	return newCertificate(first.TLS.PeerCertificates[0])
+}
+
+// NamesUnder returns the certificate names that fall under domain, sorted
+// and deduplicated. Wildcard names are reduced to the zone they cover, e.g.
+// *.dev.example.com to dev.example.com.
+func NamesUnder(names []string, domain string) []string {
+	domain = strings.ToLower(strings.TrimSuffix(domain, "."))
+	seen := make(map[string]bool)
+	under := make([]string, 0)
+
+	for _, name := range names {
+		name = strings.ToLower(strings.TrimSuffix(strings.TrimPrefix(name, "*."), "."))
+		if name != domain && !strings.HasSuffix(name, "."+domain) {
+			continue
+		}
+		if !seen[name] {
+			seen[name] = true
+			under = append(under, name)
+		}
+	}
+	sort.Strings(under)
+
+	return under
+}
@@ -0,0 +1,336 @@
+package probe
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"net"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+const (
+	// maxBodySize bounds how much of a response is read for the title
+	maxBodySize = 1 << 20
+	// maxRedirects is the longest redirect chain followed
+	maxRedirects = 10
+)
+
+// DefaultPorts are probed when no ports are configured
+var DefaultPorts = []int{80, 443}
+
+var titleRe = regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title>`)
+
+// ErrOutOfScope is returned when a host resolves to an address outside the
+// prober's scope
+var ErrOutOfScope = errors.New("address out of scope")
+
+// Scope limits what the prober may contact, e.g. a scope.Scope
+type Scope interface {
+	HostAllowed(host string) bool
+	AddressAllowed(ip string) bool
+}
+
+// Options configures a prober
+type Options struct {
+	Ports     []int         // ports to probe on every host
+	Threads   int           // concurrent requests
+	RateLimit int           // requests per second, 0 means unlimited
+	Timeout   time.Duration // per request, including redirects
+	UserAgent string
+
+	// FollowRedirects follows redirects to other hosts too. By default only
+	// redirects within the probed host are followed, so the final response
+	// describes the host that was asked for.
+	FollowRedirects bool
+
+	// Scope, if set, is checked for every redirect target before it is
+	// followed and for every address before it is dialled
+	Scope Scope
+}
+
+// Response is what a host served on one port
+type Response struct {
+	URL           string       // URL requested
+	FinalURL      string       // URL of the final response, URL if not redirected
+	StatusCode    int          // status of the final response
+	Title         string       // HTML title of the final response
+	ContentLength int64        // body size of the final response
+	Redirects     []string     // URLs followed after the first, in order
+	Server        string       // Server header of the final response
+	TLSVersion    string       // e.g. "TLS 1.3", empty over plain HTTP
+	Certificate   *Certificate // presented by the probed host, nil over plain HTTP
+}
+
+// Prober requests http and https on a set of ports. HTTPClient may be
+// replaced, e.g. in tests; the prober installs its own redirect policy.
+type Prober struct {
+	HTTPClient *http.Client
+
+	opts    Options
+	limiter *rate.Limiter
+}
+
+// New creates a prober. Certificates are not verified: the point is to see
+// what is served, not whether it is trusted.
+func New(opts Options) *Prober {
+	if len(opts.Ports) == 0 {
+		opts.Ports = DefaultPorts
+	}
+	if opts.Threads <= 0 {
+		opts.Threads = 25
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 10 * time.Second
+	}
+	if opts.UserAgent == "" {
+		opts.UserAgent = "SubFinder-Pro/1.0"
+	}
+
+	transport := &http.Transport{
+		TLSClientConfig:     &tls.Config{InsecureSkipVerify: true},
+		MaxIdleConnsPerHost: 2,
+		IdleConnTimeout:     30 * time.Second,
+	}
+	if opts.Scope != nil {
+		transport.DialContext = scopedDialer(opts.Scope, &net.Dialer{Timeout: opts.Timeout})
+	}
+
+	p := &Prober{
+		HTTPClient: &http.Client{
+			Timeout:   opts.Timeout,
+			Transport: transport,
+		},
+		opts: opts,
+	}
+
+	if opts.RateLimit > 0 {
+		p.limiter = rate.NewLimiter(rate.Limit(opts.RateLimit), 1)
+	}
+
+	return p
+}
+
+// ParsePorts parses a comma-separated port list such as "80,443,8080"
+func ParsePorts(list string) ([]int, error) {
+	ports := make([]int, 0)
+	seen := make(map[int]bool)
+	for _, field := range strings.Split(list, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		port, err := strconv.Atoi(field)
+		if err != nil || port < 1 || port > 65535 {
+			return nil, fmt.Errorf("invalid port %q", field)
+		}
+		if !seen[port] {
+			seen[port] = true
+			ports = append(ports, port)
+		}
+	}
+
+	return ports, nil
+}
+
+// Probe requests every configured port of host and returns what answered,
+// in port order. Port 80 is only tried over http and 443 only over https;
+// other ports are tried over https first, then http.
+func (p *Prober) Probe(ctx context.Context, host string) []*Response {
+	responses := make([]*Response, 0)
+	for _, port := range p.opts.Ports {
+		for _, scheme := range schemes(port) {
+			resp, err := p.fetch(ctx, scheme, host, port)
+			if err != nil {
+				if ctx.Err() != nil {
+					return responses
+				}
+				continue
+			}
+			responses = append(responses, resp)
+			break
+		}
+	}
+
+	return responses
+}
+
+// ProbeMany probes hosts concurrently using the configured number of
+// threads. Responses are returned in the order of the input; hosts left
+// unprobed because ctx was cancelled get none.
+func (p *Prober) ProbeMany(ctx context.Context, hosts []string) [][]*Response {
+	responses := make([][]*Response, len(hosts))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+
+	for w := 0; w < p.opts.Threads; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for i := range jobs {
+				responses[i] = p.Probe(ctx, hosts[i])
+			}
+		}()
+	}
+
+feed:
+	for i := range hosts {
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	return responses
+}
+
+// fetch requests the root of host:port and describes the final response
+func (p *Prober) fetch(ctx context.Context, scheme, host string, port int) (*Response, error) {
+	if p.limiter != nil {
+		if err := p.limiter.Wait(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	target := scheme + "://" + hostPort(scheme, host, port) + "/"
+	req, err := http.NewRequestWithContext(ctx, "GET", target, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", p.opts.UserAgent)
+
+	// Follow redirects on a copy so the caller's client is left alone
+	client := *p.HTTPClient
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if len(via) >= maxRedirects {
+			return http.ErrUseLastResponse
+		}
+		// Stop at the redirect itself when it leaves the host, unless asked
+		// to follow it
+		if !p.opts.FollowRedirects && !strings.EqualFold(req.URL.Hostname(), via[0].URL.Hostname()) {
+			return http.ErrUseLastResponse
+		}
+		// Never follow a redirect out of scope
+		if p.opts.Scope != nil && !p.opts.Scope.HostAllowed(req.URL.Hostname()) {
+			return http.ErrUseLastResponse
+		}
+		return nil
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxBodySize))
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Response{
+		URL:           target,
+		FinalURL:      resp.Request.URL.String(),
+		StatusCode:    resp.StatusCode,
+		Title:         extractTitle(body),
+		ContentLength: resp.ContentLength,
+		Redirects:     redirectChain(resp),
+		Server:        resp.Header.Get("Server"),
+	}
+	if result.ContentLength < 0 {
+		result.ContentLength = int64(len(body))
+	}
+	if resp.TLS != nil {
+		result.TLSVersion = tls.VersionName(resp.TLS.Version)
+	}
+	result.Certificate = peerCertificate(resp)
+
+	return result, nil
+}
+
+// scopedDialer resolves the host itself and only dials addresses in scope.
+// Every address must be allowed, and the connection goes to an address that
+// was checked, so a name can't be rebound out of scope between the check
+// and the dial.
+func scopedDialer(sc Scope, dialer *net.Dialer) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+
+		ips, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+		for _, ip := range ips {
+			if !sc.AddressAllowed(ip.IP.String()) {
+				return nil, fmt.Errorf("%s resolves to %s: %w", host, ip.IP, ErrOutOfScope)
+			}
+		}
+
+		var lastErr error
+		for _, ip := range ips {
+			conn, err := dialer.DialContext(ctx, network, net.JoinHostPort(ip.IP.String(), port))
+			if err == nil {
+				return conn, nil
+			}
+			lastErr = err
+		}
+		return nil, lastErr
+	}
+}
+
+// schemes returns the schemes to try on port, in order
+func schemes(port int) []string {
+	switch port {
+	case 80:
+		return []string{"http"}
+	case 443:
+		return []string{"https"}
+	default:
+		return []string{"https", "http"}
+	}
+}
+
+// hostPort leaves out the port when it is the scheme's default
+func hostPort(scheme, host string, port int) string {
+	if scheme == "http" && port == 80 || scheme == "https" && port == 443 {
+		if strings.Contains(host, ":") {
+			return "[" + host + "]"
+		}
+		return host
+	}
+	return net.JoinHostPort(host, strconv.Itoa(port))
+}
+
+// redirectChain returns the URLs requested after the first one
+func redirectChain(resp *http.Response) []string {
+	var chain []string
+	for req := resp.Request; req != nil && req.Response != nil; req = req.Response.Request {
+		chain = append([]string{req.URL.String()}, chain...)
+	}
+	return chain
+}
+
+// extractTitle returns the HTML title with whitespace collapsed
+func extractTitle(body []byte) string {
+	m := titleRe.FindSubmatch(body)
+	if m == nil {
+		return ""
+	}
+	return strings.Join(strings.Fields(html.UnescapeString(string(m[1]))), " ")
+}
@@ -0,0 +1,194 @@
+package ptr
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/yourusername/subrecon/internal/resolve"
+)
+
+// SourceName is the source recorded for hosts found by reverse DNS sweeps
+const SourceName = "ptr"
+
+const (
+	// DefaultIPv4Prefix groups IPv4 addresses into /24 ranges
+	DefaultIPv4Prefix = 24
+	// DefaultIPv6Prefix groups IPv6 addresses into /120 ranges
+	DefaultIPv6Prefix = 120
+
+	// Prefixes are bounded so a single range never exceeds 65536 addresses
+	minIPv4Prefix = 16
+	minIPv6Prefix = 112
+)
+
+// Options configures a sweep
+type Options struct {
+	IPv4Prefix int // prefix length IPv4 addresses are grouped into
+	IPv6Prefix int // prefix length IPv6 addresses are grouped into
+	Threads    int // concurrent PTR lookups
+}
+
+// Hit is a name under the target domain that an address points back to
+type Hit struct {
+	Host string
+	IP   string
+}
+
+// Stats summarizes a sweep
+type Stats struct {
+	Ranges    int // ranges swept
+	Addresses int // addresses queried
+	Answered  int // addresses with at least one PTR record
+	Failed    int // lookups that failed
+}
+
+// Validate checks that the prefix lengths are usable
+func (o *Options) Validate() error {
+	if o.IPv4Prefix < minIPv4Prefix || o.IPv4Prefix > 32 {
+		return fmt.Errorf("IPv4 PTR prefix must be between /%d and /32, got /%d", minIPv4Prefix, o.IPv4Prefix)
+	}
+	if o.IPv6Prefix < minIPv6Prefix || o.IPv6Prefix > 128 {
+		return fmt.Errorf("IPv6 PTR prefix must be between /%d and /128, got /%d", minIPv6Prefix, o.IPv6Prefix)
+	}
+	return nil
+}
+
+// Ranges groups addresses into the networks containing them, using the given
+// prefix lengths. Invalid addresses are ignored; the ranges are deduplicated
+// and sorted with IPv4 first.
+func Ranges(ips []string, v4Prefix, v6Prefix int) []*net.IPNet {
+	seen := make(map[string]bool)
+	ranges := make([]*net.IPNet, 0)
+
+	for _, ip := range ips {
+		addr := net.ParseIP(strings.TrimSpace(ip))
+		if addr == nil {
+			continue
+		}
+
+		var network *net.IPNet
+		if v4 := addr.To4(); v4 != nil {
+			mask := net.CIDRMask(v4Prefix, 32)
+			network = &net.IPNet{IP: v4.Mask(mask), Mask: mask}
+		} else {
+			mask := net.CIDRMask(v6Prefix, 128)
+			network = &net.IPNet{IP: addr.Mask(mask), Mask: mask}
+		}
+
+		key := network.String()
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		ranges = append(ranges, network)
+	}
+
+	sort.Slice(ranges, func(i, j int) bool {
+		if len(ranges[i].IP) != len(ranges[j].IP) {
+			return len(ranges[i].IP) < len(ranges[j].IP)
+		}
+		return bytes.Compare(ranges[i].IP, ranges[j].IP) < 0
+	})
+
+	return ranges
+}
+
+// Run sweeps the ranges around ips with PTR lookups through the resolver pool
+// and returns the names under domain that the addresses point back to, sorted
+// by host and address
+func Run(ctx context.Context, resolver *resolve.Resolver, ips []string, domain string, opts Options) ([]Hit, *Stats, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, nil, err
+	}
+
+	threads := opts.Threads
+	if threads <= 0 {
+		threads = 10
+	}
+	domain = resolve.CanonicalName(domain)
+
+	ranges := Ranges(ips, opts.IPv4Prefix, opts.IPv6Prefix)
+	stats := &Stats{Ranges: len(ranges)}
+
+	addrs := make(chan string)
+	go func() {
+		defer close(addrs)
+		for _, network := range ranges {
+			for ip := cloneIP(network.IP); network.Contains(ip); ip = nextIP(ip) {
+				select {
+				case addrs <- ip.String():
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	seen := make(map[Hit]bool)
+	hits := make([]Hit, 0)
+
+	for w := 0; w < threads; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for ip := range addrs {
+				names, err := resolver.LookupPTR(ctx, ip)
+
+				mu.Lock()
+				stats.Addresses++
+				if err != nil {
+					stats.Failed++
+				} else if len(names) > 0 {
+					stats.Answered++
+				}
+				for _, name := range names {
+					name = resolve.CanonicalName(name)
+					if name != domain && !strings.HasSuffix(name, "."+domain) {
+						continue
+					}
+					hit := Hit{Host: name, IP: ip}
+					if !seen[hit] {
+						seen[hit] = true
+						hits = append(hits, hit)
+					}
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Host != hits[j].Host {
+			return hits[i].Host < hits[j].Host
+		}
+		return hits[i].IP < hits[j].IP
+	})
+
+	return hits, stats, ctx.Err()
+}
+
+func cloneIP(ip net.IP) net.IP {
+	return append(net.IP(nil), ip...)
+}
+
+// nextIP returns the address after ip. The last address wraps around to the
+// first, which is outside any range being swept.
+func nextIP(ip net.IP) net.IP {
+	next := cloneIP(ip)
+	for i := len(next) - 1; i >= 0; i-- {
+		next[i]++
+		if next[i] != 0 {
+			break
+		}
+	}
+	return next
+}
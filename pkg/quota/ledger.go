@@ -0,0 +1,301 @@
+package quota
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Period is the window a quota applies to
+type Period string
+
+const (
+	// Daily budgets reset at 00:00 UTC every day
+	Daily Period = "daily"
+	// Monthly budgets reset at 00:00 UTC on the first day of the month
+	Monthly Period = "monthly"
+)
+
+// ParsePeriod parses a period name, defaulting to daily
+func ParsePeriod(s string) (Period, error) {
+	switch Period(s) {
+	case "", Daily:
+		return Daily, nil
+	case Monthly:
+		return Monthly, nil
+	default:
+		return "", fmt.Errorf("unknown quota period: %s", s)
+	}
+}
+
+// Start returns the beginning of the period containing t
+func (p Period) Start(t time.Time) time.Time {
+	t = t.UTC()
+	if p == Monthly {
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	}
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// Next returns the beginning of the period following the one containing t
+func (p Period) Next(t time.Time) time.Time {
+	start := p.Start(t)
+	if p == Monthly {
+		return start.AddDate(0, 1, 0)
+	}
+	return start.AddDate(0, 0, 1)
+}
+
+// Usage holds the request count of a source or key for one period
+type Usage struct {
+	Period Period    `json:"period"`
+	Start  time.Time `json:"start"`
+	Count  int       `json:"count"`
+}
+
+// Status describes the budget of a source for the current period
+type Status struct {
+	Source    string
+	Key       string
+	Period    Period
+	Used      int
+	Limit     int // 0 means unlimited
+	Remaining int
+	Resets    time.Time
+}
+
+type limit struct {
+	max    int
+	period Period
+	key    string
+}
+
+type ledgerFile struct {
+	Sources map[string]*Usage `json:"sources"`
+	Keys    map[string]*Usage `json:"keys"`
+}
+
+// Ledger is a persistent per-source and per-key request counter
+type Ledger struct {
+	path    string
+	data    ledgerFile
+	limits  map[string]limit // source -> limit
+	mu      sync.Mutex
+	nowFunc func() time.Time
+}
+
+// DefaultPath returns the default location of the usage ledger
+func DefaultPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ".subrecon-usage.json"
+	}
+	return filepath.Join(dir, "subrecon", "usage.json")
+}
+
+// Open loads the ledger stored at path, starting empty if it doesn't exist
+func Open(path string) (*Ledger, error) {
+	l := &Ledger{
+		path: path,
+		data: ledgerFile{
+			Sources: make(map[string]*Usage),
+			Keys:    make(map[string]*Usage),
+		},
+		limits:  make(map[string]limit),
+		nowFunc: time.Now,
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return l, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read usage ledger: %w", err)
+	}
+
+	if err := json.Unmarshal(data, &l.data); err != nil {
+		return nil, fmt.Errorf("failed to parse usage ledger: %w", err)
+	}
+	if l.data.Sources == nil {
+		l.data.Sources = make(map[string]*Usage)
+	}
+	if l.data.Keys == nil {
+		l.data.Keys = make(map[string]*Usage)
+	}
+
+	return l, nil
+}
+
+// SetClock overrides the time source, used by tests
+func (l *Ledger) SetClock(now func() time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.nowFunc = now
+}
+
+// SetLimit sets the budget of a source. When apiKey is not empty the budget
+// is tracked against the key, so rotating to a fresh key gets a fresh budget.
+func (l *Ledger) SetLimit(source, apiKey string, max int, period Period) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.limits[source] = limit{
+		max:    max,
+		period: period,
+		key:    keyID(source, apiKey),
+	}
+}
+
+// Reserve counts one request sent by source with apiKey and reports whether
+// it fits in the budget. With a key the budget is that of the key, so
+// rotating to a fresh key gets a fresh budget. Requests over budget are not
+// counted.
+func (l *Ledger) Reserve(source, apiKey string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	lim, ok := l.limits[source]
+	if !ok {
+		lim = limit{period: Daily}
+	}
+	now := l.nowFunc()
+
+	sourceUsage := l.current(l.data.Sources, source, lim.period, now)
+	var keyUsage *Usage
+	if key := keyID(source, apiKey); key != "" {
+		keyUsage = l.current(l.data.Keys, key, lim.period, now)
+	}
+
+	budgetUsage := sourceUsage
+	if keyUsage != nil {
+		budgetUsage = keyUsage
+	}
+	if lim.max > 0 && budgetUsage.Count >= lim.max {
+		return false
+	}
+
+	sourceUsage.Count++
+	if keyUsage != nil {
+		keyUsage.Count++
+	}
+
+	return true
+}
+
+// Remaining returns the remaining budget of a source and whether it is limited
+func (l *Ledger) Remaining(source string) (int, bool) {
+	st := l.Status(source)
+	return st.Remaining, st.Limit > 0
+}
+
+// Status returns the budget status of a source for the current period
+func (l *Ledger) Status(source string) Status {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	lim, ok := l.limits[source]
+	if !ok {
+		lim = limit{period: Daily}
+	}
+	now := l.nowFunc()
+
+	usage := l.current(l.data.Sources, source, lim.period, now)
+	if lim.key != "" {
+		usage = l.current(l.data.Keys, lim.key, lim.period, now)
+	}
+
+	st := Status{
+		Source: source,
+		Key:    lim.key,
+		Period: lim.period,
+		Used:   usage.Count,
+		Limit:  lim.max,
+		Resets: lim.period.Next(now),
+	}
+	if lim.max > 0 {
+		st.Remaining = lim.max - usage.Count
+		if st.Remaining < 0 {
+			st.Remaining = 0
+		}
+	}
+
+	return st
+}
+
+// Sources returns the names of all sources with a limit or recorded usage
+func (l *Ledger) Sources() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	seen := make(map[string]bool)
+	for name := range l.limits {
+		seen[name] = true
+	}
+	for name := range l.data.Sources {
+		seen[name] = true
+	}
+
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// Save writes the ledger back to disk
+func (l *Ledger) Save() error {
+	l.mu.Lock()
+	data, err := json.MarshalIndent(l.data, "", "  ")
+	l.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("failed to encode usage ledger: %w", err)
+	}
+
+	if dir := filepath.Dir(l.path); dir != "" {
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			return fmt.Errorf("failed to create ledger directory: %w", err)
+		}
+	}
+
+	// Write to a temporary file first so a crash never leaves a truncated ledger
+	tmp := l.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("failed to write usage ledger: %w", err)
+	}
+	if err := os.Rename(tmp, l.path); err != nil {
+		return fmt.Errorf("failed to write usage ledger: %w", err)
+	}
+
+	return nil
+}
+
+// current returns the usage entry for name in the period containing now,
+// resetting it if the stored entry belongs to an earlier period
+func (l *Ledger) current(m map[string]*Usage, name string, period Period, now time.Time) *Usage {
+	start := period.Start(now)
+
+	usage, ok := m[name]
+	if !ok || usage.Period != period || !usage.Start.Equal(start) {
+		usage = &Usage{Period: period, Start: start}
+		m[name] = usage
+	}
+
+	return usage
+}
+
+// keyID derives a stable identifier for an API key without storing the key
+func keyID(source, apiKey string) string {
+	if apiKey == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(apiKey))
+	return source + ":" + hex.EncodeToString(sum[:6])
+}
@@ -2,12 +2,14 @@ package runner
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sort"
 	"sync"
 	"time"
 
-	"github.com/yourusername/subfinder-pro/pkg/sources"
+	"github.com/yourusername/subrecon/pkg/quota"
+	"github.com/yourusername/subrecon/pkg/sources"
 	"golang.org/x/time/rate"
 )
 
@@ -17,6 +19,7 @@ type Runner struct {
 	workers      int
 	timeout      time.Duration
 	rateLimiters map[string]*rate.Limiter
+	ledger       *quota.Ledger
 	verbose      bool
 	silent       bool
 }
@@ -55,6 +58,59 @@ func (r *Runner) SetRateLimit(sourceName string, requestsPerSecond int) {
 	r.rateLimiters[sourceName] = rate.NewLimiter(rate.Limit(requestsPerSecond), 1)
 }
 
+// SetLedger sets the usage ledger used to enforce source quotas
+func (r *Runner) SetLedger(ledger *quota.Ledger) {
+	r.ledger = ledger
+}
+
+// exhausted reports whether a source has no budget left
+func (r *Runner) exhausted(name string) bool {
+	remaining, limited := r.ledger.Remaining(name)
+	return limited && remaining == 0
+}
+
+// runSource executes a single source, honouring its quota and rate limit
+func (r *Runner) runSource(ctx context.Context, src sources.Source, domain string) Result {
+	// Skip sources whose budget for the period is already spent; the
+	// requests themselves are charged as the source sends them
+	if r.ledger != nil && r.exhausted(src.Name()) {
+		if r.verbose && !r.silent {
+			fmt.Printf("[-] Quota exhausted for %s, skipping\n", src.Name())
+		}
+		return Result{
+			Source: src.Name(),
+			Error:  sources.ErrQuotaExhausted,
+		}
+	}
+	
+	// Apply rate limiting if configured
+	if limiter, ok := r.rateLimiters[src.Name()]; ok {
+		if err := limiter.Wait(ctx); err != nil {
+			return Result{
+				Source: src.Name(),
+				Error:  fmt.Errorf("rate limit wait failed: %w", err),
+			}
+		}
+	}
+	
+	if r.verbose && !r.silent {
+		fmt.Printf("[*] Running source: %s\n", src.Name())
+	}
+	
+	// Execute source
+	subdomains, err := src.Run(ctx, domain)
+	
+	if err != nil && r.verbose && !r.silent {
+		fmt.Printf("[-] Error from %s: %v\n", src.Name(), err)
+	}
+	
+	return Result{
+		Source:     src.Name(),
+		Subdomains: subdomains,
+		Error:      err,
+	}
+}
+
 // Result holds the result from a source
 type Result struct {
 	Source     string
@@ -89,33 +145,7 @@ func (r *Runner) Run(ctx context.Context, domain string) ([]string, error) {
 			sem <- struct{}{}
 			defer func() { <-sem }()
 			
-			// Apply rate limiting if configured
-			if limiter, ok := r.rateLimiters[src.Name()]; ok {
-				if err := limiter.Wait(ctx); err != nil {
-					resultsChan <- Result{
-						Source: src.Name(),
-						Error:  fmt.Errorf("rate limit wait failed: %w", err),
-					}
-					return
-				}
-			}
-			
-			if r.verbose && !r.silent {
-				fmt.Printf("[*] Running source: %s\n", src.Name())
-			}
-			
-			// Execute source
-			subdomains, err := src.Run(ctx, domain)
-			
-			if err != nil && r.verbose && !r.silent {
-				fmt.Printf("[-] Error from %s: %v\n", src.Name(), err)
-			}
-			
-			resultsChan <- Result{
-				Source:     src.Name(),
-				Subdomains: subdomains,
-				Error:      err,
-			}
+			resultsChan <- r.runSource(ctx, src, domain)
 		}(source)
 	}
 	
@@ -127,11 +157,11 @@ func (r *Runner) Run(ctx context.Context, domain string) ([]string, error) {
 	
 	// Collect results and deduplicate
 	subdomainMap := make(map[string]string) // subdomain -> source
-	var errors []error
+	var errs []error
 	
 	for result := range resultsChan {
 		if result.Error != nil {
-			errors = append(errors, fmt.Errorf("%s: %w", result.Source, result.Error))
+			errs = append(errs, fmt.Errorf("%s: %w", result.Source, result.Error))
 			continue
 		}
 		
@@ -154,8 +184,8 @@ func (r *Runner) Run(ctx context.Context, domain string) ([]string, error) {
 	sort.Strings(subdomains)
 	
 	// Return error only if all sources failed
-	if len(subdomains) == 0 && len(errors) > 0 {
-		return nil, fmt.Errorf("all sources failed: %v", errors)
+	if len(subdomains) == 0 && len(errs) > 0 {
+		return nil, fmt.Errorf("all sources failed: %w", errors.Join(errs...))
 	}
 	
 	return subdomains, nil
@@ -188,33 +218,7 @@ func (r *Runner) RunWithMetadata(ctx context.Context, domain string) ([]Subdomai
 			sem <- struct{}{}
 			defer func() { <-sem }()
 			
-			// Apply rate limiting if configured
-			if limiter, ok := r.rateLimiters[src.Name()]; ok {
-				if err := limiter.Wait(ctx); err != nil {
-					resultsChan <- Result{
-						Source: src.Name(),
-						Error:  fmt.Errorf("rate limit wait failed: %w", err),
-					}
-					return
-				}
-			}
-			
-			if r.verbose && !r.silent {
-				fmt.Printf("[*] Running source: %s\n", src.Name())
-			}
-			
-			// Execute source
-			subdomains, err := src.Run(ctx, domain)
-			
-			if err != nil && r.verbose && !r.silent {
-				fmt.Printf("[-] Error from %s: %v\n", src.Name(), err)
-			}
-			
-			resultsChan <- Result{
-				Source:     src.Name(),
-				Subdomains: subdomains,
-				Error:      err,
-			}
+			resultsChan <- r.runSource(ctx, src, domain)
 		}(source)
 	}
 	
@@ -226,11 +230,11 @@ func (r *Runner) RunWithMetadata(ctx context.Context, domain string) ([]Subdomai
 	
 	// Collect results with metadata
 	subdomainMap := make(map[string]*SubdomainResult)
-	var errors []error
+	var errs []error
 	
 	for result := range resultsChan {
 		if result.Error != nil {
-			errors = append(errors, fmt.Errorf("%s: %w", result.Source, result.Error))
+			errs = append(errs, fmt.Errorf("%s: %w", result.Source, result.Error))
 			continue
 		}
 		
@@ -261,8 +265,8 @@ func (r *Runner) RunWithMetadata(ctx context.Context, domain string) ([]Subdomai
 	})
 	
 	// Return error only if all sources failed
-	if len(results) == 0 && len(errors) > 0 {
-		return nil, fmt.Errorf("all sources failed: %v", errors)
+	if len(results) == 0 && len(errs) > 0 {
+		return nil, fmt.Errorf("all sources failed: %w", errors.Join(errs...))
 	}
 	
 	return results, nil
@@ -270,8 +274,65 @@ func (r *Runner) RunWithMetadata(ctx context.Context, domain string) ([]Subdomai
 
 // SubdomainResult holds subdomain with metadata
 type SubdomainResult struct {
-	Host      string    `json:"host"`
-	Source    string    `json:"source"`
-	Timestamp time.Time `json:"timestamp"`
-	IPs       []string  `json:"ips,omitempty"`
+	Host      string     `json:"host"`
+	Source    string     `json:"source"`
+	Timestamp time.Time  `json:"timestamp"`
+	IPs       []string   `json:"ips,omitempty"`  // every address, IPv4 and IPv6
+	IPv4      []string   `json:"ipv4,omitempty"` // A answers
+	IPv6      []string   `json:"ipv6,omitempty"` // AAAA answers
+	DNS       *DNSInfo   `json:"dns,omitempty"`
+	HTTP      []HTTPInfo `json:"http,omitempty"`     // one entry per port that answered
+	Ports     []int      `json:"ports,omitempty"`    // TCP ports open on any of the addresses
+	Geo       []GeoInfo  `json:"geo,omitempty"`      // one entry per address the GeoIP databases know
+	Provider  string     `json:"provider,omitempty"` // CDN or hosting provider serving the host
+	CDN       bool       `json:"cdn,omitempty"`      // the addresses are edges shared with other customers
+	
+	TakeoverCandidate bool   `json:"takeover_candidate,omitempty"` // CNAME points at a claimable resource
+	TakeoverService   string `json:"takeover_service,omitempty"`   // service the resource belongs to
+}
+
+// DNSInfo holds the DNS answers collected for a subdomain
+type DNSInfo struct {
+	Rcode      string                 `json:"rcode"`
+	Records    map[string][]DNSRecord `json:"records,omitempty"` // keyed by record type
+	CNAMEChain []string               `json:"cname_chain,omitempty"`
+	Validation string                 `json:"validation,omitempty"` // verdict of the trusted resolvers
+}
+
+// HTTPInfo describes what a host served on one port
+type HTTPInfo struct {
+	URL           string    `json:"url"`
+	StatusCode    int       `json:"status_code"`
+	Title         string    `json:"title,omitempty"`
+	ContentLength int64     `json:"content_length"`
+	Redirects     []string  `json:"redirects,omitempty"` // URLs followed after the first
+	FinalURL      string    `json:"final_url,omitempty"` // URL the status, title and server describe, when redirected
+	Server        string    `json:"server,omitempty"`
+	TLSVersion    string    `json:"tls_version,omitempty"`
+	Certificate   *CertInfo `json:"certificate,omitempty"`
+}
+
+// CertInfo describes the TLS certificate a host presented
+type CertInfo struct {
+	Subject     string    `json:"subject"`
+	Issuer      string    `json:"issuer"`
+	Expires     time.Time `json:"expires"`
+	Fingerprint string    `json:"fingerprint_sha256"`
+	Names       []string  `json:"names,omitempty"` // DNS subject alternative names
+}
+
+// GeoInfo describes who announces an address and where it is
+type GeoInfo struct {
+	IP      string `json:"ip"`
+	ASN     uint   `json:"asn,omitempty"`
+	Org     string `json:"org,omitempty"`
+	Country string `json:"country,omitempty"` // ISO 3166-1 alpha-2 code
+	City    string `json:"city,omitempty"`
+}
+
+// DNSRecord is a single DNS answer
+type DNSRecord struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+	TTL   uint32 `json:"ttl"`
 }
@@ -0,0 +1,190 @@
+package scope
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// File is the layout of a scope file
+type File struct {
+	InScope        []string `yaml:"in_scope"`        // hosts that may be touched, wildcards allowed
+	OutOfScope     []string `yaml:"out_of_scope"`    // hosts that must not be touched, even when in scope
+	AllowedCIDRs   []string `yaml:"allowed_cidrs"`   // if set, every address of a host must be in one of these
+	ForbiddenCIDRs []string `yaml:"forbidden_cidrs"` // no address of a host may be in one of these
+}
+
+// Scope decides which hosts and addresses may be touched
+type Scope struct {
+	include   []*regexp.Regexp
+	exclude   []*regexp.Regexp
+	allowed   []*net.IPNet
+	forbidden []*net.IPNet
+}
+
+// Load reads a scope file
+func Load(path string) (*Scope, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read scope file: %w", err)
+	}
+
+	s, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse scope file %s: %w", path, err)
+	}
+
+	return s, nil
+}
+
+// Parse parses a scope file. A scope that allows nothing is rejected, as it
+// is almost certainly a mistake.
+func Parse(data []byte) (*Scope, error) {
+	var f File
+	if err := yaml.Unmarshal(data, &f); err != nil {
+		return nil, err
+	}
+	if len(f.InScope) == 0 && len(f.AllowedCIDRs) == 0 && len(f.OutOfScope) == 0 && len(f.ForbiddenCIDRs) == 0 {
+		return nil, fmt.Errorf("scope is empty")
+	}
+
+	s := &Scope{}
+	var err error
+	if s.include, err = compileHosts(f.InScope); err != nil {
+		return nil, fmt.Errorf("in_scope: %w", err)
+	}
+	if s.exclude, err = compileHosts(f.OutOfScope); err != nil {
+		return nil, fmt.Errorf("out_of_scope: %w", err)
+	}
+	if s.allowed, err = parseCIDRs(f.AllowedCIDRs); err != nil {
+		return nil, fmt.Errorf("allowed_cidrs: %w", err)
+	}
+	if s.forbidden, err = parseCIDRs(f.ForbiddenCIDRs); err != nil {
+		return nil, fmt.Errorf("forbidden_cidrs: %w", err)
+	}
+
+	return s, nil
+}
+
+// compileHosts turns host patterns into expressions. A leading "*." matches
+// one or more labels, so *.example.com covers every subdomain of
+// example.com but not example.com itself; any other "*" matches within a
+// label, e.g. api-*.example.com.
+func compileHosts(patterns []string) ([]*regexp.Regexp, error) {
+	res := make([]*regexp.Regexp, 0, len(patterns))
+	for _, pattern := range patterns {
+		p := strings.ToLower(strings.TrimSuffix(strings.TrimSpace(pattern), "."))
+		if p == "" {
+			continue
+		}
+
+		expr := "^"
+		if strings.HasPrefix(p, "*.") {
+			expr += `(?:[^.]+\.)+`
+			p = p[2:]
+		}
+		if p == "" || strings.Contains(p, "..") {
+			return nil, fmt.Errorf("invalid host pattern %q", pattern)
+		}
+		expr += strings.ReplaceAll(regexp.QuoteMeta(p), `\*`, `[^.]*`) + "$"
+
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid host pattern %q", pattern)
+		}
+		res = append(res, re)
+	}
+	return res, nil
+}
+
+// parseCIDRs parses ranges; bare addresses are taken as single-address ranges
+func parseCIDRs(cidrs []string) ([]*net.IPNet, error) {
+	networks := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		cidr = strings.TrimSpace(cidr)
+		if !strings.Contains(cidr, "/") {
+			ip := net.ParseIP(cidr)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid range %q", cidr)
+			}
+			bits := 128
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 32
+			}
+			networks = append(networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid range %q", cidr)
+		}
+		networks = append(networks, network)
+	}
+	return networks, nil
+}
+
+// HostAllowed reports whether host is in scope by name: it matches an
+// in_scope pattern, or none are given, and no out_of_scope pattern
+func (s *Scope) HostAllowed(host string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+
+	for _, re := range s.exclude {
+		if re.MatchString(host) {
+			return false
+		}
+	}
+	if len(s.include) == 0 {
+		return true
+	}
+	for _, re := range s.include {
+		if re.MatchString(host) {
+			return true
+		}
+	}
+	return false
+}
+
+// AddressAllowed reports whether ip is in scope: it lies in an allowed
+// range, or none are given, and in no forbidden range. Unparseable
+// addresses are never allowed.
+func (s *Scope) AddressAllowed(ip string) bool {
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return false
+	}
+
+	for _, network := range s.forbidden {
+		if network.Contains(addr) {
+			return false
+		}
+	}
+	if len(s.allowed) == 0 {
+		return true
+	}
+	for _, network := range s.allowed {
+		if network.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// Allowed reports whether a host with the given addresses may be touched,
+// and if not, why. Every address must be allowed: a host that also resolves
+// into a forbidden range could send traffic there.
+func (s *Scope) Allowed(host string, ips []string) (bool, string) {
+	if !s.HostAllowed(host) {
+		return false, "host out of scope"
+	}
+	for _, ip := range ips {
+		if !s.AddressAllowed(ip) {
+			return false, fmt.Sprintf("address %s out of scope", ip)
+		}
+	}
+	return true, ""
+}
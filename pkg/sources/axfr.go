@@ -0,0 +1,193 @@
+package sources
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/yourusername/subrecon/internal/resolve"
+)
+
+// AXFR asks the target's authoritative nameservers for a zone transfer. Most
+// servers refuse, but a misconfigured one hands over every name in the zone.
+type AXFR struct {
+	config      *SourceConfig
+	client      *resolve.Client
+	resolver    string   // server used to look up NS records instead of config.Resolver
+	nameservers []string // skip the NS lookup and ask these servers instead
+}
+
+// NewAXFR creates a new zone transfer source
+func NewAXFR(config *SourceConfig) *AXFR {
+	if config == nil {
+		config = DefaultConfig()
+	}
+
+	return &AXFR{
+		config: config,
+		client: resolve.NewClient(config.GetTimeout()),
+	}
+}
+
+// SetResolver sets the server used to look up NS records instead of the
+// configured resolver
+func (a *AXFR) SetResolver(server string) {
+	a.resolver = server
+}
+
+// SetNameservers sets the servers to ask for a transfer instead of the ones
+// listed in the zone's NS records
+func (a *AXFR) SetNameservers(servers []string) {
+	a.nameservers = servers
+}
+
+// Run executes the AXFR source
+func (a *AXFR) Run(ctx context.Context, domain string) ([]string, error) {
+	domain = resolve.CanonicalName(domain)
+
+	nameservers := a.nameservers
+	if len(nameservers) == 0 {
+		var err error
+		nameservers, err = lookupNameservers(ctx, a.client, a.config, a.resolver, domain)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if a.config.Scope != nil {
+		if nameservers = screenNameservers(ctx, a.config, nameservers); len(nameservers) == 0 {
+			return nil, fmt.Errorf("no nameservers of %s in scope", domain)
+		}
+	}
+
+	// One successful transfer is the whole zone, so stop at the first
+	for _, ns := range nameservers {
+		records, err := a.client.Transfer(ctx, ns, domain)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			continue
+		}
+
+		subdomainMap := make(map[string]bool)
+		for _, rr := range records {
+			name := resolve.CanonicalName(rr.Name)
+			if strings.HasPrefix(name, "*.") {
+				continue
+			}
+			if strings.HasSuffix(name, "."+domain) || name == domain {
+				subdomainMap[name] = true
+			}
+		}
+
+		subdomains := make([]string, 0, len(subdomainMap))
+		for subdomain := range subdomainMap {
+			subdomains = append(subdomains, subdomain)
+		}
+
+		return subdomains, nil
+	}
+
+	// Refusing transfers is the norm, not an error
+	return []string{}, nil
+}
+
+// lookupNameservers returns the addresses of the zone's NS hosts. They are
+// looked up through server if set, else through the configured resolver, so
+// dns.servers and --resolvers apply, else through the system's servers.
+func lookupNameservers(ctx context.Context, client *resolve.Client, config *SourceConfig, server, domain string) ([]string, error) {
+	var resp *resolve.Message
+	var err error
+	if server != "" {
+		resp, err = client.Query(ctx, server, domain, resolve.TypeNS)
+	} else {
+		resolver := config.Resolver
+		if resolver == nil {
+			resolver = resolve.NewResolver(&resolve.Config{Timeout: config.GetTimeout()})
+		}
+		resp, err = resolver.Query(ctx, domain, resolve.TypeNS)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("NS lookup failed: %w", err)
+	}
+	if resp.Rcode != resolve.RcodeSuccess {
+		return nil, fmt.Errorf("NS lookup failed: %s", resolve.RcodeString(resp.Rcode))
+	}
+
+	servers := make([]string, 0)
+	for _, rr := range resp.Answers {
+		if rr.Type == resolve.TypeNS {
+			servers = append(servers, net.JoinHostPort(rr.Data, "53"))
+		}
+	}
+
+	return servers, nil
+}
+
+// screenNameservers returns the addresses of the servers config.Scope allows
+// by name and by every address, so only checked addresses are dialled. Zones
+// are often served by third parties whose servers the scope rules out.
+func screenNameservers(ctx context.Context, config *SourceConfig, servers []string) []string {
+	allowed := make([]string, 0, len(servers))
+	for _, server := range servers {
+		host, port, err := net.SplitHostPort(server)
+		if err != nil {
+			continue
+		}
+
+		// Servers given as addresses have no name to check
+		var ips []string
+		if net.ParseIP(host) != nil {
+			ips = []string{host}
+		} else {
+			if !config.Scope.HostAllowed(host) {
+				continue
+			}
+			ips = lookupAddresses(ctx, config, host)
+		}
+		if len(ips) == 0 || !addressesAllowed(config, ips) {
+			continue
+		}
+
+		for _, ip := range ips {
+			allowed = append(allowed, net.JoinHostPort(ip, port))
+		}
+	}
+
+	return allowed
+}
+
+// lookupAddresses resolves host through the configured resolver, or the
+// system's when there is none
+func lookupAddresses(ctx context.Context, config *SourceConfig, host string) []string {
+	if config.Resolver == nil {
+		ips, _ := net.DefaultResolver.LookupHost(ctx, host)
+		return ips
+	}
+	res, err := config.Resolver.Resolve(ctx, host)
+	if err != nil {
+		return nil
+	}
+	return res.IPs
+}
+
+// addressesAllowed reports whether config.Scope allows every one of ips
+func addressesAllowed(config *SourceConfig, ips []string) bool {
+	for _, ip := range ips {
+		if !config.Scope.AddressAllowed(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// Name returns the source name
+func (a *AXFR) Name() string {
+	return "axfr"
+}
+
+// NeedsKey indicates if API key is required
+func (a *AXFR) NeedsKey() bool {
+	return false
+}
@@ -0,0 +1,37 @@
+package sources
+
+import (
+	"errors"
+	"net/http"
+)
+
+// ErrQuotaExhausted is returned by a source whose request budget ran out
+var ErrQuotaExhausted = errors.New("quota exhausted")
+
+// Budget meters the requests sources send, e.g. a quota.Ledger
+type Budget interface {
+	// Reserve counts one request by source with apiKey and reports whether
+	// it may be sent
+	Reserve(source, apiKey string) bool
+}
+
+// budgetTransport charges every outgoing request, retries and follow-up
+// pages included, to the budget of the source's config
+type budgetTransport struct {
+	base   http.RoundTripper
+	config *SourceConfig
+	source string
+}
+
+// newBudgetTransport wraps base so requests are charged to source
+func newBudgetTransport(config *SourceConfig, source string, base http.RoundTripper) http.RoundTripper {
+	return &budgetTransport{base: base, config: config, source: source}
+}
+
+// RoundTrip refuses the request once the budget is spent
+func (t *budgetTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if b := t.config.Budget; b != nil && !b.Reserve(t.source, t.config.APIKey) {
+		return nil, ErrQuotaExhausted
+	}
+	return t.base.RoundTrip(req)
+}
@@ -2,6 +2,7 @@ package sources
 
 import (
 	"context"
+	"errors"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -28,11 +29,11 @@ func NewCrtSh(config *SourceConfig) *CrtSh {
 		config: config,
 		client: &http.Client{
 			Timeout: config.GetTimeout(),
-			Transport: &http.Transport{
+			Transport: newBudgetTransport(config, "crtsh", &http.Transport{
 				MaxIdleConns:        100,
 				MaxIdleConnsPerHost: 10,
 				IdleConnTimeout:     90 * time.Second,
-			},
+			}),
 		},
 	}
 }
@@ -63,6 +64,11 @@ func (c *CrtSh) Run(ctx context.Context, domain string) ([]string, error) {
 			break
 		}
 		
+		// Stop once the budget is spent rather than retrying
+		if errors.Is(lastErr, ErrQuotaExhausted) {
+			return nil, ErrQuotaExhausted
+		}
+		
 		if resp != nil {
 			resp.Body.Close()
 		}
@@ -0,0 +1,363 @@
+package sources
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/yourusername/subrecon/internal/resolve"
+)
+
+const (
+	// maxWalkSteps bounds an NSEC walk
+	maxWalkSteps = 100000
+	// maxNSEC3Probes is the most random names queried to collect NSEC3 hashes
+	maxNSEC3Probes = 1000
+	// nsec3IdleProbes stops collecting after this many probes without a new hash
+	nsec3IdleProbes = 50
+)
+
+// NSECWalk enumerates DNSSEC-signed zones from their denial-of-existence
+// records. Zones signed with NSEC are walked from the apex along the chain of
+// next owner names, which lists every name in the zone. For NSEC3 zones only
+// hashes of the names are exposed; they are collected and, when the source
+// has a wordlist configured, cracked offline. Queries go to the zone's
+// authoritative servers, as recursive resolvers often don't return the
+// chain for arbitrary names.
+type NSECWalk struct {
+	config   *SourceConfig
+	client   *resolve.Client
+	resolver string   // server used to look up NS records instead of config.Resolver
+	servers  []string // skip the NS lookup and query these servers instead
+
+	mu     sync.Mutex
+	hashes map[string]*resolve.NSEC3 // hashed owner -> parameters it was hashed with
+}
+
+// NewNSECWalk creates a new NSEC walking source
+func NewNSECWalk(config *SourceConfig) *NSECWalk {
+	if config == nil {
+		config = DefaultConfig()
+	}
+
+	return &NSECWalk{
+		config: config,
+		client: resolve.NewClient(config.GetTimeout()),
+		hashes: make(map[string]*resolve.NSEC3),
+	}
+}
+
+// SetResolver sets the server used to look up NS records instead of the
+// configured resolver
+func (w *NSECWalk) SetResolver(server string) {
+	w.resolver = server
+}
+
+// SetServer sets the DNS server queried during the walk instead of the
+// zone's authoritative servers
+func (w *NSECWalk) SetServer(server string) {
+	w.servers = []string{server}
+}
+
+// Hashes returns the NSEC3 hashed owner names collected by the last run,
+// e.g. for cracking with external tools
+func (w *NSECWalk) Hashes() []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	hashes := make([]string, 0, len(w.hashes))
+	for hash := range w.hashes {
+		hashes = append(hashes, hash)
+	}
+	sort.Strings(hashes)
+
+	return hashes
+}
+
+// Run executes the NSEC walking source
+func (w *NSECWalk) Run(ctx context.Context, domain string) ([]string, error) {
+	domain = resolve.CanonicalName(domain)
+
+	servers := w.servers
+	if len(servers) == 0 {
+		var err error
+		servers, err = lookupNameservers(ctx, w.client, w.config, w.resolver, domain)
+		if err != nil {
+			return nil, err
+		}
+		if len(servers) == 0 {
+			return nil, fmt.Errorf("no nameservers found for %s", domain)
+		}
+	}
+	if w.config.Scope != nil {
+		if servers = screenNameservers(ctx, w.config, servers); len(servers) == 0 {
+			return nil, fmt.Errorf("no nameservers of %s in scope", domain)
+		}
+	}
+
+	names, walked, err := w.walk(ctx, servers, domain)
+	if err != nil || walked {
+		return names, err
+	}
+
+	// Not NSEC signed, try NSEC3
+	if err := w.collectHashes(ctx, servers, domain); err != nil {
+		return nil, err
+	}
+	if w.config.Wordlist == "" {
+		return []string{}, nil
+	}
+
+	return w.crack(ctx, domain)
+}
+
+// walk follows the NSEC chain from the apex, stepping past delegations via
+// the NSEC that denies the name after them. It reports false when the apex
+// has no NSEC record.
+func (w *NSECWalk) walk(ctx context.Context, servers []string, domain string) ([]string, bool, error) {
+	names := make([]string, 0)
+	seen := make(map[string]bool)
+
+	current := domain
+	for step := 0; step < maxWalkSteps; step++ {
+		resp, err := w.query(ctx, servers, current, resolve.TypeNSEC)
+		if err != nil {
+			if step == 0 {
+				return nil, false, err
+			}
+			// Keep what the walk found before the server gave up
+			return names, true, nil
+		}
+
+		rr := ownNSEC(resp, current)
+		if rr == nil && step > 0 {
+			// Delegations have no NSEC answer of their own: the parent
+			// refers to the child instead. Ask for the name right after
+			// current and everything below it; the NSEC denying it is
+			// current's, as in ldns-walk.
+			if succ, ok := successor(current); ok {
+				if resp, err := w.query(ctx, servers, succ, resolve.TypeA); err == nil {
+					rr = ownNSEC(resp, current)
+				}
+			}
+		}
+		if rr == nil {
+			return names, step > 0, nil
+		}
+		next, _, err := resolve.ParseNSEC(rr.Data)
+		if err != nil {
+			return names, step > 0, nil
+		}
+
+		seen[current] = true
+		if !strings.HasPrefix(current, "*.") {
+			names = append(names, current)
+		}
+
+		// The chain wraps around to the apex after the last name
+		if next == domain || seen[next] || !strings.HasSuffix(next, "."+domain) {
+			return names, true, nil
+		}
+		current = next
+	}
+
+	return names, true, nil
+}
+
+// ownNSEC returns the NSEC record owned by name in the answer or, for
+// referrals and denials, the authority section of resp
+func ownNSEC(resp *resolve.Message, name string) *resolve.RR {
+	for _, section := range [][]resolve.RR{resp.Answers, resp.Authority} {
+		for i := range section {
+			if section[i].Type == resolve.TypeNSEC && resolve.CanonicalName(section[i].Name) == name {
+				return &section[i]
+			}
+		}
+	}
+	return nil
+}
+
+// successor returns the first name after name and its subtree in canonical
+// order, made by appending a zero octet to the first label
+func successor(name string) (string, bool) {
+	label, rest, _ := strings.Cut(name, ".")
+	if len(label) >= 63 {
+		return "", false
+	}
+	return label + "\x00." + rest, true
+}
+
+// collectHashes queries random names under domain and records the NSEC3
+// hashes returned as proof of their non-existence
+func (w *NSECWalk) collectHashes(ctx context.Context, servers []string, domain string) error {
+	w.mu.Lock()
+	w.hashes = make(map[string]*resolve.NSEC3)
+	w.mu.Unlock()
+
+	idle := 0
+	for probe := 0; probe < maxNSEC3Probes && idle < nsec3IdleProbes; probe++ {
+		resp, err := w.query(ctx, servers, randomLabel()+"."+domain, resolve.TypeA)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			idle++
+			continue
+		}
+
+		found := false
+		for _, rr := range resp.Authority {
+			if rr.Type != resolve.TypeNSEC3 {
+				continue
+			}
+			rec, err := resolve.ParseNSEC3(rr.Data)
+			if err != nil {
+				continue
+			}
+
+			owner := rr.Name[:strings.Index(rr.Name+".", ".")]
+			if w.addHash(owner, rec) {
+				found = true
+			}
+			if w.addHash(rec.NextHash, rec) {
+				found = true
+			}
+		}
+
+		// An unsigned zone has nothing to collect
+		if probe == 0 && !found {
+			return nil
+		}
+		if found {
+			idle = 0
+		} else {
+			idle++
+		}
+	}
+
+	return nil
+}
+
+// addHash records a hashed owner name, reporting whether it was new
+func (w *NSECWalk) addHash(hash string, rec *resolve.NSEC3) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	hash = strings.ToLower(hash)
+	if _, ok := w.hashes[hash]; ok {
+		return false
+	}
+	w.hashes[hash] = rec
+	return true
+}
+
+// nsec3Params identifies how a zone's names were hashed
+type nsec3Params struct {
+	salt       string
+	iterations uint16
+}
+
+// crack hashes word.domain for every word of the configured wordlist and
+// returns the names whose hash was collected. Hashes are grouped by salt and
+// iteration count, which change when a zone is re-signed, and every word is
+// hashed once per group.
+func (w *NSECWalk) crack(ctx context.Context, domain string) ([]string, error) {
+	w.mu.Lock()
+	groups := make(map[nsec3Params]map[string]bool)
+	for hash, rec := range w.hashes {
+		params := nsec3Params{salt: strings.ToLower(rec.Salt), iterations: rec.Iterations}
+		if groups[params] == nil {
+			groups[params] = make(map[string]bool)
+		}
+		groups[params][hash] = true
+	}
+	w.mu.Unlock()
+	if len(groups) == 0 {
+		return []string{}, nil
+	}
+
+	file, err := os.Open(w.config.Wordlist)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open wordlist: %w", err)
+	}
+	defer file.Close()
+
+	subdomains := make([]string, 0)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if ctx.Err() != nil {
+			return subdomains, ctx.Err()
+		}
+
+		word := strings.ToLower(strings.TrimSpace(scanner.Text()))
+		if word == "" || strings.HasPrefix(word, "#") {
+			continue
+		}
+
+		name := word + "." + domain
+		for params, hashes := range groups {
+			hash, err := resolve.HashName(name, params.iterations, params.salt)
+			if err == nil && hashes[hash] {
+				subdomains = append(subdomains, name)
+				break
+			}
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return subdomains, fmt.Errorf("failed to read wordlist: %w", err)
+	}
+
+	return subdomains, nil
+}
+
+// query sends a DNSSEC-enabled query so denial-of-existence records are
+// included in the response, trying the servers in turn until one answers
+func (w *NSECWalk) query(ctx context.Context, servers []string, name string, qtype uint16) (*resolve.Message, error) {
+	m := resolve.NewQuery(name, qtype)
+	m.SetEDNS0(1232, true)
+
+	var lastResp *resolve.Message
+	var lastErr error
+	for _, server := range servers {
+		resp, err := w.client.Exchange(ctx, server, m)
+		if err == nil && resp.Rcode != resolve.RcodeServFail && resp.Rcode != resolve.RcodeRefused {
+			return resp, nil
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if err == nil {
+			lastResp = resp
+		}
+		lastErr = err
+	}
+
+	if lastResp != nil {
+		return lastResp, nil
+	}
+	return nil, lastErr
+}
+
+// Name returns the source name
+func (w *NSECWalk) Name() string {
+	return "nsec"
+}
+
+// NeedsKey indicates if API key is required
+func (w *NSECWalk) NeedsKey() bool {
+	return false
+}
+
+// randomLabel returns a label that almost certainly doesn't exist
+func randomLabel() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -3,6 +3,9 @@ package sources
 import (
 	"context"
 	"time"
+
+	"github.com/yourusername/subrecon/internal/resolve"
+	"github.com/yourusername/subrecon/pkg/scope"
 )
 
 // Source represents a subdomain enumeration source
@@ -19,13 +22,24 @@ type Source interface {
 
 // SourceConfig holds configuration for a source
 type SourceConfig struct {
-	APIKey      string `yaml:"api_key"`
-	RateLimit   int    `yaml:"rate_limit"`   // requests per second
-	Timeout     int    `yaml:"timeout"`       // in seconds
-	Enabled     bool   `yaml:"enabled"`
-	Retry       int    `yaml:"retry"`
-	UserAgent   string `yaml:"user_agent"`
-	MaxResults  int    `yaml:"max_results"`
+	APIKey     string            `yaml:"api_key"`
+	RateLimit  int               `yaml:"rate_limit"` // requests per second
+	Timeout    int               `yaml:"timeout"`    // in seconds
+	Enabled    bool              `yaml:"enabled"`
+	Retry      int               `yaml:"retry"`
+	UserAgent  string            `yaml:"user_agent"`
+	MaxResults int               `yaml:"max_results"`
+	Quota      *Quota            `yaml:"quota"`
+	Wordlist   string            `yaml:"wordlist"` // words to crack NSEC3 hashes with (nsec)
+	Budget     Budget            `yaml:"-"`        // charged for every request sent, if set
+	Resolver   *resolve.Resolver `yaml:"-"`        // looks up NS records (axfr, nsec), system servers if nil
+	Scope      *scope.Scope      `yaml:"-"`        // nameservers axfr and nsec may contact, any if nil
+}
+
+// Quota holds the request budget of a source
+type Quota struct {
+	Limit  int    `yaml:"limit"`  // requests per period, 0 means unlimited
+	Period string `yaml:"period"` // daily or monthly
 }
 
 // GetTimeout returns timeout as time.Duration
@@ -0,0 +1,247 @@
+package takeover
+
+import (
+	"context"
+	_ "embed"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yourusername/subrecon/internal/resolve"
+	"gopkg.in/yaml.v3"
+)
+
+// maxBodySize bounds how much of a response is searched for signatures
+const maxBodySize = 1 << 20
+
+// Evidence recorded on findings
+const (
+	EvidenceNXDomain = "nxdomain" // the CNAME target doesn't resolve
+	EvidenceBody     = "body"     // the service answered with an unclaimed page
+)
+
+//go:embed fingerprints.yaml
+var defaultFingerprints []byte
+
+// Fingerprint describes a service whose resources can be claimed by anyone
+// once they are deleted while a CNAME still points at them
+type Fingerprint struct {
+	Service  string   `yaml:"service"`
+	CNAMEs   []string `yaml:"cname"`    // suffixes of CNAME targets belonging to the service
+	NXDomain bool     `yaml:"nxdomain"` // a target that doesn't resolve can be claimed
+	Body     []string `yaml:"body"`     // response body fragments of an unclaimed resource
+}
+
+// Finding is a host that looks open to takeover
+type Finding struct {
+	Host     string `json:"host"`
+	Service  string `json:"service"`
+	Target   string `json:"target"`   // CNAME target matched by the fingerprint
+	Evidence string `json:"evidence"` // EvidenceNXDomain or EvidenceBody
+}
+
+// Checker matches CNAME chains against fingerprints. HTTPClient and
+// UserAgent are used for body checks and may be replaced, e.g. in tests.
+type Checker struct {
+	HTTPClient *http.Client
+	UserAgent  string
+
+	fingerprints []Fingerprint
+}
+
+// DefaultFingerprints returns the built-in fingerprint set
+func DefaultFingerprints() []Fingerprint {
+	fingerprints, err := ParseFingerprints(defaultFingerprints)
+	if err != nil {
+		panic(fmt.Sprintf("takeover: invalid built-in fingerprints: %v", err))
+	}
+	return fingerprints
+}
+
+// LoadFingerprints reads a fingerprint set from a YAML file
+func LoadFingerprints(path string) ([]Fingerprint, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fingerprints: %w", err)
+	}
+
+	fingerprints, err := ParseFingerprints(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse fingerprints %s: %w", path, err)
+	}
+
+	return fingerprints, nil
+}
+
+// ParseFingerprints parses a YAML list of fingerprints. Every entry needs a
+// service, at least one CNAME suffix and a way to tell the resource is
+// unclaimed.
+func ParseFingerprints(data []byte) ([]Fingerprint, error) {
+	var fingerprints []Fingerprint
+	if err := yaml.Unmarshal(data, &fingerprints); err != nil {
+		return nil, err
+	}
+
+	for i := range fingerprints {
+		fp := &fingerprints[i]
+		if fp.Service == "" {
+			return nil, fmt.Errorf("fingerprint %d has no service", i+1)
+		}
+		if len(fp.CNAMEs) == 0 {
+			return nil, fmt.Errorf("fingerprint %s has no cname suffixes", fp.Service)
+		}
+		if !fp.NXDomain && len(fp.Body) == 0 {
+			return nil, fmt.Errorf("fingerprint %s needs nxdomain or body signatures", fp.Service)
+		}
+		for j, suffix := range fp.CNAMEs {
+			fp.CNAMEs[j] = resolve.CanonicalName(strings.TrimPrefix(suffix, "."))
+		}
+	}
+
+	return fingerprints, nil
+}
+
+// New creates a checker using fingerprints, or the built-in set when nil
+func New(fingerprints []Fingerprint, timeout time.Duration) *Checker {
+	if fingerprints == nil {
+		fingerprints = DefaultFingerprints()
+	}
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+
+	return &Checker{
+		HTTPClient: &http.Client{
+			Timeout: timeout,
+			// An unclaimed resource answers directly; don't wander off
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		UserAgent:    "SubFinder-Pro/1.0",
+		fingerprints: fingerprints,
+	}
+}
+
+// Match returns the fingerprint matching the CNAME chain and the target it
+// matched. Targets are tried from the end of the chain.
+func (c *Checker) Match(chain []string) (*Fingerprint, string) {
+	for i := len(chain) - 1; i >= 0; i-- {
+		target := resolve.CanonicalName(chain[i])
+		for j := range c.fingerprints {
+			for _, suffix := range c.fingerprints[j].CNAMEs {
+				if target == suffix || strings.HasSuffix(target, "."+suffix) {
+					return &c.fingerprints[j], target
+				}
+			}
+		}
+	}
+
+	return nil, ""
+}
+
+// Check reports whether the resolved host points at a service resource that
+// can be claimed. Hosts whose CNAME target doesn't resolve are candidates
+// for services that accept NXDOMAIN as proof; hosts that do resolve are
+// fetched over HTTPS and then HTTP and matched against the body signatures.
+// It returns nil when the host doesn't look vulnerable.
+func (c *Checker) Check(ctx context.Context, res *resolve.Result) *Finding {
+	fp, target := c.Match(res.CNAMEChain())
+	if fp == nil {
+		return nil
+	}
+
+	finding := &Finding{Host: res.Host, Service: fp.Service, Target: target}
+
+	if res.Rcode == resolve.RcodeNXDomain {
+		if fp.NXDomain {
+			finding.Evidence = EvidenceNXDomain
+			return finding
+		}
+		return nil
+	}
+
+	if len(fp.Body) == 0 || !res.Exists {
+		return nil
+	}
+
+	for _, scheme := range []string{"https", "http"} {
+		body, err := c.fetch(ctx, scheme+"://"+res.Host+"/")
+		if err != nil {
+			continue
+		}
+		for _, signature := range fp.Body {
+			if strings.Contains(body, signature) {
+				finding.Evidence = EvidenceBody
+				return finding
+			}
+		}
+		// The first scheme that answers decides
+		return nil
+	}
+
+	return nil
+}
+
+// CheckMany checks results concurrently using a fixed pool of workers.
+// Findings are returned in the order of the input, nil for hosts that don't
+// look vulnerable or were left unchecked because ctx was cancelled.
+func (c *Checker) CheckMany(ctx context.Context, results []*resolve.Result, workers int) []*Finding {
+	if workers <= 0 {
+		workers = 10
+	}
+
+	findings := make([]*Finding, len(results))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for i := range jobs {
+				findings[i] = c.Check(ctx, results[i])
+			}
+		}()
+	}
+
+feed:
+	for i := range results {
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	return findings
+}
+
+// fetch returns the start of the body served at url
+func (c *Checker) fetch(ctx context.Context, url string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("User-Agent", c.UserAgent)
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxBodySize))
+	if err != nil {
+		return "", err
+	}
+
+	return string(body), nil
+}
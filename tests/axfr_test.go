@@ -0,0 +1,157 @@
+package tests
+
+import (
+	"context"
+	"net"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yourusername/subrecon/internal/resolve"
+	"github.com/yourusername/subrecon/pkg/scope"
+	"github.com/yourusername/subrecon/pkg/sources"
+)
+
+func newTransferServer(t *testing.T, allowed bool) *fakeDNSServer {
+	t.Helper()
+
+	server := newFakeDNSServer(t)
+	server.SetSOA(exampleSOA)
+	server.Add(
+		"example.com NS ns1.example.com",
+		"www.example.com A 192.0.2.1",
+		"mail.example.com A 192.0.2.2",
+		"vpn.internal.example.com A 10.0.0.1",
+		"*.dev.example.com A 192.0.2.66",
+	)
+	server.SetTransfer(allowed)
+
+	return server
+}
+
+func TestClientTransfer(t *testing.T) {
+	server := newTransferServer(t, true)
+	client := resolve.NewClient(2 * time.Second)
+
+	records, err := client.Transfer(context.Background(), server.Addr, "example.com")
+	if err != nil {
+		t.Fatalf("Transfer failed: %v", err)
+	}
+
+	// SOA, five records, SOA
+	if len(records) != 7 {
+		t.Fatalf("Expected 7 records, got %d", len(records))
+	}
+	if records[0].Type != resolve.TypeSOA || records[6].Type != resolve.TypeSOA {
+		t.Error("Expected the transfer to be framed by SOA records")
+	}
+	if server.TCPQueries() != 1 {
+		t.Errorf("Expected a single TCP query, got %d", server.TCPQueries())
+	}
+}
+
+func TestClientTransferRefused(t *testing.T) {
+	server := newTransferServer(t, false)
+	client := resolve.NewClient(2 * time.Second)
+
+	if _, err := client.Transfer(context.Background(), server.Addr, "example.com"); err == nil {
+		t.Error("Expected refused transfer to fail")
+	}
+	if _, err := client.Transfer(context.Background(), "https://dns.example/dns-query", "example.com"); err == nil {
+		t.Error("Expected transfer over DoH to be rejected")
+	}
+}
+
+func TestAXFRSource(t *testing.T) {
+	refusing := newTransferServer(t, false)
+	allowing := newTransferServer(t, true)
+
+	src := sources.NewAXFR(nil)
+	src.SetNameservers([]string{refusing.Addr, allowing.Addr})
+
+	subdomains, err := src.Run(context.Background(), "example.com")
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	sort.Strings(subdomains)
+
+	want := "example.com,mail.example.com,vpn.internal.example.com,www.example.com"
+	if strings.Join(subdomains, ",") != want {
+		t.Errorf("Run = %v, want %s", subdomains, want)
+	}
+	if src.Name() != "axfr" || src.NeedsKey() {
+		t.Error("Unexpected source metadata")
+	}
+}
+
+func TestAXFRSourceAllRefused(t *testing.T) {
+	server := newTransferServer(t, false)
+
+	src := sources.NewAXFR(nil)
+	src.SetNameservers([]string{server.Addr})
+
+	subdomains, err := src.Run(context.Background(), "example.com")
+	if err != nil {
+		t.Fatalf("Expected refusal not to be an error, got %v", err)
+	}
+	if len(subdomains) != 0 {
+		t.Errorf("Expected no subdomains, got %v", subdomains)
+	}
+}
+
+func TestAXFRLooksUpNSThroughConfiguredResolver(t *testing.T) {
+	server := newFakeDNSServer(t)
+	server.Add("example.com NS ns1.example.invalid")
+
+	config := sources.DefaultConfig()
+	config.Timeout = 2
+	config.Resolver = resolve.NewResolver(&resolve.Config{
+		Servers: []string{server.Addr},
+		Timeout: 2 * time.Second,
+	})
+
+	src := sources.NewAXFR(config)
+	if _, err := src.Run(context.Background(), "example.com"); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if server.Queries() != 1 {
+		t.Errorf("Expected the NS lookup to go to the configured resolver, got %d queries", server.Queries())
+	}
+}
+
+func TestAXFRSkipsNameserversOutOfScope(t *testing.T) {
+	server := newTransferServer(t, true)
+	_, port, _ := net.SplitHostPort(server.Addr)
+
+	tests := []struct {
+		nameserver string
+		scope      string
+		allowed    bool
+	}{
+		{"localhost:" + port, "out_of_scope: [localhost]", false},
+		{server.Addr, "forbidden_cidrs: [127.0.0.0/8]", false},
+		{server.Addr, "forbidden_cidrs: [10.0.0.0/8]", true},
+	}
+	for _, tt := range tests {
+		sc, err := scope.Parse([]byte(tt.scope))
+		if err != nil {
+			t.Fatal(err)
+		}
+		config := sources.DefaultConfig()
+		config.Scope = sc
+
+		src := sources.NewAXFR(config)
+		src.SetNameservers([]string{tt.nameserver})
+
+		before := server.TCPQueries()
+		subdomains, err := src.Run(context.Background(), "example.com")
+		contacted := server.TCPQueries() > before
+		if tt.allowed && (err != nil || len(subdomains) == 0 || !contacted) {
+			t.Errorf("Expected %s to be asked with %q, got %v, %v", tt.nameserver, tt.scope, subdomains, err)
+		}
+		if !tt.allowed && (err == nil || contacted) {
+			t.Errorf("Expected %s not to be contacted with %q", tt.nameserver, tt.scope)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package tests
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/yourusername/subrecon/pkg/bruteforce"
+)
+
+func TestBruteforceCandidates(t *testing.T) {
+	wordlist := strings.NewReader("www\n# comment\n\n  API \nbad word\ndev.internal\n*\n")
+
+	names, readErr := bruteforce.Candidates(context.Background(), wordlist, "Example.com")
+	got := make([]string, 0)
+	for name := range names {
+		got = append(got, name)
+	}
+	if err := readErr(); err != nil {
+		t.Fatalf("Candidates failed: %v", err)
+	}
+
+	want := []string{"www.example.com", "api.example.com", "dev.internal.example.com"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("Candidates = %v, want %v", got, want)
+	}
+}
+
+func TestBruteforceRun(t *testing.T) {
+	server := newFakeDNSServer(t)
+	server.Add(
+		"www.example.com A 192.0.2.1",
+		"mail.example.com A 192.0.2.2",
+		"*.dev.example.com A 192.0.2.66",
+		"api.dev.example.com A 192.0.2.10",
+	)
+
+	resolver := newTestResolver(t, server)
+	wordlist := strings.NewReader("www\nmail\nnothing\napi.dev\njunk.dev\n")
+
+	hits, stats, err := bruteforce.Run(context.Background(), resolver, wordlist, "example.com", 4)
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	hosts := make([]string, len(hits))
+	for i, hit := range hits {
+		hosts[i] = hit.Host
+	}
+	want := []string{"api.dev.example.com", "mail.example.com", "www.example.com"}
+	if strings.Join(hosts, ",") != strings.Join(want, ",") {
+		t.Errorf("Hits = %v, want %v", hosts, want)
+	}
+
+	if stats.Candidates != 5 || stats.Resolved != 4 || stats.Wildcards != 1 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+}
+
+func TestBruteforceCancelled(t *testing.T) {
+	server := newFakeDNSServer(t)
+	resolver := newTestResolver(t, server)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	wordlist := strings.NewReader(strings.Repeat("word\n", 10000))
+	if _, _, err := bruteforce.Run(ctx, resolver, wordlist, "example.com", 4); err == nil {
+		t.Error("Expected cancelled run to return an error")
+	}
+}
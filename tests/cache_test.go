@@ -0,0 +1,203 @@
+package tests
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/yourusername/subrecon/internal/resolve"
+)
+
+// exampleSOA lets negative answers be cached for a minute
+var exampleSOA = resolve.RR{
+	Name:  "example.com",
+	Type:  resolve.TypeSOA,
+	Class: resolve.ClassINET,
+	TTL:   3600,
+	Data:  "ns1.example.com hostmaster.example.com 1 7200 900 1209600 60",
+}
+
+// defaultTypes are the record types a resolver asks for by default
+var defaultTypes = []uint16{resolve.TypeA, resolve.TypeAAAA}
+
+func TestCacheExpiresWithTTL(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	cache := resolve.NewCache(10)
+	cache.SetClock(func() time.Time { return now })
+
+	cache.Put(&resolve.Result{Host: "www.example.com", Exists: true}, defaultTypes, time.Minute)
+	cache.Put(&resolve.Result{Host: "zero.example.com", Exists: true}, defaultTypes, 0)
+
+	if _, ok := cache.Get("www.example.com", defaultTypes); !ok {
+		t.Error("Expected fresh entry to be cached")
+	}
+	if _, ok := cache.Get("zero.example.com", defaultTypes); ok {
+		t.Error("Expected zero TTL answer not to be cached")
+	}
+
+	now = now.Add(time.Minute)
+	if _, ok := cache.Get("www.example.com", defaultTypes); ok {
+		t.Error("Expected entry to expire after its TTL")
+	}
+	if cache.Len() != 0 {
+		t.Errorf("Expected expired entry to be evicted, %d left", cache.Len())
+	}
+}
+
+func TestCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	cache := resolve.NewCache(2)
+
+	cache.Put(&resolve.Result{Host: "a.example.com"}, defaultTypes, time.Hour)
+	cache.Put(&resolve.Result{Host: "b.example.com"}, defaultTypes, time.Hour)
+	cache.Get("a.example.com", defaultTypes)
+	cache.Put(&resolve.Result{Host: "c.example.com"}, defaultTypes, time.Hour)
+
+	if cache.Len() != 2 {
+		t.Errorf("Expected 2 entries, got %d", cache.Len())
+	}
+	if _, ok := cache.Get("b.example.com", defaultTypes); ok {
+		t.Error("Expected least recently used entry to be evicted")
+	}
+	for _, host := range []string{"a.example.com", "c.example.com"} {
+		if _, ok := cache.Get(host, defaultTypes); !ok {
+			t.Errorf("Expected %s to be cached", host)
+		}
+	}
+}
+
+func TestCacheKeyedOnRecordTypes(t *testing.T) {
+	cache := resolve.NewCache(10)
+	cache.Put(&resolve.Result{Host: "www.example.com", Exists: true}, []uint16{resolve.TypeA}, time.Minute)
+
+	if _, ok := cache.Get("www.example.com", defaultTypes); ok {
+		t.Error("Expected an answer to A alone not to serve a lookup of A and AAAA")
+	}
+	if _, ok := cache.Get("www.example.com", []uint16{resolve.TypeA, resolve.TypeA}); !ok {
+		t.Error("Expected the same set of types to hit the cache")
+	}
+}
+
+func TestResolverNegativeCaching(t *testing.T) {
+	server := newFakeDNSServer(t)
+	server.Add("www.example.com A 192.0.2.1")
+	server.SetRcode("broken.example.com", resolve.RcodeServFail)
+
+	resolver := newTestResolver(t, server)
+	ctx := context.Background()
+
+	// Without an SOA record NXDOMAIN must not be cached
+	resolver.Resolve(ctx, "missing.example.com")
+	queries := server.Queries()
+	resolver.Resolve(ctx, "missing.example.com")
+	if server.Queries() == queries {
+		t.Error("Expected NXDOMAIN without SOA not to be cached")
+	}
+
+	server.SetSOA(exampleSOA)
+	resolver.Resolve(ctx, "gone.example.com")
+	queries = server.Queries()
+	result, err := resolver.Resolve(ctx, "gone.example.com")
+	if err != nil || result.Exists {
+		t.Fatalf("Expected cached NXDOMAIN, got %+v, %v", result, err)
+	}
+	if server.Queries() != queries {
+		t.Error("Expected NXDOMAIN with SOA to be served from cache")
+	}
+
+	// Server failures are never cached
+	resolver.Resolve(ctx, "broken.example.com")
+	queries = server.Queries()
+	resolver.Resolve(ctx, "broken.example.com")
+	if server.Queries() == queries {
+		t.Error("Expected SERVFAIL not to be cached")
+	}
+
+	// Positive answers are
+	resolver.Resolve(ctx, "www.example.com")
+	queries = server.Queries()
+	resolver.Resolve(ctx, "www.example.com")
+	if server.Queries() != queries {
+		t.Error("Expected positive answer to be served from cache")
+	}
+}
+
+func TestResolverCachePersistence(t *testing.T) {
+	server := newFakeDNSServer(t)
+	server.Add("www.example.com A 192.0.2.1")
+	server.SetSOA(exampleSOA)
+
+	path := filepath.Join(t.TempDir(), "dns-cache.json")
+	ctx := context.Background()
+
+	first := newTestResolver(t, server)
+	if _, err := first.Resolve(ctx, "www.example.com"); err != nil {
+		t.Fatalf("Resolve failed: %v", err)
+	}
+	if err := first.SaveCache(path); err != nil {
+		t.Fatalf("SaveCache failed: %v", err)
+	}
+
+	second := newTestResolver(t, server)
+	if err := second.LoadCache(path); err != nil {
+		t.Fatalf("LoadCache failed: %v", err)
+	}
+
+	queries := server.Queries()
+	result, err := second.Resolve(ctx, "www.example.com")
+	if err != nil {
+		t.Fatalf("Resolve failed: %v", err)
+	}
+	if server.Queries() != queries {
+		t.Error("Expected persisted answer to be used without querying")
+	}
+	if !result.Exists || len(result.IPs) != 1 || result.IPs[0] != "192.0.2.1" {
+		t.Errorf("Unexpected persisted answer: %+v", result)
+	}
+
+	if err := second.LoadCache(filepath.Join(t.TempDir(), "missing.json")); err != nil {
+		t.Errorf("Expected missing cache file to be ignored, got %v", err)
+	}
+}
+
+func TestResolverCacheFileRecordTypes(t *testing.T) {
+	server := newFakeDNSServer(t)
+	server.Add("www.example.com A 192.0.2.1", "www.example.com TXT hello")
+
+	path := filepath.Join(t.TempDir(), "dns-cache.json")
+	ctx := context.Background()
+
+	first := newTestResolver(t, server)
+	if _, err := first.Resolve(ctx, "www.example.com"); err != nil {
+		t.Fatalf("Resolve failed: %v", err)
+	}
+	if err := first.SaveCache(path); err != nil {
+		t.Fatalf("SaveCache failed: %v", err)
+	}
+
+	// A run asking for TXT too must not take the A/AAAA answer as complete
+	second := resolve.NewResolver(&resolve.Config{
+		Servers:     []string{server.Addr},
+		Timeout:     2 * time.Second,
+		RecordTypes: []uint16{resolve.TypeA, resolve.TypeAAAA, resolve.TypeTXT},
+	})
+	if err := second.LoadCache(path); err != nil {
+		t.Fatalf("LoadCache failed: %v", err)
+	}
+
+	queries := server.Queries()
+	result, err := second.Resolve(ctx, "www.example.com")
+	if err != nil {
+		t.Fatalf("Resolve failed: %v", err)
+	}
+	if server.Queries() == queries {
+		t.Error("Expected an answer to other record types to be dropped on load")
+	}
+	found := false
+	for _, rr := range result.Records {
+		found = found || rr.Type == resolve.TypeTXT
+	}
+	if !found {
+		t.Errorf("Expected the TXT record, got %+v", result)
+	}
+}
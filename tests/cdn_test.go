@@ -0,0 +1,102 @@
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/yourusername/subrecon/pkg/cdn"
+)
+
+func TestDefaultProviders(t *testing.T) {
+	providers := cdn.DefaultProviders()
+	names := make(map[string]bool)
+	for _, p := range providers {
+		names[p.Name] = true
+	}
+	for _, want := range []string{"Cloudflare", "Akamai", "Fastly", "AWS", "Google Cloud", "Azure"} {
+		if !names[want] {
+			t.Errorf("Expected %s in the built-in providers", want)
+		}
+	}
+}
+
+func TestClassify(t *testing.T) {
+	c := cdn.New(nil)
+
+	tests := []struct {
+		name     string
+		chain    []string
+		ips      []string
+		provider string
+		isCDN    bool
+		evidence string
+	}{
+		{"cloudflare address", nil, []string{"104.16.1.1"}, "Cloudflare", true, cdn.EvidenceIP},
+		{"cloudflare IPv6", nil, []string{"2606:4700::6810:1"}, "Cloudflare", true, cdn.EvidenceIP},
+		{"fastly cname", []string{"www.example.com", "example.map.fastly.net."}, []string{"192.0.2.1"}, "Fastly", true, cdn.EvidenceCNAME},
+		{"cloudfront within aws", nil, []string{"52.84.10.10"}, "Amazon CloudFront", true, cdn.EvidenceIP},
+		{"plain aws", nil, []string{"52.1.2.3"}, "AWS", false, cdn.EvidenceIP},
+		{"akamai cname over aws address", []string{"www.example.com", "www.example.com.edgekey.net"}, []string{"52.1.2.3"}, "Akamai", true, cdn.EvidenceCNAME},
+		{"second address matches", nil, []string{"192.0.2.1", "35.190.0.1"}, "Google Cloud", false, cdn.EvidenceIP},
+		{"unknown", nil, []string{"192.0.2.1", "not-an-ip"}, "", false, ""},
+	}
+	for _, tt := range tests {
+		match := c.Classify(tt.chain, tt.ips)
+		if tt.provider == "" {
+			if match != nil {
+				t.Errorf("%s: expected no match, got %+v", tt.name, match)
+			}
+			continue
+		}
+		if match == nil || match.Provider != tt.provider || match.CDN != tt.isCDN || match.Evidence != tt.evidence {
+			t.Errorf("%s: got %+v, want %s (cdn %v, %s)", tt.name, match, tt.provider, tt.isCDN, tt.evidence)
+		}
+	}
+}
+
+func TestLoadProviders(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ranges.yaml")
+	data := `
+- provider: Example Edge
+  cdn: true
+  cname: [.edge.example.net]
+  ranges: [198.51.100.0/24]
+- provider: Example Cloud
+  ranges: [198.51.0.0/16]
+`
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	providers, err := cdn.LoadProviders(path)
+	if err != nil {
+		t.Fatalf("LoadProviders failed: %v", err)
+	}
+	c := cdn.New(providers)
+
+	if m := c.Classify(nil, []string{"198.51.100.7"}); m == nil || m.Provider != "Example Edge" || !m.CDN {
+		t.Errorf("Expected the narrower range to win, got %+v", m)
+	}
+	if m := c.Classify(nil, []string{"198.51.7.7"}); m == nil || m.Provider != "Example Cloud" || m.CDN {
+		t.Errorf("Unexpected match %+v", m)
+	}
+	if m := c.Classify([]string{"a.edge.example.net"}, nil); m == nil || m.Provider != "Example Edge" {
+		t.Errorf("Expected a CNAME match, got %+v", m)
+	}
+
+	// A loaded list replaces the built-in one
+	if m := c.Classify(nil, []string{"104.16.1.1"}); m != nil {
+		t.Errorf("Expected built-in ranges to be replaced, got %+v", m)
+	}
+
+	for _, bad := range []string{
+		"- cname: [x.net]",
+		"- provider: Empty",
+		"- provider: Broken\n  ranges: [10.0.0.0/33]",
+	} {
+		if _, err := cdn.ParseProviders([]byte(bad)); err == nil {
+			t.Errorf("Expected %q to be rejected", bad)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package tests
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"net"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/yourusername/subrecon/pkg/probe"
+)
+
+// newCertServer starts a TLS server presenting a self-signed certificate
+// for names
+func newCertServer(t *testing.T, names []string, notAfter time.Time) (*httptest.Server, *x509.Certificate) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: names[0], Organization: []string{"Example Corp"}},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     notAfter,
+		DNSNames:     names,
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+
+	srv := httptest.NewUnstartedServer(newPortalHandler())
+	srv.TLS = &tls.Config{Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}}}
+	srv.StartTLS()
+	t.Cleanup(srv.Close)
+
+	return srv, cert
+}
+
+func TestProbeCertificate(t *testing.T) {
+	notAfter := time.Now().Add(30 * 24 * time.Hour).Truncate(time.Second)
+	srv, cert := newCertServer(t, []string{"www.example.com", "*.dev.example.com", "vpn.corp.example.com"}, notAfter)
+
+	prober := probe.New(probe.Options{Ports: []int{serverPort(t, srv)}, Timeout: 2 * time.Second})
+	responses := prober.Probe(context.Background(), "127.0.0.1")
+	if len(responses) != 1 {
+		t.Fatalf("Expected one response, got %d", len(responses))
+	}
+
+	got := responses[0].Certificate
+	if got == nil {
+		t.Fatal("Expected the certificate to be recorded")
+	}
+	sum := sha256.Sum256(cert.Raw)
+	if got.Fingerprint != hex.EncodeToString(sum[:]) {
+		t.Errorf("Fingerprint = %s", got.Fingerprint)
+	}
+	if got.Subject != "CN=www.example.com,O=Example Corp" || got.Issuer != got.Subject {
+		t.Errorf("Unexpected subject or issuer: %q, %q", got.Subject, got.Issuer)
+	}
+	if !got.NotAfter.Equal(notAfter) {
+		t.Errorf("NotAfter = %v, want %v", got.NotAfter, notAfter)
+	}
+	if len(got.Names) != 3 {
+		t.Errorf("Names = %v", got.Names)
+	}
+}
+
+func TestProbeCertificateOverHTTP(t *testing.T) {
+	srv := httptest.NewServer(newPortalHandler())
+	defer srv.Close()
+
+	prober := probe.New(probe.Options{Ports: []int{serverPort(t, srv)}, Timeout: 2 * time.Second})
+	responses := prober.Probe(context.Background(), "127.0.0.1")
+	if len(responses) != 1 || responses[0].Certificate != nil {
+		t.Errorf("Expected no certificate over plain HTTP, got %+v", responses)
+	}
+}
+
+func TestNamesUnder(t *testing.T) {
+	names := probe.NamesUnder([]string{
+		"*.dev.example.com",
+		"VPN.corp.example.com.",
+		"example.com",
+		"notexample.com",
+		"www.example.org",
+		"dev.example.com",
+	}, "example.com")
+
+	if fmt.Sprint(names) != "[dev.example.com example.com vpn.corp.example.com]" {
+		t.Errorf("NamesUnder = %v", names)
+	}
+}
@@ -0,0 +1,312 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yourusername/subrecon/internal/resolve"
+)
+
+func TestMessageRoundTrip(t *testing.T) {
+	m := resolve.NewQuery("Example.COM.", resolve.TypeMX)
+	m.ID = 4242
+	m.Response = true
+	m.Rcode = resolve.RcodeSuccess
+	m.Answers = []resolve.RR{
+		{Name: "example.com", Type: resolve.TypeA, TTL: 60, Data: "192.0.2.1"},
+		{Name: "example.com", Type: resolve.TypeAAAA, TTL: 60, Data: "2001:db8::1"},
+		{Name: "www.example.com", Type: resolve.TypeCNAME, TTL: 60, Data: "example.com"},
+		{Name: "example.com", Type: resolve.TypeMX, TTL: 60, Data: "10 mail.example.com"},
+		{Name: "example.com", Type: resolve.TypeTXT, TTL: 60, Data: "v=spf1 -all"},
+		{Name: "example.com", Type: resolve.TypeNS, TTL: 60, Data: "ns1.example.com"},
+		{Name: "example.com", Type: resolve.TypeSOA, TTL: 60, Data: "ns1.example.com hostmaster.example.com 1 7200 3600 1209600 300"},
+		{Name: "1.2.0.192.in-addr.arpa", Type: resolve.TypePTR, TTL: 60, Data: "host.example.com"},
+		{Name: "_sip._tcp.example.com", Type: resolve.TypeSRV, TTL: 60, Data: "10 5 5060 sip.example.com"},
+	}
+
+	data, err := m.Pack()
+	if err != nil {
+		t.Fatalf("Pack failed: %v", err)
+	}
+
+	got, err := resolve.Unpack(data)
+	if err != nil {
+		t.Fatalf("Unpack failed: %v", err)
+	}
+
+	if got.ID != 4242 || !got.Response || !got.RecursionDesired {
+		t.Errorf("Header not preserved: %+v", got.Header)
+	}
+	if got.Questions[0].Name != "example.com" || got.Questions[0].Type != resolve.TypeMX {
+		t.Errorf("Question not preserved: %+v", got.Questions[0])
+	}
+	if len(got.Answers) != len(m.Answers) {
+		t.Fatalf("Expected %d answers, got %d", len(m.Answers), len(got.Answers))
+	}
+	for i, rr := range m.Answers {
+		if got.Answers[i].Data != rr.Data || got.Answers[i].Type != rr.Type || got.Answers[i].TTL != rr.TTL {
+			t.Errorf("Answer %d: expected %v, got %v", i, rr, got.Answers[i])
+		}
+	}
+}
+
+func TestUnpackCompressedNames(t *testing.T) {
+	// Response for www.example.com A with the answer owner compressed to
+	// point at the question name
+	msg := []byte{
+		0x00, 0x01, 0x81, 0x80, 0x00, 0x01, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00,
+		3, 'w', 'w', 'w', 7, 'e', 'x', 'a', 'm', 'p', 'l', 'e', 3, 'c', 'o', 'm', 0,
+		0x00, 0x01, 0x00, 0x01,
+		0xc0, 0x0c, 0x00, 0x01, 0x00, 0x01, 0x00, 0x00, 0x0e, 0x10, 0x00, 0x04, 192, 0, 2, 7,
+	}
+
+	m, err := resolve.Unpack(msg)
+	if err != nil {
+		t.Fatalf("Unpack failed: %v", err)
+	}
+
+	if len(m.Answers) != 1 {
+		t.Fatalf("Expected 1 answer, got %d", len(m.Answers))
+	}
+	rr := m.Answers[0]
+	if rr.Name != "www.example.com" || rr.Data != "192.0.2.7" || rr.TTL != 3600 {
+		t.Errorf("Unexpected answer: %v", rr)
+	}
+}
+
+func TestClientQuery(t *testing.T) {
+	server := newFakeDNSServer(t)
+	server.Add(
+		"www.example.com CNAME web.example.com",
+		"web.example.com A 192.0.2.10",
+		"example.com TXT hello world",
+	)
+
+	client := resolve.NewClient(2 * time.Second)
+	ctx := context.Background()
+
+	resp, err := client.Query(ctx, server.Addr, "www.example.com", resolve.TypeA)
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if resp.Rcode != resolve.RcodeSuccess {
+		t.Errorf("Expected NOERROR, got %s", resolve.RcodeString(resp.Rcode))
+	}
+	if len(resp.Answers) != 2 || resp.Answers[0].Type != resolve.TypeCNAME || resp.Answers[1].Data != "192.0.2.10" {
+		t.Errorf("Expected CNAME chain and A record, got %v", resp.Answers)
+	}
+
+	resp, err = client.Query(ctx, server.Addr, "example.com", resolve.TypeTXT)
+	if err != nil {
+		t.Fatalf("TXT query failed: %v", err)
+	}
+	if len(resp.Answers) != 1 || resp.Answers[0].Data != "hello world" {
+		t.Errorf("Unexpected TXT answer: %v", resp.Answers)
+	}
+}
+
+func TestClientRcodes(t *testing.T) {
+	server := newFakeDNSServer(t)
+	server.SetRcode("broken.example.com", resolve.RcodeServFail)
+	server.SetRcode("private.example.com", resolve.RcodeRefused)
+
+	client := resolve.NewClient(2 * time.Second)
+	ctx := context.Background()
+
+	tests := map[string]int{
+		"missing.example.com": resolve.RcodeNXDomain,
+		"broken.example.com":  resolve.RcodeServFail,
+		"private.example.com": resolve.RcodeRefused,
+	}
+	for name, want := range tests {
+		resp, err := client.Query(ctx, server.Addr, name, resolve.TypeA)
+		if err != nil {
+			t.Fatalf("Query for %s failed: %v", name, err)
+		}
+		if resp.Rcode != want {
+			t.Errorf("%s: expected %s, got %s", name, resolve.RcodeString(want), resolve.RcodeString(resp.Rcode))
+		}
+	}
+}
+
+func TestClientTCPFallback(t *testing.T) {
+	server := newFakeDNSServer(t)
+	server.Add("big.example.com A 192.0.2.1")
+	server.SetTruncate(true)
+
+	client := resolve.NewClient(2 * time.Second)
+	resp, err := client.Query(context.Background(), server.Addr, "big.example.com", resolve.TypeA)
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+
+	if server.TCPQueries() != 1 {
+		t.Errorf("Expected a TCP retry, got %d TCP queries", server.TCPQueries())
+	}
+	if len(resp.Answers) != 1 || resp.Answers[0].Data != "192.0.2.1" {
+		t.Errorf("Unexpected answer over TCP: %v", resp.Answers)
+	}
+}
+
+func TestResolverAgainstFakeServer(t *testing.T) {
+	server := newFakeDNSServer(t)
+	server.Add(
+		"api.example.com A 192.0.2.1",
+		"api.example.com AAAA 2001:db8::1",
+	)
+	server.SetRcode("flaky.example.com", resolve.RcodeServFail)
+
+	resolver := resolve.NewResolver(&resolve.Config{
+		Servers: []string{server.Addr},
+		Timeout: 2 * time.Second,
+	})
+	ctx := context.Background()
+
+	result, err := resolver.Resolve(ctx, "api.example.com")
+	if err != nil {
+		t.Fatalf("Resolve failed: %v", err)
+	}
+	if !result.Exists || len(result.IPs) != 2 {
+		t.Errorf("Expected A and AAAA answers, got %v", result.IPs)
+	}
+
+	result, _ = resolver.Resolve(ctx, "nope.example.com")
+	if result.Exists || result.Rcode != resolve.RcodeNXDomain || result.Error != nil {
+		t.Errorf("Expected clean NXDOMAIN, got rcode %d err %v", result.Rcode, result.Error)
+	}
+
+	result, _ = resolver.Resolve(ctx, "flaky.example.com")
+	if result.Rcode != resolve.RcodeServFail || result.Error == nil {
+		t.Errorf("Expected SERVFAIL to surface as an error, got rcode %d err %v", result.Rcode, result.Error)
+	}
+}
+
+func TestResolveManyPreservesOrder(t *testing.T) {
+	server := newFakeDNSServer(t)
+	server.Add(
+		"a.example.com A 192.0.2.1",
+		"b.example.com A 192.0.2.2",
+		"c.example.com A 192.0.2.3",
+	)
+
+	resolver := resolve.NewResolver(&resolve.Config{
+		Servers: []string{server.Addr},
+		Timeout: 2 * time.Second,
+	})
+
+	hosts := []string{"c.example.com", "a.example.com", "missing.example.com", "b.example.com"}
+	results, err := resolver.ResolveMany(context.Background(), hosts, 4)
+	if err != nil {
+		t.Fatalf("ResolveMany failed: %v", err)
+	}
+
+	if len(results) != len(hosts) {
+		t.Fatalf("Expected %d results, got %d", len(hosts), len(results))
+	}
+	for i, result := range results {
+		if result.Host != hosts[i] {
+			t.Errorf("Result %d: expected %s, got %s", i, hosts[i], result.Host)
+		}
+	}
+	if results[2].Exists {
+		t.Error("Expected missing host not to exist")
+	}
+}
+
+func TestResolverRecordTypes(t *testing.T) {
+	server := newFakeDNSServer(t)
+	server.Add(
+		"www.example.com CNAME edge.example.com",
+		"edge.example.com CNAME edge.cdn.example.com",
+		"edge.cdn.example.com A 192.0.2.20",
+		"mail.example.com MX 10 mx1.example.com",
+		"dangling.example.com CNAME gone.example.com",
+	)
+
+	resolver := resolve.NewResolver(&resolve.Config{
+		Servers:     []string{server.Addr},
+		Timeout:     2 * time.Second,
+		RecordTypes: []uint16{resolve.TypeA, resolve.TypeMX},
+	})
+	ctx := context.Background()
+
+	result, _ := resolver.Resolve(ctx, "www.example.com")
+	chain := result.CNAMEChain()
+	if len(chain) != 2 || chain[0] != "edge.example.com" || chain[1] != "edge.cdn.example.com" {
+		t.Errorf("Unexpected CNAME chain: %v", chain)
+	}
+	if len(result.RecordsByType()["A"]) != 1 {
+		t.Errorf("Expected the final A record, got %v", result.RecordsByType())
+	}
+
+	result, _ = resolver.Resolve(ctx, "mail.example.com")
+	if !result.Exists || len(result.IPs) != 0 {
+		t.Errorf("Expected MX-only host to exist without IPs, got exists=%v ips=%v", result.Exists, result.IPs)
+	}
+	if mx := result.RecordsByType()["MX"]; len(mx) != 1 || mx[0].Data != "10 mx1.example.com" {
+		t.Errorf("Unexpected MX records: %v", mx)
+	}
+
+	// A CNAME pointing nowhere doesn't make the host exist unless asked for
+	result, _ = resolver.Resolve(ctx, "dangling.example.com")
+	if result.Exists {
+		t.Error("Expected dangling CNAME not to count as an existing host")
+	}
+}
+
+func TestResolverMixedRcodes(t *testing.T) {
+	// A queries always fail; AAAA answers depend on the host
+	server := newFakeDNSServer(t)
+	server.SetHandler(func(q *resolve.Message) *resolve.Message {
+		resp := q.Reply()
+		question := q.Questions[0]
+		switch {
+		case question.Type == resolve.TypeA:
+			resp.Rcode = resolve.RcodeServFail
+		case strings.HasPrefix(question.Name, "mixed."):
+			resp.Answers = append(resp.Answers, resolve.RR{
+				Name:  question.Name,
+				Type:  resolve.TypeAAAA,
+				Class: resolve.ClassINET,
+				TTL:   300,
+				Data:  "2001:db8::10",
+			})
+		case strings.HasPrefix(question.Name, "gone."):
+			resp.Rcode = resolve.RcodeNXDomain
+		default:
+			resp.Rcode = resolve.RcodeServFail
+		}
+		return resp
+	})
+
+	resolver := resolve.NewResolver(&resolve.Config{
+		Servers: []string{server.Addr},
+		Timeout: 2 * time.Second,
+		Retries: 1,
+	})
+	ctx := context.Background()
+
+	// Success wins over a failed type
+	res, err := resolver.Resolve(ctx, "mixed.example.com")
+	if err != nil {
+		t.Fatalf("Resolve failed: %v", err)
+	}
+	if !res.Exists || res.Error != nil || res.Rcode != resolve.RcodeSuccess || fmt.Sprint(res.IPs) != "[2001:db8::10]" {
+		t.Errorf("Expected the AAAA answer to win, got %+v", res)
+	}
+
+	// NXDOMAIN wins over a failed type
+	res, _ = resolver.Resolve(ctx, "gone.example.com")
+	if res.Exists || res.Error != nil || res.Rcode != resolve.RcodeNXDomain {
+		t.Errorf("Expected NXDOMAIN, got %+v", res)
+	}
+
+	// Only when every type fails is the lookup an error
+	res, _ = resolver.Resolve(ctx, "broken.example.com")
+	if res.Error == nil || res.Rcode != resolve.RcodeServFail {
+		t.Errorf("Expected SERVFAIL error, got %+v", res)
+	}
+}
@@ -0,0 +1,342 @@
+package tests
+
+import (
+	"encoding/binary"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/yourusername/subrecon/internal/resolve"
+)
+
+// fakeDNSServer is an in-process DNS server answering from a static zone over
+// UDP and TCP on the same port
+type fakeDNSServer struct {
+	Addr string
+
+	udp net.PacketConn
+	tcp net.Listener
+
+	mu       sync.Mutex
+	records  []resolve.RR
+	rcodes   map[string]int
+	soa      *resolve.RR
+	truncate bool // answer every UDP query with TC set
+	transfer bool // allow AXFR over TCP
+	handler  func(q *resolve.Message) *resolve.Message
+	queries  int
+	tcpCount int
+}
+
+func newFakeDNSServer(t *testing.T) *fakeDNSServer {
+	t.Helper()
+	return newFakeDNSServerOn(t, "127.0.0.1")
+}
+
+// newFakeDNSServerOn starts a fake server listening on the given loopback
+// address, e.g. "::1"
+func newFakeDNSServerOn(t *testing.T, host string) *fakeDNSServer {
+	t.Helper()
+
+	s := &fakeDNSServer{rcodes: make(map[string]int)}
+
+	// Bind UDP first, then grab the same port for TCP
+	for i := 0; i < 10; i++ {
+		udp, err := net.ListenPacket("udp", net.JoinHostPort(host, "0"))
+		if err != nil {
+			t.Fatalf("failed to listen on UDP: %v", err)
+		}
+		tcp, err := net.Listen("tcp", udp.LocalAddr().String())
+		if err != nil {
+			udp.Close()
+			continue
+		}
+		s.udp, s.tcp = udp, tcp
+		break
+	}
+	if s.udp == nil {
+		t.Fatal("failed to bind UDP and TCP on the same port")
+	}
+	s.Addr = s.udp.LocalAddr().String()
+
+	go s.serveUDP()
+	go s.serveTCP()
+	t.Cleanup(s.Close)
+
+	return s
+}
+
+func (s *fakeDNSServer) Close() {
+	s.udp.Close()
+	s.tcp.Close()
+}
+
+// Add adds records in zone file style, e.g. "www.example.com A 192.0.2.1"
+func (s *fakeDNSServer) Add(lines ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, line := range lines {
+		fields := strings.SplitN(line, " ", 3)
+		rtype, err := resolve.ParseType(fields[1])
+		if err != nil {
+			panic(err)
+		}
+		s.records = append(s.records, resolve.RR{
+			Name:  resolve.CanonicalName(fields[0]),
+			Type:  rtype,
+			Class: resolve.ClassINET,
+			TTL:   300,
+			Data:  fields[2],
+		})
+	}
+}
+
+// SetRcode makes the server answer every query for name with rcode
+func (s *fakeDNSServer) SetRcode(name string, rcode int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rcodes[resolve.CanonicalName(name)] = rcode
+}
+
+// SetSOA sets the SOA returned in the authority section of negative answers
+func (s *fakeDNSServer) SetSOA(rr resolve.RR) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.soa = &rr
+}
+
+// SetTransfer allows zone transfers over TCP. The zone is sent between two
+// copies of the SOA record, two records per message.
+func (s *fakeDNSServer) SetTransfer(allowed bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.transfer = allowed
+}
+
+// SetTruncate makes every UDP answer truncated, forcing a TCP retry
+func (s *fakeDNSServer) SetTruncate(truncate bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.truncate = truncate
+}
+
+// SetHandler replaces the zone lookup with a custom handler
+func (s *fakeDNSServer) SetHandler(h func(q *resolve.Message) *resolve.Message) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handler = h
+}
+
+// Queries returns the number of queries received
+func (s *fakeDNSServer) Queries() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.queries
+}
+
+// TCPQueries returns the number of queries received over TCP
+func (s *fakeDNSServer) TCPQueries() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.tcpCount
+}
+
+func (s *fakeDNSServer) serveUDP() {
+	buf := make([]byte, 65535)
+	for {
+		n, addr, err := s.udp.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+
+		q, err := resolve.Unpack(buf[:n])
+		if err != nil {
+			continue
+		}
+
+		resp := s.answer(q, false)
+		s.mu.Lock()
+		if s.truncate {
+			resp = q.Reply()
+			resp.Truncated = true
+		}
+		s.mu.Unlock()
+
+		data, err := resp.Pack()
+		if err != nil {
+			continue
+		}
+		s.udp.WriteTo(data, addr)
+	}
+}
+
+func (s *fakeDNSServer) serveTCP() {
+	for {
+		conn, err := s.tcp.Accept()
+		if err != nil {
+			return
+		}
+		go s.handleTCP(conn)
+	}
+}
+
+func (s *fakeDNSServer) handleTCP(conn net.Conn) {
+	defer conn.Close()
+
+	for {
+		var length [2]byte
+		if _, err := io.ReadFull(conn, length[:]); err != nil {
+			return
+		}
+		msg := make([]byte, binary.BigEndian.Uint16(length[:]))
+		if _, err := io.ReadFull(conn, msg); err != nil {
+			return
+		}
+
+		q, err := resolve.Unpack(msg)
+		if err != nil {
+			return
+		}
+
+		responses := []*resolve.Message{s.answer(q, true)}
+		if len(q.Questions) > 0 && q.Questions[0].Type == resolve.TypeAXFR && responses[0].Rcode == resolve.RcodeSuccess {
+			responses = s.transferMessages(q)
+		}
+
+		for _, resp := range responses {
+			data, err := resp.Pack()
+			if err != nil {
+				return
+			}
+			out := binary.BigEndian.AppendUint16(nil, uint16(len(data)))
+			if _, err := conn.Write(append(out, data...)); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// transferMessages splits the zone into AXFR response messages
+func (s *fakeDNSServer) transferMessages(q *resolve.Message) []*resolve.Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	records := append([]resolve.RR{*s.soa}, s.records...)
+	records = append(records, *s.soa)
+
+	var messages []*resolve.Message
+	for len(records) > 0 {
+		n := 2
+		if len(records) < n {
+			n = len(records)
+		}
+		resp := q.Reply()
+		resp.Authoritative = true
+		resp.Answers = records[:n]
+		records = records[n:]
+		messages = append(messages, resp)
+	}
+
+	return messages
+}
+
+func (s *fakeDNSServer) answer(q *resolve.Message, tcp bool) *resolve.Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.queries++
+	if tcp {
+		s.tcpCount++
+	}
+
+	if s.handler != nil {
+		resp := s.handler(q)
+		resp.ID = q.ID
+		resp.Response = true
+		return resp
+	}
+
+	resp := q.Reply()
+	resp.Authoritative = true
+	resp.RecursionAvailable = true
+	if len(q.Questions) == 0 {
+		resp.Rcode = resolve.RcodeFormErr
+		return resp
+	}
+
+	question := q.Questions[0]
+	name := resolve.CanonicalName(question.Name)
+	if question.Type == resolve.TypeAXFR {
+		if !tcp || !s.transfer || s.soa == nil {
+			resp.Rcode = resolve.RcodeRefused
+		}
+		return resp
+	}
+	if rcode, ok := s.rcodes[name]; ok {
+		resp.Rcode = rcode
+		return resp
+	}
+
+	// Follow CNAMEs inside the zone
+	for hops := 0; hops < 8; hops++ {
+		matched := s.lookup(name)
+		if len(matched) == 0 {
+			if hops == 0 {
+				resp.Rcode = resolve.RcodeNXDomain
+			}
+			break
+		}
+
+		var cname string
+		for _, rr := range matched {
+			if rr.Type == question.Type {
+				resp.Answers = append(resp.Answers, rr)
+			} else if rr.Type == resolve.TypeCNAME && question.Type != resolve.TypeCNAME {
+				resp.Answers = append(resp.Answers, rr)
+				cname = rr.Data
+			}
+		}
+		if cname == "" {
+			break
+		}
+		name = cname
+	}
+
+	if len(resp.Answers) == 0 && s.soa != nil {
+		resp.Authority = append(resp.Authority, *s.soa)
+	}
+
+	return resp
+}
+
+// lookup returns the records owned by name, falling back to the closest
+// wildcard owner
+func (s *fakeDNSServer) lookup(name string) []resolve.RR {
+	var matched []resolve.RR
+	for _, rr := range s.records {
+		if rr.Name == name {
+			matched = append(matched, rr)
+		}
+	}
+	if len(matched) > 0 {
+		return matched
+	}
+
+	for parent := name; strings.Contains(parent, "."); {
+		parent = parent[strings.Index(parent, ".")+1:]
+		for _, rr := range s.records {
+			if rr.Name == "*."+parent {
+				rr.Name = name
+				matched = append(matched, rr)
+			}
+		}
+		if len(matched) > 0 {
+			return matched
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,251 @@
+package tests
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/yourusername/subrecon/pkg/filter"
+	"github.com/yourusername/subrecon/pkg/runner"
+)
+
+// hostResults wraps hosts in results
+func hostResults(hosts ...string) []runner.SubdomainResult {
+	results := make([]runner.SubdomainResult, len(hosts))
+	for i, host := range hosts {
+		results[i] = runner.SubdomainResult{Host: host}
+	}
+	return results
+}
+
+// resultHosts lists the hosts of results
+func resultHosts(results []runner.SubdomainResult) []string {
+	hosts := make([]string, len(results))
+	for i, r := range results {
+		hosts[i] = r.Host
+	}
+	return hosts
+}
+
+func TestParsePattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		host    string
+		want    bool
+	}{
+		// Plain patterns are globs over the whole host
+		{"*.dev.example.com", "api.dev.example.com", true},
+		{"*.dev.example.com", "a.b.dev.example.com", true},
+		{"*.dev.example.com", "dev.example.com", false},
+		{"*.dev.example.com", "devexample.com", false},
+		{"api-*", "api-v2.example.com", true},
+		{"api-*", "www.api-v2.example.com", false},
+		{"API-?.example.com", "api-1.example.com", true},
+		{"api-[0-9].example.com", "api-7.example.com", true},
+		{"api-[!0-9].example.com", "api-7.example.com", false},
+		{"glob:www.example.com", "WWW.example.com.", true},
+		{"glob:www.example.com", "wwwxexample.com", false},
+
+		// Regular expressions match anywhere, as written
+		{`re:^(api|dev)\.`, "dev.example.com", true},
+		{`re:^(api|dev)\.`, "www.dev.example.com", false},
+		{"re:internal", "app.internal.example.com", true},
+
+		// Suffixes follow label boundaries
+		{"suffix:example.com", "example.com", true},
+		{"suffix:.example.com", "a.b.example.com", true},
+		{"suffix:example.com", "notexample.com", false},
+
+		// Labels match one label anywhere in the host
+		{"label:staging", "api.staging.example.com", true},
+		{"label:staging", "staging2.example.com", false},
+		{"label:stag*", "staging2.example.com", true},
+		{"label:dev*", "www.example.com.dev", true},
+	}
+	for _, tt := range tests {
+		p, err := filter.ParsePattern(tt.pattern)
+		if err != nil {
+			t.Errorf("ParsePattern(%q) failed: %v", tt.pattern, err)
+			continue
+		}
+		if got := p.Match(tt.host); got != tt.want {
+			t.Errorf("%q matching %s = %v, want %v", tt.pattern, tt.host, got, tt.want)
+		}
+		if p.String() != tt.pattern {
+			t.Errorf("String() = %q, want %q", p.String(), tt.pattern)
+		}
+	}
+
+	for _, bad := range []string{"re:(", "re:", "suffix:", "label:a.b", "label:", "api-[0-9", "glob:", "x[]"} {
+		if _, err := filter.ParsePattern(bad); err == nil {
+			t.Errorf("Expected %q to be rejected", bad)
+		}
+	}
+}
+
+func TestFilterPatterns(t *testing.T) {
+	results := hostResults("api.dev.example.com", "www.example.com", "api-v2.example.com", "test.example.com")
+
+	f := filter.NewFilter()
+	if err := f.AddMatchPattern("*.dev.example.com, api-*, label:test"); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.AddExcludePattern("re:^test"); err != nil {
+		t.Fatal(err)
+	}
+
+	got := resultHosts(f.Apply(results))
+	if fmt.Sprint(got) != "[api.dev.example.com api-v2.example.com]" {
+		t.Errorf("Apply = %v", got)
+	}
+}
+
+func TestFilterPatternFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "patterns.txt")
+	data := "# staging and anything under dev\nlabel:staging\n\nsuffix:dev.example.com\nre:^vpn[0-9]+\\.\n"
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	f := filter.NewFilter()
+	if err := f.AddMatchPattern("@" + path); err != nil {
+		t.Fatalf("Loading patterns failed: %v", err)
+	}
+
+	got := resultHosts(f.Apply(hostResults("staging.example.com", "dev.example.com", "vpn12.example.com", "vpn.example.com", "www.example.com")))
+	if fmt.Sprint(got) != "[staging.example.com dev.example.com vpn12.example.com]" {
+		t.Errorf("Apply = %v", got)
+	}
+
+	if err := os.WriteFile(path, []byte("re:(\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := filter.NewFilter().AddExcludePattern("@" + path); err == nil {
+		t.Error("Expected an invalid pattern in a file to be rejected")
+	}
+}
+
+// exprResults are results with the attributes expressions look at
+func exprResults() []runner.SubdomainResult {
+	return []runner.SubdomainResult{
+		{
+			Host:   "cdn.example.com",
+			Source: "crtsh",
+			IPs:    []string{"13.32.0.10", "2600:9000::1"},
+			DNS:    &runner.DNSInfo{CNAMEChain: []string{"d111111abcdef8.cloudfront.net"}},
+			Geo:    []runner.GeoInfo{{IP: "13.32.0.10", ASN: 16509, Org: "Amazon.com, Inc.", Country: "US"}},
+			HTTP:   []runner.HTTPInfo{{StatusCode: 403, Server: "CloudFront"}},
+			CDN:    true,
+		},
+		{
+			Host:   "vpn.example.com",
+			Source: "alienvault",
+			IPs:    []string{"10.1.2.3"},
+			HTTP:   []runner.HTTPInfo{{StatusCode: 200, Title: "Sign In"}, {StatusCode: 502}},
+			Ports:  []int{443, 8443},
+		},
+		{
+			Host:   "www.example.com",
+			Source: "crtsh",
+			IPs:    []string{"192.0.2.1"},
+			Geo:    []runner.GeoInfo{{IP: "192.0.2.1", ASN: 64500, Country: "DE"}},
+			HTTP:   []runner.HTTPInfo{{StatusCode: 200}},
+		},
+	}
+}
+
+func TestParseExpr(t *testing.T) {
+	tests := []struct {
+		expr string
+		want string
+	}{
+		{"ip in 10.0.0.0/8", "[vpn.example.com]"},
+		{"ip in 2600:9000::/28", "[cdn.example.com]"},
+		{"ip == 192.0.2.1", "[www.example.com]"},
+		{"cname ~ cloudfront.net", "[cdn.example.com]"},
+		{"cname ~ suffix:cloudfront.net", "[cdn.example.com]"},
+		{"asn == 16509", "[cdn.example.com]"},
+		{"asn != 16509", "[vpn.example.com www.example.com]"},
+		{"status >= 400", "[cdn.example.com vpn.example.com]"},
+		{"status==200 and port==8443", "[vpn.example.com]"},
+		{"source == crtsh", "[cdn.example.com www.example.com]"},
+		{"SOURCE == CRTSH", "[cdn.example.com www.example.com]"},
+		{"host ~ 're:^(vpn|www)\\.'", "[vpn.example.com www.example.com]"},
+		{"title ~ \"sign in\"", "[vpn.example.com]"},
+		{"org !~ amazon", "[vpn.example.com www.example.com]"},
+		{"cdn", "[cdn.example.com]"},
+		{"not cdn and status < 300", "[vpn.example.com www.example.com]"},
+		{"cdn == false", "[vpn.example.com www.example.com]"},
+
+		// and binds tighter than or
+		{"source == alienvault or source == crtsh and country == DE", "[vpn.example.com www.example.com]"},
+		{"(source == alienvault or source == crtsh) and country == DE", "[www.example.com]"},
+		{"not (ip in 10.0.0.0/8 or asn == 16509)", "[www.example.com]"},
+	}
+	for _, tt := range tests {
+		expr, err := filter.ParseExpr(tt.expr)
+		if err != nil {
+			t.Errorf("ParseExpr(%q) failed: %v", tt.expr, err)
+			continue
+		}
+
+		var got []string
+		for _, r := range exprResults() {
+			if expr.Eval(&r) {
+				got = append(got, r.Host)
+			}
+		}
+		if fmt.Sprint(got) != tt.want {
+			t.Errorf("%q matched %v, want %s", tt.expr, got, tt.want)
+		}
+	}
+
+	for _, bad := range []string{
+		"",
+		"ip",
+		"color == red",
+		"ip in 10.0.0.0/33",
+		"host in 10.0.0.0/8",
+		"source >= crtsh",
+		"status == ok",
+		"ip == example.com",
+		"cdn == maybe",
+		"cdn ~ true",
+		"(status == 200",
+		"status == 200)",
+		"status == 200 and",
+		"host ~ re:(",
+		"host == 'unterminated",
+		"not",
+	} {
+		if _, err := filter.ParseExpr(bad); err == nil {
+			t.Errorf("Expected %q to be rejected", bad)
+		}
+	}
+}
+
+func TestFilterExpr(t *testing.T) {
+	f := filter.NewFilter()
+	if err := f.AddMatchPattern("*.example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.AddExcludePattern("www.example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.AddExpr("status >= 400"); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.AddExpr("not cdn"); err != nil {
+		t.Fatal(err)
+	}
+
+	got := resultHosts(f.Apply(exprResults()))
+	if fmt.Sprint(got) != "[vpn.example.com]" {
+		t.Errorf("Apply = %v", got)
+	}
+
+	if err := f.AddExpr("status >"); err == nil {
+		t.Error("Expected an incomplete expression to be rejected")
+	}
+}
@@ -0,0 +1,329 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/yourusername/subrecon/internal/mmdb"
+	"github.com/yourusername/subrecon/pkg/geoip"
+)
+
+// mmdbPointer is written as a pointer to that offset of the data section
+type mmdbPointer uint
+
+// mmdbNetwork is a network and the record stored for it
+type mmdbNetwork struct {
+	cidr   string
+	record interface{}
+}
+
+// buildMMDB writes a MaxMind DB holding networks. shared is written first
+// in the data section, at offset 0, so records can point at it.
+func buildMMDB(t *testing.T, ipVersion, recordSize int, dbType string, shared interface{}, networks []mmdbNetwork) []byte {
+	t.Helper()
+
+	type ref struct {
+		kind int // 0 empty, 1 node, 2 data
+		val  int
+	}
+	nodes := [][2]ref{{}}
+
+	var data bytes.Buffer
+	if shared != nil {
+		encodeMMDB(t, &data, shared)
+	}
+
+	for _, network := range networks {
+		_, ipnet, err := net.ParseCIDR(network.cidr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ones, _ := ipnet.Mask.Size()
+		addr := ipnet.IP.To16()
+		if v4 := ipnet.IP.To4(); v4 != nil {
+			if ipVersion == 6 {
+				// IPv4 lives under ::/96 in an IPv6 tree
+				addr = append(make([]byte, 12), v4...)
+				ones += 96
+			} else {
+				addr = v4
+			}
+		}
+
+		offset := data.Len()
+		encodeMMDB(t, &data, network.record)
+
+		node := 0
+		for i := 0; i < ones; i++ {
+			bit := addr[i/8] >> (7 - i%8) & 1
+			if i == ones-1 {
+				nodes[node][bit] = ref{kind: 2, val: offset}
+				break
+			}
+			if nodes[node][bit].kind != 1 {
+				nodes = append(nodes, [2]ref{})
+				nodes[node][bit] = ref{kind: 1, val: len(nodes) - 1}
+			}
+			node = nodes[node][bit].val
+		}
+	}
+
+	count := len(nodes)
+	value := func(r ref) uint32 {
+		switch r.kind {
+		case 1:
+			return uint32(r.val)
+		case 2:
+			return uint32(count + 16 + r.val)
+		}
+		return uint32(count)
+	}
+
+	var buf bytes.Buffer
+	for _, node := range nodes {
+		left, right := value(node[0]), value(node[1])
+		switch recordSize {
+		case 24:
+			buf.Write([]byte{byte(left >> 16), byte(left >> 8), byte(left), byte(right >> 16), byte(right >> 8), byte(right)})
+		case 28:
+			buf.Write([]byte{byte(left >> 16), byte(left >> 8), byte(left),
+				byte(left>>24)<<4 | byte(right>>24)&0x0f,
+				byte(right >> 16), byte(right >> 8), byte(right)})
+		default:
+			binary.Write(&buf, binary.BigEndian, []uint32{left, right})
+		}
+	}
+	buf.Write(make([]byte, 16))
+	buf.Write(data.Bytes())
+
+	buf.WriteString("\xAB\xCD\xEFMaxMind.com")
+	encodeMMDB(t, &buf, map[string]interface{}{
+		"binary_format_major_version": uint16(2),
+		"binary_format_minor_version": uint16(0),
+		"build_epoch":                 uint64(1700000000),
+		"database_type":               dbType,
+		"ip_version":                  uint16(ipVersion),
+		"languages":                   []interface{}{"en"},
+		"node_count":                  uint32(count),
+		"record_size":                 uint16(recordSize),
+	})
+
+	return buf.Bytes()
+}
+
+// encodeMMDB appends v in the MaxMind DB data format
+func encodeMMDB(t *testing.T, buf *bytes.Buffer, v interface{}) {
+	t.Helper()
+
+	header := func(typ, size int) {
+		var ctrl byte
+		var extra []byte
+		switch {
+		case size < 29:
+			ctrl = byte(size)
+		case size < 285:
+			ctrl, extra = 29, []byte{byte(size - 29)}
+		default:
+			ctrl, extra = 30, []byte{byte((size - 285) >> 8), byte(size - 285)}
+		}
+		if typ > 7 {
+			buf.Write([]byte{ctrl, byte(typ - 7)})
+		} else {
+			buf.WriteByte(byte(typ)<<5 | ctrl)
+		}
+		buf.Write(extra)
+	}
+	unsigned := func(typ int, n uint64) {
+		var b []byte
+		for ; n > 0; n >>= 8 {
+			b = append([]byte{byte(n)}, b...)
+		}
+		header(typ, len(b))
+		buf.Write(b)
+	}
+
+	switch v := v.(type) {
+	case mmdbPointer:
+		buf.Write([]byte{1<<5 | byte(v>>8)&0x7, byte(v)})
+	case string:
+		header(2, len(v))
+		buf.WriteString(v)
+	case float64:
+		header(3, 8)
+		binary.Write(buf, binary.BigEndian, math.Float64bits(v))
+	case uint16:
+		unsigned(5, uint64(v))
+	case uint32:
+		unsigned(6, uint64(v))
+	case uint64:
+		unsigned(9, v)
+	case bool:
+		n := 0
+		if v {
+			n = 1
+		}
+		header(14, n)
+	case []interface{}:
+		header(11, len(v))
+		for _, item := range v {
+			encodeMMDB(t, buf, item)
+		}
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		header(7, len(keys))
+		for _, key := range keys {
+			encodeMMDB(t, buf, key)
+			encodeMMDB(t, buf, v[key])
+		}
+	default:
+		t.Fatalf("can't encode %T", v)
+	}
+}
+
+// The organization is longer than 29 bytes, so its size takes an extra byte
+const testOrg = "Example Networks International Ltd"
+
+func buildASNDatabase(t *testing.T, recordSize int) []byte {
+	return buildMMDB(t, 6, recordSize, "GeoLite2-ASN", testOrg, []mmdbNetwork{
+		{"192.0.2.0/24", map[string]interface{}{
+			"autonomous_system_number":       uint32(64500),
+			"autonomous_system_organization": mmdbPointer(0),
+		}},
+		{"2001:db8::/32", map[string]interface{}{
+			"autonomous_system_number":       uint32(64501),
+			"autonomous_system_organization": "Example IPv6",
+		}},
+	})
+}
+
+func TestMMDBLookup(t *testing.T) {
+	for _, size := range []int{24, 28, 32} {
+		r, err := mmdb.New(buildASNDatabase(t, size))
+		if err != nil {
+			t.Fatalf("record size %d: %v", size, err)
+		}
+		if meta := r.Metadata(); meta.DatabaseType != "GeoLite2-ASN" || meta.RecordSize != uint(size) || meta.IPVersion != 6 {
+			t.Errorf("Unexpected metadata: %+v", meta)
+		}
+
+		record, err := r.Lookup(net.ParseIP("192.0.2.77"))
+		if err != nil {
+			t.Fatalf("record size %d: %v", size, err)
+		}
+		m, _ := record.(map[string]interface{})
+		if m["autonomous_system_number"] != uint64(64500) || m["autonomous_system_organization"] != testOrg {
+			t.Errorf("record size %d: unexpected record %v", size, record)
+		}
+
+		record, _ = r.Lookup(net.ParseIP("2001:db8:1::1"))
+		if m, _ := record.(map[string]interface{}); m["autonomous_system_number"] != uint64(64501) {
+			t.Errorf("record size %d: unexpected IPv6 record %v", size, record)
+		}
+
+		for _, miss := range []string{"198.51.100.1", "2001:db9::1"} {
+			if record, err := r.Lookup(net.ParseIP(miss)); record != nil || err != nil {
+				t.Errorf("record size %d: expected no record for %s, got %v, %v", size, miss, record, err)
+			}
+		}
+	}
+}
+
+func TestMMDBIPv4Database(t *testing.T) {
+	r, err := mmdb.New(buildMMDB(t, 4, 24, "Test", nil, []mmdbNetwork{
+		{"10.0.0.0/8", map[string]interface{}{"private": true, "score": 0.5}},
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	record, err := r.Lookup(net.ParseIP("10.1.2.3"))
+	m, _ := record.(map[string]interface{})
+	if err != nil || m["private"] != true || m["score"] != 0.5 {
+		t.Errorf("Unexpected record %v, %v", record, err)
+	}
+	if _, err := r.Lookup(net.ParseIP("2001:db8::1")); err == nil {
+		t.Error("Expected an IPv6 lookup in an IPv4 database to fail")
+	}
+}
+
+func TestMMDBRejectsGarbage(t *testing.T) {
+	if _, err := mmdb.New([]byte("not a database")); err == nil {
+		t.Error("Expected a file without metadata to be rejected")
+	}
+
+	// Metadata claiming more nodes than the file holds
+	db := buildASNDatabase(t, 24)
+	marker := bytes.LastIndex(db, []byte("MaxMind.com"))
+	if _, err := mmdb.New(db[marker-3:]); err == nil {
+		t.Error("Expected a truncated search tree to be rejected")
+	}
+}
+
+func TestGeoIPEnricher(t *testing.T) {
+	dir := t.TempDir()
+	asnPath := filepath.Join(dir, "GeoLite2-ASN.mmdb")
+	cityPath := filepath.Join(dir, "GeoLite2-City.mmdb")
+
+	city := buildMMDB(t, 6, 28, "GeoLite2-City", nil, []mmdbNetwork{
+		{"192.0.2.0/25", map[string]interface{}{
+			"city":    map[string]interface{}{"names": map[string]interface{}{"en": "Springfield", "de": "Springfeld"}},
+			"country": map[string]interface{}{"iso_code": "US", "names": map[string]interface{}{"en": "United States"}},
+		}},
+		{"192.0.2.128/25", map[string]interface{}{
+			"registered_country": map[string]interface{}{"iso_code": "DE"},
+		}},
+	})
+	if err := os.WriteFile(asnPath, buildASNDatabase(t, 24), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(cityPath, city, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	enricher, err := geoip.Open(asnPath, cityPath)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+
+	tests := []struct {
+		ip   string
+		want geoip.Info
+	}{
+		{"192.0.2.10", geoip.Info{ASN: 64500, Org: testOrg, Country: "US", City: "Springfield"}},
+		{"192.0.2.200", geoip.Info{ASN: 64500, Org: testOrg, Country: "DE"}},
+		{"2001:db8::5", geoip.Info{ASN: 64501, Org: "Example IPv6"}},
+		{"203.0.113.1", geoip.Info{}},
+		{"not-an-ip", geoip.Info{}},
+	}
+	for _, tt := range tests {
+		if got := enricher.Lookup(tt.ip); got != tt.want {
+			t.Errorf("Lookup(%s) = %+v, want %+v", tt.ip, got, tt.want)
+		}
+	}
+
+	// Either database alone is enough
+	asnOnly, err := geoip.Open(asnPath, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := asnOnly.Lookup("192.0.2.10"); got.ASN != 64500 || got.Country != "" {
+		t.Errorf("Unexpected ASN-only lookup: %+v", got)
+	}
+
+	if _, err := geoip.Open("", ""); err == nil {
+		t.Error("Expected Open without databases to fail")
+	}
+	if _, err := geoip.Open(filepath.Join(dir, "missing.mmdb"), ""); err == nil {
+		t.Error("Expected a missing database to fail")
+	}
+}
@@ -0,0 +1,65 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/yourusername/subrecon/internal/resolve"
+)
+
+func TestSplitIPs(t *testing.T) {
+	v4, v6 := resolve.SplitIPs([]string{"192.0.2.1", "2001:db8::1", "bogus", "::ffff:198.51.100.7", "2001:db8::2"})
+
+	if fmt.Sprint(v4) != "[192.0.2.1 ::ffff:198.51.100.7]" {
+		t.Errorf("Unexpected IPv4 addresses: %v", v4)
+	}
+	if fmt.Sprint(v6) != "[2001:db8::1 2001:db8::2]" {
+		t.Errorf("Unexpected IPv6 addresses: %v", v6)
+	}
+}
+
+func TestResolverIPv6Server(t *testing.T) {
+	probe, err := net.ListenPacket("udp", "[::1]:0")
+	if err != nil {
+		t.Skip("IPv6 loopback not available")
+	}
+	probe.Close()
+
+	server := newFakeDNSServerOn(t, "::1")
+	server.Add(
+		"v6only.example.com AAAA 2001:db8::10",
+		"dual.example.com A 192.0.2.10",
+		"dual.example.com AAAA 2001:db8::11",
+	)
+
+	pool := resolve.NewPool([]string{server.Addr})
+	if pool.Stats()[0].Addr != server.Addr {
+		t.Fatalf("Expected pool to keep %s, got %s", server.Addr, pool.Stats()[0].Addr)
+	}
+
+	resolver := resolve.NewResolver(&resolve.Config{
+		Servers: []string{server.Addr},
+		Timeout: 2 * time.Second,
+	})
+
+	res, err := resolver.Resolve(context.Background(), "v6only.example.com")
+	if err != nil {
+		t.Fatalf("Resolve failed: %v", err)
+	}
+	v4, v6 := resolve.SplitIPs(res.IPs)
+	if !res.Exists || len(v4) != 0 || fmt.Sprint(v6) != "[2001:db8::10]" {
+		t.Errorf("Unexpected answer for v6-only host: %+v", res)
+	}
+
+	res, err = resolver.Resolve(context.Background(), "dual.example.com")
+	if err != nil {
+		t.Fatalf("Resolve failed: %v", err)
+	}
+	v4, v6 = resolve.SplitIPs(res.IPs)
+	if len(v4) != 1 || len(v6) != 1 {
+		t.Errorf("Expected one address per family, got %v and %v", v4, v6)
+	}
+}
@@ -0,0 +1,314 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/yourusername/subrecon/internal/resolve"
+	"github.com/yourusername/subrecon/pkg/scope"
+	"github.com/yourusername/subrecon/pkg/sources"
+)
+
+func TestNSECRecordRoundtrip(t *testing.T) {
+	m := resolve.NewQuery("example.com", resolve.TypeNSEC)
+	m.Response = true
+	m.Answers = []resolve.RR{
+		{Name: "example.com", Type: resolve.TypeNSEC, Class: resolve.ClassINET, TTL: 300, Data: "api.example.com A NS SOA RRSIG NSEC DNSKEY"},
+		{Name: "abc.example.com", Type: resolve.TypeNSEC3, Class: resolve.ClassINET, TTL: 300, Data: "1 1 12 aabbccdd 2t7b4g4vsa5smi47k61mv5bv1a22bojr A RRSIG"},
+	}
+
+	data, err := m.Pack()
+	if err != nil {
+		t.Fatalf("Pack failed: %v", err)
+	}
+	got, err := resolve.Unpack(data)
+	if err != nil {
+		t.Fatalf("Unpack failed: %v", err)
+	}
+
+	if got.Answers[0].Data != "api.example.com A NS SOA RRSIG NSEC DNSKEY" {
+		t.Errorf("Unexpected NSEC data: %q", got.Answers[0].Data)
+	}
+	if got.Answers[1].Data != m.Answers[1].Data {
+		t.Errorf("NSEC3 data = %q, want %q", got.Answers[1].Data, m.Answers[1].Data)
+	}
+
+	rec, err := resolve.ParseNSEC3(got.Answers[1].Data)
+	if err != nil {
+		t.Fatalf("ParseNSEC3 failed: %v", err)
+	}
+	if rec.Iterations != 12 || rec.Salt != "aabbccdd" || len(rec.Types) != 2 {
+		t.Errorf("Unexpected NSEC3 fields: %+v", rec)
+	}
+}
+
+func TestHashName(t *testing.T) {
+	// Test vector from RFC 5155, Appendix A
+	hash, err := resolve.HashName("example", 12, "aabbccdd")
+	if err != nil {
+		t.Fatalf("HashName failed: %v", err)
+	}
+	if hash != "0p9mhaveqvm6t7vbl5lop2u3t2rp3tom" {
+		t.Errorf("HashName = %s", hash)
+	}
+}
+
+func TestNSECWalk(t *testing.T) {
+	server := newFakeDNSServer(t)
+	server.Add(
+		"example.com NSEC api.example.com A NS SOA RRSIG NSEC",
+		"api.example.com NSEC *.dev.example.com A RRSIG NSEC",
+		"*.dev.example.com NSEC internal.example.com A RRSIG NSEC",
+		"internal.example.com NSEC www.example.com A RRSIG NSEC",
+		"www.example.com NSEC example.com A RRSIG NSEC",
+	)
+
+	src := sources.NewNSECWalk(nil)
+	src.SetServer(server.Addr)
+
+	names, err := src.Run(context.Background(), "example.com")
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	want := "example.com,api.example.com,internal.example.com,www.example.com"
+	if strings.Join(names, ",") != want {
+		t.Errorf("Walk = %v, want %s", names, want)
+	}
+	if server.Queries() != 5 {
+		t.Errorf("Expected one query per name, got %d", server.Queries())
+	}
+}
+
+func TestNSECWalkPastDelegation(t *testing.T) {
+	nsec := func(owner, next string) resolve.RR {
+		return resolve.RR{Name: owner, Type: resolve.TypeNSEC, Class: resolve.ClassINET, TTL: 300, Data: next + " NS RRSIG NSEC"}
+	}
+	chain := map[string]resolve.RR{
+		"example.com":       nsec("example.com", "api.example.com"),
+		"api.example.com":   nsec("api.example.com", "child.example.com"),
+		"child.example.com": nsec("child.example.com", "www.example.com"),
+		"www.example.com":   nsec("www.example.com", "example.com"),
+	}
+
+	// The parent refers queries at and below the signed child zone to its
+	// servers, without an NSEC answer; names after it are denied with the
+	// NSEC that covers them
+	server := newFakeDNSServer(t)
+	server.SetHandler(func(q *resolve.Message) *resolve.Message {
+		resp := q.Reply()
+		name := resolve.CanonicalName(q.Questions[0].Name)
+		switch {
+		case name == "child.example.com" || strings.HasSuffix(name, ".child.example.com"):
+			resp.Authority = append(resp.Authority,
+				resolve.RR{Name: "child.example.com", Type: resolve.TypeNS, Class: resolve.ClassINET, TTL: 300, Data: "ns1.child.example.com"})
+		case name == "child\x00.example.com":
+			resp.Rcode = resolve.RcodeNXDomain
+			resp.Authority = append(resp.Authority, chain["child.example.com"])
+		default:
+			if rr, ok := chain[name]; ok {
+				resp.Answers = append(resp.Answers, rr)
+			} else {
+				resp.Rcode = resolve.RcodeNXDomain
+			}
+		}
+		return resp
+	})
+
+	src := sources.NewNSECWalk(nil)
+	src.SetServer(server.Addr)
+
+	names, err := src.Run(context.Background(), "example.com")
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	want := "example.com,api.example.com,child.example.com,www.example.com"
+	if strings.Join(names, ",") != want {
+		t.Errorf("Walk = %v, want %s", names, want)
+	}
+}
+
+func TestNSEC3Cracking(t *testing.T) {
+	hosts := []string{"www.example.com", "mail.example.com", "secret-db.example.com"}
+	hashes := make([]string, len(hosts))
+	for i, host := range hosts {
+		hash, err := resolve.HashName(host, 5, "aabb")
+		if err != nil {
+			t.Fatal(err)
+		}
+		hashes[i] = hash
+	}
+	sorted := append([]string(nil), hashes...)
+	sort.Strings(sorted)
+
+	// Every negative answer proves non-existence with the whole chain
+	server := newFakeDNSServer(t)
+	server.SetHandler(func(q *resolve.Message) *resolve.Message {
+		resp := q.Reply()
+		resp.Rcode = resolve.RcodeNXDomain
+		for i, hash := range sorted {
+			next := sorted[(i+1)%len(sorted)]
+			resp.Authority = append(resp.Authority, resolve.RR{
+				Name:  hash + ".example.com",
+				Type:  resolve.TypeNSEC3,
+				Class: resolve.ClassINET,
+				TTL:   300,
+				Data:  "1 0 5 aabb " + next + " A RRSIG",
+			})
+		}
+		return resp
+	})
+
+	wordlist := filepath.Join(t.TempDir(), "words.txt")
+	if err := os.WriteFile(wordlist, []byte("www\nftp\nsecret-db\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := sources.DefaultConfig()
+	cfg.Wordlist = wordlist
+	src := sources.NewNSECWalk(cfg)
+	src.SetServer(server.Addr)
+
+	names, err := src.Run(context.Background(), "example.com")
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	sort.Strings(names)
+
+	if strings.Join(names, ",") != "secret-db.example.com,www.example.com" {
+		t.Errorf("Cracked = %v", names)
+	}
+	if strings.Join(src.Hashes(), ",") != strings.Join(sorted, ",") {
+		t.Errorf("Hashes = %v, want %v", src.Hashes(), sorted)
+	}
+}
+
+func TestNSECWalkAsksAuthoritativeServers(t *testing.T) {
+	// A recursive resolver that would answer the walk, but lists no
+	// nameservers for the zone
+	resolver := newFakeDNSServer(t)
+	resolver.Add(
+		"example.com NSEC www.example.com A NS SOA RRSIG NSEC",
+		"www.example.com NSEC example.com A RRSIG NSEC",
+	)
+
+	src := sources.NewNSECWalk(nil)
+	src.SetResolver(resolver.Addr)
+
+	if _, err := src.Run(context.Background(), "example.com"); err == nil {
+		t.Error("Expected an error without nameservers to walk")
+	}
+	if resolver.Queries() != 1 {
+		t.Errorf("Expected only the NS lookup to reach the resolver, got %d queries", resolver.Queries())
+	}
+}
+
+func TestNSECWalkLooksUpNSThroughConfiguredResolver(t *testing.T) {
+	server := newFakeDNSServer(t)
+
+	config := sources.DefaultConfig()
+	config.Resolver = resolve.NewResolver(&resolve.Config{
+		Servers: []string{server.Addr},
+		Timeout: 2 * time.Second,
+		Retries: 1,
+	})
+
+	src := sources.NewNSECWalk(config)
+	if _, err := src.Run(context.Background(), "example.com"); err == nil {
+		t.Error("Expected an error without nameservers to walk")
+	}
+	if server.Queries() != 1 {
+		t.Errorf("Expected the NS lookup to go to the configured resolver, got %d queries", server.Queries())
+	}
+}
+
+func TestNSECWalkSkipsNameserversOutOfScope(t *testing.T) {
+	server := newFakeDNSServer(t)
+	server.Add("example.com NSEC www.example.com A NS SOA RRSIG NSEC")
+
+	sc, err := scope.Parse([]byte("forbidden_cidrs: [127.0.0.0/8]"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := sources.DefaultConfig()
+	config.Scope = sc
+
+	src := sources.NewNSECWalk(config)
+	src.SetServer(server.Addr)
+
+	if _, err := src.Run(context.Background(), "example.com"); err == nil {
+		t.Error("Expected an error without nameservers in scope")
+	}
+	if server.Queries() != 0 {
+		t.Errorf("Expected the out of scope server not to be queried, got %d queries", server.Queries())
+	}
+}
+
+func TestNSEC3CrackingMixedParameters(t *testing.T) {
+	// The zone is re-signed mid-walk: answers alternate between two salts
+	// and iteration counts
+	type signing struct {
+		salt       string
+		iterations uint16
+		host       string
+	}
+	signings := []signing{
+		{"aabb", 5, "www.example.com"},
+		{"ccdd", 0, "mail.example.com"},
+	}
+	records := make([]resolve.RR, len(signings))
+	for i, s := range signings {
+		hash, err := resolve.HashName(s.host, s.iterations, s.salt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		records[i] = resolve.RR{
+			Name:  hash + ".example.com",
+			Type:  resolve.TypeNSEC3,
+			Class: resolve.ClassINET,
+			TTL:   300,
+			Data:  fmt.Sprintf("1 0 %d %s %s A RRSIG", s.iterations, s.salt, hash),
+		}
+	}
+
+	server := newFakeDNSServer(t)
+	var mu sync.Mutex
+	queries := 0
+	server.SetHandler(func(q *resolve.Message) *resolve.Message {
+		mu.Lock()
+		defer mu.Unlock()
+		resp := q.Reply()
+		resp.Rcode = resolve.RcodeNXDomain
+		resp.Authority = append(resp.Authority, records[queries%len(records)])
+		queries++
+		return resp
+	})
+
+	wordlist := filepath.Join(t.TempDir(), "words.txt")
+	if err := os.WriteFile(wordlist, []byte("www\nmail\nftp\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := sources.DefaultConfig()
+	cfg.Wordlist = wordlist
+	src := sources.NewNSECWalk(cfg)
+	src.SetServer(server.Addr)
+
+	names, err := src.Run(context.Background(), "example.com")
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	sort.Strings(names)
+
+	if strings.Join(names, ",") != "mail.example.com,www.example.com" {
+		t.Errorf("Cracked = %v, want names from both signings", names)
+	}
+}
@@ -0,0 +1,57 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/yourusername/subrecon/pkg/output"
+	"github.com/yourusername/subrecon/pkg/runner"
+)
+
+func TestJSONFormatterDNSBlock(t *testing.T) {
+	results := []runner.SubdomainResult{
+		{
+			Host:      "www.example.com",
+			Source:    "crtsh",
+			Timestamp: time.Now(),
+			IPs:       []string{"192.0.2.1"},
+			DNS: &runner.DNSInfo{
+				Rcode: "NOERROR",
+				Records: map[string][]runner.DNSRecord{
+					"CNAME": {{Name: "www.example.com", Value: "edge.example.net", TTL: 300}},
+					"A":     {{Name: "edge.example.net", Value: "192.0.2.1", TTL: 60}},
+				},
+				CNAMEChain: []string{"edge.example.net"},
+			},
+		},
+		{Host: "api.example.com", Source: "crtsh", Timestamp: time.Now()},
+	}
+
+	var buf bytes.Buffer
+	if err := output.NewJSONFormatter(false).Format(results, &buf); err != nil {
+		t.Fatalf("Format failed: %v", err)
+	}
+
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 JSON lines, got %d", len(lines))
+	}
+
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(lines[0], &decoded); err != nil {
+		t.Fatalf("Invalid JSON: %v", err)
+	}
+	dns, ok := decoded["dns"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("Expected dns block, got %s", lines[0])
+	}
+	if dns["rcode"] != "NOERROR" || dns["cname_chain"] == nil {
+		t.Errorf("Unexpected dns block: %v", dns)
+	}
+
+	if bytes.Contains(lines[1], []byte(`"dns"`)) {
+		t.Errorf("Expected no dns block for unresolved host, got %s", lines[1])
+	}
+}
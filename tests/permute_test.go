@@ -0,0 +1,124 @@
+package tests
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/yourusername/subrecon/pkg/permute"
+)
+
+func TestPermuteGenerate(t *testing.T) {
+	gen := permute.New([]string{"dev"})
+	got := gen.Generate([]string{"api2.eu.example.com", "other.org"}, "example.com")
+
+	want := []string{
+		"api0.eu.example.com",
+		"api1.eu.example.com",
+		"api2-dev.eu.example.com",
+		"api2.dev.eu.example.com",
+		"api2.eu.dev.example.com",
+		"api2dev.eu.example.com",
+		"api3.eu.example.com",
+		"api4.eu.example.com",
+		"dev-api2.eu.example.com",
+		"dev.api2.eu.example.com",
+		"devapi2.eu.example.com",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Generate =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestPermuteDeterministic(t *testing.T) {
+	gen := permute.New(nil)
+	hosts := []string{"www.example.com", "web01.example.com", "*.stage.example.com", "mail.example.com"}
+
+	first := gen.Generate(hosts, "example.com")
+	if !sort.StringsAreSorted(first) {
+		t.Error("Expected sorted output")
+	}
+
+	// Input order must not matter
+	reversed := []string{hosts[3], hosts[2], hosts[1], hosts[0]}
+	second := gen.Generate(reversed, "example.com")
+	if strings.Join(first, ",") != strings.Join(second, ",") {
+		t.Error("Expected identical output for the same hosts")
+	}
+
+	seen := make(map[string]bool)
+	for _, name := range first {
+		if seen[name] {
+			t.Errorf("Duplicate candidate %s", name)
+		}
+		seen[name] = true
+		for _, host := range hosts {
+			if name == host {
+				t.Errorf("Known host %s returned as candidate", host)
+			}
+		}
+	}
+
+	for _, name := range []string{"web00.example.com", "web02.example.com", "dev.stage.example.com", "dev-www.example.com"} {
+		if !seen[name] {
+			t.Errorf("Expected candidate %s", name)
+		}
+	}
+}
+
+func TestPermuteLoadWords(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "words.txt")
+	if err := os.WriteFile(path, []byte("# envs\ndev\n\nPREPROD\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	words, err := permute.LoadWords(path)
+	if err != nil {
+		t.Fatalf("LoadWords failed: %v", err)
+	}
+	if strings.Join(words, ",") != "dev,PREPROD" {
+		t.Errorf("Unexpected words: %v", words)
+	}
+
+	got := permute.New(words).Generate([]string{"www.example.com"}, "example.com")
+	found := false
+	for _, name := range got {
+		if name == "preprod-www.example.com" {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("Expected loaded words to be lowercased and used")
+	}
+}
+
+func TestPermuteRun(t *testing.T) {
+	server := newFakeDNSServer(t)
+	server.Add(
+		"api.example.com A 192.0.2.1",
+		"dev-api.example.com A 192.0.2.2",
+		"api2.example.com A 192.0.2.3",
+	)
+
+	resolver := newTestResolver(t, server)
+	gen := permute.New([]string{"dev", "staging"})
+
+	hits, stats, err := gen.Run(context.Background(), resolver, []string{"api.example.com", "api1.example.com"}, "example.com", 4)
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	hosts := make([]string, len(hits))
+	for i, hit := range hits {
+		hosts[i] = hit.Host
+	}
+	if strings.Join(hosts, ",") != "api2.example.com,dev-api.example.com" {
+		t.Errorf("Unexpected hits: %v", hosts)
+	}
+	if stats.Candidates == 0 || stats.Resolved != 2 || stats.Wildcards != 0 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+}
@@ -0,0 +1,132 @@
+package tests
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/yourusername/subrecon/internal/resolve"
+)
+
+func TestPoolRoundRobin(t *testing.T) {
+	pool := resolve.NewPool([]string{"10.0.0.1:53", "10.0.0.2:53", "10.0.0.3:53"})
+
+	counts := make(map[string]int)
+	for i := 0; i < 30; i++ {
+		counts[pool.Next()]++
+	}
+
+	if len(counts) != 3 {
+		t.Fatalf("Expected queries spread over 3 servers, got %v", counts)
+	}
+	for server, n := range counts {
+		if n != 10 {
+			t.Errorf("Expected 10 queries for %s, got %d", server, n)
+		}
+	}
+}
+
+func TestPoolEvictsFailingServer(t *testing.T) {
+	now := time.Now()
+	pool := resolve.NewPool([]string{"10.0.0.1:53", "10.0.0.2:53"})
+	pool.SetClock(func() time.Time { return now })
+
+	for i := 0; i < 5; i++ {
+		pool.Report("10.0.0.1:53", time.Millisecond, errors.New("timeout"))
+	}
+
+	for i := 0; i < 4; i++ {
+		if server := pool.Next(); server != "10.0.0.2:53" {
+			t.Fatalf("Expected evicted server to be skipped, got %s", server)
+		}
+	}
+
+	// The server rejoins the rotation once the eviction expires
+	now = now.Add(time.Minute)
+	seen := make(map[string]bool)
+	for i := 0; i < 2; i++ {
+		seen[pool.Next()] = true
+	}
+	if !seen["10.0.0.1:53"] {
+		t.Error("Expected server to return after eviction")
+	}
+}
+
+func TestPoolBan(t *testing.T) {
+	pool := resolve.NewPool([]string{"10.0.0.1:53", "10.0.0.2:53"})
+	pool.Ban("10.0.0.2:53")
+
+	for i := 0; i < 4; i++ {
+		if server := pool.Next(); server != "10.0.0.1:53" {
+			t.Fatalf("Expected banned server to be skipped, got %s", server)
+		}
+	}
+}
+
+func TestPoolAllEvicted(t *testing.T) {
+	pool := resolve.NewPool([]string{"10.0.0.1:53"})
+	for i := 0; i < 5; i++ {
+		pool.Report("10.0.0.1:53", time.Millisecond, errors.New("timeout"))
+	}
+
+	if server := pool.Next(); server != "10.0.0.1:53" {
+		t.Errorf("Expected fallback to the only server, got %q", server)
+	}
+}
+
+func TestPoolServersConcurrent(t *testing.T) {
+	pool := resolve.NewPool([]string{"10.0.0.1:53", "10.0.0.2:53", "10.0.0.3:53"})
+
+	// Reading the server list while health changes must be safe; run
+	// with -race to check
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				pool.Report("10.0.0.1:53", time.Millisecond, errors.New("timeout"))
+				pool.Ban("10.0.0.3:53")
+				pool.Next()
+			}
+		}()
+	}
+	for j := 0; j < 100; j++ {
+		servers := pool.Servers()
+		if len(servers) != 3 {
+			t.Fatalf("Expected 3 servers, got %v", servers)
+		}
+		servers[0] = "changed"
+	}
+	wg.Wait()
+
+	if servers := pool.Servers(); servers[0] != "10.0.0.1:53" {
+		t.Errorf("Expected Servers to return a copy, got %v", servers)
+	}
+}
+
+func TestLoadServers(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "resolvers.txt")
+	content := "# public resolvers\n8.8.8.8\n1.1.1.1:5353\n\n9.9.9.9:53\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	servers, err := resolve.LoadServers(path)
+	if err != nil {
+		t.Fatalf("LoadServers failed: %v", err)
+	}
+
+	expected := []string{"8.8.8.8:53", "1.1.1.1:5353", "9.9.9.9:53"}
+	if len(servers) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, servers)
+	}
+	for i := range expected {
+		if servers[i] != expected[i] {
+			t.Errorf("Expected %s, got %s", expected[i], servers[i])
+		}
+	}
+}
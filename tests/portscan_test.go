@@ -0,0 +1,132 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/yourusername/subrecon/pkg/portscan"
+)
+
+// listen opens a local TCP listener that accepts and drops connections
+func listen(t *testing.T) int {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+
+	return ln.Addr().(*net.TCPAddr).Port
+}
+
+// closedPort returns a local port nothing listens on
+func closedPort(t *testing.T) int {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := ln.Addr().(*net.TCPAddr).Port
+	ln.Close()
+	return port
+}
+
+func TestParseScanPorts(t *testing.T) {
+	ports, err := portscan.ParsePorts("443, 80,8000-8003,443")
+	if err != nil {
+		t.Fatalf("ParsePorts failed: %v", err)
+	}
+	if fmt.Sprint(ports) != "[443 80 8000 8001 8002 8003]" {
+		t.Errorf("ParsePorts = %v", ports)
+	}
+
+	top, err := portscan.ParsePorts("top-100,22,61000")
+	if err != nil {
+		t.Fatalf("ParsePorts failed: %v", err)
+	}
+	if len(top) != 101 || top[len(top)-1] != 61000 {
+		t.Errorf("Expected the top 100 plus one port, got %d ports", len(top))
+	}
+
+	for _, bad := range []string{"", "http", "0", "70000", "90-80", "80-"} {
+		if _, err := portscan.ParsePorts(bad); err == nil {
+			t.Errorf("Expected %q to be rejected", bad)
+		}
+	}
+}
+
+func TestScanFindsOpenPorts(t *testing.T) {
+	open1, open2, closed := listen(t), listen(t), closedPort(t)
+
+	scanner := portscan.New(portscan.Options{
+		Ports:   []int{closed, open2, open1},
+		Threads: 4,
+		Timeout: time.Second,
+	})
+
+	// The duplicate address is only scanned once
+	found, stats := scanner.Scan(context.Background(), []string{"127.0.0.1", "127.0.0.1", "not-an-ip"})
+
+	want := []int{open1, open2}
+	if open2 < open1 {
+		want = []int{open2, open1}
+	}
+	if fmt.Sprint(found["127.0.0.1"]) != fmt.Sprint(want) || len(found) != 1 {
+		t.Errorf("Scan = %v, want 127.0.0.1: %v", found, want)
+	}
+	if stats.Addresses != 1 || stats.Attempts != 3 || stats.Open != 2 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+}
+
+func TestScanRateLimit(t *testing.T) {
+	open := listen(t)
+
+	scanner := portscan.New(portscan.Options{
+		Ports:     []int{open},
+		Threads:   10,
+		RateLimit: 20,
+		Timeout:   time.Second,
+	})
+
+	// Ten addresses on the loopback network, one attempt each, at 20/s
+	ips := make([]string, 10)
+	for i := range ips {
+		ips[i] = fmt.Sprintf("127.0.0.%d", i+1)
+	}
+
+	start := time.Now()
+	_, stats := scanner.Scan(context.Background(), ips)
+	if elapsed := time.Since(start); elapsed < 400*time.Millisecond {
+		t.Errorf("Expected 10 attempts at 20/s to take at least 400ms, took %v", elapsed)
+	}
+	if stats.Attempts != 10 {
+		t.Errorf("Expected 10 attempts, got %d", stats.Attempts)
+	}
+}
+
+func TestScanCancelled(t *testing.T) {
+	scanner := portscan.New(portscan.Options{Ports: []int{listen(t)}, RateLimit: 1})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, stats := scanner.Scan(ctx, []string{"127.0.0.1", "127.0.0.2"}); stats.Attempts > 1 {
+		t.Errorf("Expected a cancelled scan to stop, made %d attempts", stats.Attempts)
+	}
+}
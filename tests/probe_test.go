@@ -0,0 +1,222 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/yourusername/subrecon/pkg/probe"
+	"github.com/yourusername/subrecon/pkg/scope"
+)
+
+// serverPort returns the port a test server listens on
+func serverPort(t *testing.T, srv *httptest.Server) int {
+	t.Helper()
+
+	u, _ := url.Parse(srv.URL)
+	_, portStr, _ := net.SplitHostPort(u.Host)
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		t.Fatalf("invalid test server URL %s", srv.URL)
+	}
+	return port
+}
+
+func newPortalHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/login", http.StatusFound)
+	})
+	mux.HandleFunc("/login", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Server", "test-server")
+		fmt.Fprint(w, "<html><head><title>\n  Login &amp; Portal\n</title></head><body>hi</body></html>")
+	})
+	return mux
+}
+
+func TestParsePorts(t *testing.T) {
+	ports, err := probe.ParsePorts("80, 443,8080,443")
+	if err != nil {
+		t.Fatalf("ParsePorts failed: %v", err)
+	}
+	if fmt.Sprint(ports) != "[80 443 8080]" {
+		t.Errorf("ParsePorts = %v", ports)
+	}
+
+	for _, bad := range []string{"http", "0", "70000"} {
+		if _, err := probe.ParsePorts(bad); err == nil {
+			t.Errorf("Expected %q to be rejected", bad)
+		}
+	}
+}
+
+func TestProbeHTTP(t *testing.T) {
+	srv := httptest.NewServer(newPortalHandler())
+	defer srv.Close()
+	port := serverPort(t, srv)
+
+	prober := probe.New(probe.Options{Ports: []int{port}, Timeout: 2 * time.Second})
+	responses := prober.Probe(context.Background(), "127.0.0.1")
+	if len(responses) != 1 {
+		t.Fatalf("Expected one response, got %d", len(responses))
+	}
+
+	resp := responses[0]
+	base := fmt.Sprintf("http://127.0.0.1:%d", port)
+	if resp.URL != base+"/" {
+		t.Errorf("URL = %s, want plain HTTP after HTTPS failed", resp.URL)
+	}
+	if resp.StatusCode != http.StatusOK || resp.Title != "Login & Portal" || resp.Server != "test-server" {
+		t.Errorf("Unexpected response: %+v", resp)
+	}
+	if fmt.Sprint(resp.Redirects) != "["+base+"/login]" {
+		t.Errorf("Redirects = %v", resp.Redirects)
+	}
+	if resp.FinalURL != base+"/login" {
+		t.Errorf("FinalURL = %s", resp.FinalURL)
+	}
+	if resp.ContentLength <= 0 || resp.TLSVersion != "" {
+		t.Errorf("Unexpected length or TLS version: %+v", resp)
+	}
+}
+
+func TestProbeHTTPS(t *testing.T) {
+	srv := httptest.NewTLSServer(newPortalHandler())
+	defer srv.Close()
+
+	prober := probe.New(probe.Options{Ports: []int{serverPort(t, srv)}, Timeout: 2 * time.Second})
+	responses := prober.Probe(context.Background(), "127.0.0.1")
+	if len(responses) != 1 {
+		t.Fatalf("Expected one response, got %d", len(responses))
+	}
+	if responses[0].TLSVersion == "" || responses[0].Title != "Login & Portal" {
+		t.Errorf("Unexpected response: %+v", responses[0])
+	}
+}
+
+func TestProbeRedirectToOtherHost(t *testing.T) {
+	var hits int32
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		fmt.Fprint(w, "<title>Elsewhere</title>")
+	}))
+	defer other.Close()
+	elsewhere := fmt.Sprintf("http://localhost:%d/", serverPort(t, other))
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, elsewhere, http.StatusFound)
+	}))
+	defer srv.Close()
+	port := serverPort(t, srv)
+
+	// By default the redirect is recorded, not followed
+	prober := probe.New(probe.Options{Ports: []int{port}, Timeout: 2 * time.Second})
+	responses := prober.Probe(context.Background(), "127.0.0.1")
+	if len(responses) != 1 {
+		t.Fatalf("Expected one response, got %d", len(responses))
+	}
+	resp := responses[0]
+	if resp.StatusCode != http.StatusFound || resp.FinalURL != resp.URL || len(resp.Redirects) != 0 {
+		t.Errorf("Expected the redirect itself, got %+v", resp)
+	}
+	if atomic.LoadInt32(&hits) != 0 {
+		t.Error("Expected the other host not to be contacted")
+	}
+
+	// Opting in follows it and says where the response came from
+	prober = probe.New(probe.Options{Ports: []int{port}, Timeout: 2 * time.Second, FollowRedirects: true})
+	responses = prober.Probe(context.Background(), "127.0.0.1")
+	if len(responses) != 1 {
+		t.Fatalf("Expected one response, got %d", len(responses))
+	}
+	resp = responses[0]
+	if resp.StatusCode != http.StatusOK || resp.Title != "Elsewhere" || resp.FinalURL != elsewhere {
+		t.Errorf("Expected the other host's response, got %+v", resp)
+	}
+}
+
+func TestProbeRedirectOutOfScope(t *testing.T) {
+	var hits int32
+	counting := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+	})
+
+	// One target is out of scope by name, the other by address
+	byName := httptest.NewServer(counting)
+	defer byName.Close()
+	listener, err := net.Listen("tcp", "127.0.0.2:0")
+	if err != nil {
+		t.Skipf("Can't listen on 127.0.0.2: %v", err)
+	}
+	byAddress := httptest.NewUnstartedServer(counting)
+	byAddress.Listener.Close()
+	byAddress.Listener = listener
+	byAddress.Start()
+	defer byAddress.Close()
+
+	sc, err := scope.Parse([]byte("in_scope: [127.0.0.1, 127.0.0.2]\nforbidden_cidrs: [127.0.0.2]\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	targets := []string{
+		fmt.Sprintf("http://localhost:%d/", serverPort(t, byName)),
+		byAddress.URL + "/",
+	}
+	for _, target := range targets {
+		srv := httptest.NewServer(http.RedirectHandler(target, http.StatusFound))
+		prober := probe.New(probe.Options{
+			Ports:           []int{serverPort(t, srv)},
+			Timeout:         2 * time.Second,
+			FollowRedirects: true,
+			Scope:           sc,
+		})
+		for _, resp := range prober.Probe(context.Background(), "127.0.0.1") {
+			if resp.FinalURL != resp.URL {
+				t.Errorf("Expected the redirect to %s not to be followed, got %+v", target, resp)
+			}
+		}
+		srv.Close()
+	}
+
+	if n := atomic.LoadInt32(&hits); n != 0 {
+		t.Errorf("Expected no requests out of scope, got %d", n)
+	}
+}
+
+func TestProbeManyRateLimit(t *testing.T) {
+	srv := httptest.NewServer(newPortalHandler())
+	defer srv.Close()
+
+	// A port nothing listens on
+	closed, _ := net.Listen("tcp", "127.0.0.1:0")
+	closedPort := closed.Addr().(*net.TCPAddr).Port
+	closed.Close()
+
+	prober := probe.New(probe.Options{
+		Ports:     []int{serverPort(t, srv), closedPort},
+		Threads:   4,
+		RateLimit: 20,
+		Timeout:   2 * time.Second,
+	})
+
+	start := time.Now()
+	responses := prober.ProbeMany(context.Background(), []string{"127.0.0.1", "localhost"})
+	elapsed := time.Since(start)
+
+	if len(responses) != 2 || len(responses[0]) != 1 || len(responses[1]) != 1 {
+		t.Fatalf("Expected one answering port per host, got %v", responses)
+	}
+
+	// Two hosts, two ports, https then http: eight requests at 20/s
+	if elapsed < 300*time.Millisecond {
+		t.Errorf("Expected the rate limit to spread requests out, took %v", elapsed)
+	}
+}
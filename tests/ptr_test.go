@@ -0,0 +1,103 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/yourusername/subrecon/internal/resolve"
+	"github.com/yourusername/subrecon/pkg/ptr"
+)
+
+func TestReverseName(t *testing.T) {
+	tests := []struct {
+		ip   string
+		want string
+	}{
+		{"192.0.2.10", "10.2.0.192.in-addr.arpa"},
+		{"2001:db8::567:89ab", "b.a.9.8.7.6.5.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.8.b.d.0.1.0.0.2.ip6.arpa"},
+	}
+
+	for _, tt := range tests {
+		got, err := resolve.ReverseName(tt.ip)
+		if err != nil {
+			t.Errorf("ReverseName(%s) failed: %v", tt.ip, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ReverseName(%s) = %s, want %s", tt.ip, got, tt.want)
+		}
+	}
+
+	if _, err := resolve.ReverseName("not-an-ip"); err == nil {
+		t.Error("Expected invalid address to fail")
+	}
+}
+
+func TestPTRRanges(t *testing.T) {
+	ranges := ptr.Ranges([]string{
+		"198.51.100.7",
+		"192.0.2.10",
+		"192.0.2.200",
+		"2001:db8::1",
+		"bogus",
+	}, 24, 120)
+
+	got := make([]string, len(ranges))
+	for i, network := range ranges {
+		got[i] = network.String()
+	}
+
+	want := "[192.0.2.0/24 198.51.100.0/24 2001:db8::/120]"
+	if fmt.Sprint(got) != want {
+		t.Errorf("Ranges = %v, want %s", got, want)
+	}
+}
+
+func TestPTRSweep(t *testing.T) {
+	server := newFakeDNSServer(t)
+	server.Add(
+		"3.2.0.192.in-addr.arpa PTR db.example.com",
+		"12.2.0.192.in-addr.arpa PTR mail.example.com",
+		"12.2.0.192.in-addr.arpa PTR mail.other.net",
+		"20.2.0.192.in-addr.arpa PTR outside.example.com",
+		// RFC 2317 classless delegation
+		"5.2.0.192.in-addr.arpa CNAME 5.0-28.2.0.192.in-addr.arpa",
+		"5.0-28.2.0.192.in-addr.arpa PTR vpn.example.com",
+	)
+
+	resolver := resolve.NewResolver(&resolve.Config{
+		Servers: []string{server.Addr},
+		Timeout: 2 * time.Second,
+	})
+
+	hits, stats, err := ptr.Run(context.Background(), resolver, []string{"192.0.2.10"}, "example.com", ptr.Options{
+		IPv4Prefix: 28,
+		IPv6Prefix: ptr.DefaultIPv6Prefix,
+		Threads:    4,
+	})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	want := "[{db.example.com 192.0.2.3} {mail.example.com 192.0.2.12} {vpn.example.com 192.0.2.5}]"
+	if fmt.Sprint(hits) != want {
+		t.Errorf("Run = %v, want %s", hits, want)
+	}
+	if stats.Ranges != 1 || stats.Addresses != 16 || stats.Answered != 3 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+}
+
+func TestPTRSweepRejectsWidePrefix(t *testing.T) {
+	resolver := resolve.NewResolver(&resolve.Config{Servers: []string{"127.0.0.1:1"}})
+
+	_, _, err := ptr.Run(context.Background(), resolver, []string{"192.0.2.10"}, "example.com", ptr.Options{
+		IPv4Prefix: 8,
+		IPv6Prefix: ptr.DefaultIPv6Prefix,
+	})
+	if err == nil {
+		t.Error("Expected a /8 sweep to be rejected")
+	}
+}
@@ -0,0 +1,174 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/yourusername/subrecon/pkg/quota"
+	"github.com/yourusername/subrecon/pkg/runner"
+	"github.com/yourusername/subrecon/pkg/sources"
+)
+
+func TestLedgerBudget(t *testing.T) {
+	ledger, err := quota.Open(filepath.Join(t.TempDir(), "usage.json"))
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+
+	now := time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC)
+	ledger.SetClock(func() time.Time { return now })
+	ledger.SetLimit("alienvault", "secret", 2, quota.Daily)
+
+	for i := 0; i < 2; i++ {
+		if !ledger.Reserve("alienvault", "secret") {
+			t.Fatalf("Reserve %d should fit in budget", i)
+		}
+	}
+	if ledger.Reserve("alienvault", "secret") {
+		t.Error("Expected budget to be exhausted")
+	}
+
+	st := ledger.Status("alienvault")
+	if st.Used != 2 || st.Remaining != 0 {
+		t.Errorf("Expected 2 used and 0 remaining, got %d and %d", st.Used, st.Remaining)
+	}
+
+	// Budget resets when the next day starts
+	now = now.Add(24 * time.Hour)
+	if !ledger.Reserve("alienvault", "secret") {
+		t.Error("Expected budget to reset in the next period")
+	}
+}
+
+func TestLedgerPerKey(t *testing.T) {
+	ledger, err := quota.Open(filepath.Join(t.TempDir(), "usage.json"))
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+
+	ledger.SetLimit("urlscan", "key-one", 1, quota.Monthly)
+	if !ledger.Reserve("urlscan", "key-one") {
+		t.Fatal("First request should fit in budget")
+	}
+	if ledger.Reserve("urlscan", "key-one") {
+		t.Fatal("Expected key budget to be exhausted")
+	}
+
+	// A new key gets its own budget
+	ledger.SetLimit("urlscan", "key-two", 1, quota.Monthly)
+	if !ledger.Reserve("urlscan", "key-two") {
+		t.Error("Expected fresh budget for a new key")
+	}
+
+	if st := ledger.Status("urlscan"); st.Key == "" || st.Key == "key-two" {
+		t.Errorf("Expected hashed key identifier, got %q", st.Key)
+	}
+}
+
+func TestLedgerPersistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", "usage.json")
+
+	ledger, err := quota.Open(path)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	ledger.SetLimit("crtsh", "", 5, quota.Daily)
+	ledger.Reserve("crtsh", "")
+	ledger.Reserve("crtsh", "")
+	if err := ledger.Save(); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	reopened, err := quota.Open(path)
+	if err != nil {
+		t.Fatalf("Reopen failed: %v", err)
+	}
+	reopened.SetLimit("crtsh", "", 5, quota.Daily)
+
+	if remaining, limited := reopened.Remaining("crtsh"); !limited || remaining != 3 {
+		t.Errorf("Expected 3 remaining after reload, got %d (limited=%v)", remaining, limited)
+	}
+}
+
+func TestRunnerSkipsExhaustedSource(t *testing.T) {
+	ledger, err := quota.Open(filepath.Join(t.TempDir(), "usage.json"))
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	ledger.SetLimit("limited", "", 1, quota.Daily)
+	ledger.Reserve("limited", "")
+
+	srcs := []sources.Source{
+		&MockSource{name: "limited", subdomains: []string{"api.example.com"}},
+		&MockSource{name: "free", subdomains: []string{"www.example.com"}},
+	}
+
+	r := runner.NewRunner(srcs, &runner.Config{
+		Workers: 2,
+		Timeout: 10 * time.Second,
+		Silent:  true,
+	})
+	r.SetLedger(ledger)
+
+	subdomains, err := r.Run(context.Background(), "example.com")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(subdomains) != 1 || subdomains[0] != "www.example.com" {
+		t.Errorf("Expected only the unlimited source to run, got %v", subdomains)
+	}
+}
+
+func TestRunnerQuotaErrorMatches(t *testing.T) {
+	ledger, err := quota.Open(filepath.Join(t.TempDir(), "usage.json"))
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	ledger.SetLimit("limited", "", 1, quota.Daily)
+	ledger.Reserve("limited", "")
+
+	r := runner.NewRunner([]sources.Source{&MockSource{name: "limited"}}, &runner.Config{
+		Workers: 1,
+		Timeout: 10 * time.Second,
+		Silent:  true,
+	})
+	r.SetLedger(ledger)
+
+	if _, err := r.Run(context.Background(), "example.com"); !errors.Is(err, sources.ErrQuotaExhausted) {
+		t.Errorf("Expected ErrQuotaExhausted, got %v", err)
+	}
+}
+
+// refusingBudget records the requests it is asked about and allows none
+type refusingBudget struct {
+	calls []string
+}
+
+func (b *refusingBudget) Reserve(source, apiKey string) bool {
+	b.calls = append(b.calls, source+"/"+apiKey)
+	return false
+}
+
+func TestSourceStopsWhenBudgetSpent(t *testing.T) {
+	budget := &refusingBudget{}
+	src := sources.NewAlienVault(&sources.SourceConfig{
+		APIKey:    "secret",
+		Timeout:   10,
+		Retry:     3,
+		UserAgent: "Test/1.0",
+		Budget:    budget,
+	})
+
+	// The request is refused before it is sent, so nothing goes out and
+	// the source doesn't retry
+	_, err := src.Run(context.Background(), "example.com")
+	if !errors.Is(err, sources.ErrQuotaExhausted) {
+		t.Fatalf("Expected ErrQuotaExhausted, got %v", err)
+	}
+	if len(budget.calls) != 1 || budget.calls[0] != "alienvault/secret" {
+		t.Errorf("Expected one reservation for alienvault/secret, got %v", budget.calls)
+	}
+}
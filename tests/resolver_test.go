@@ -5,7 +5,7 @@ import (
 	"testing"
 	"time"
 
-	"github.com/yourusername/subfinder-pro/internal/resolve"
+	"github.com/yourusername/subrecon/internal/resolve"
 )
 
 func TestValidateDomain(t *testing.T) {
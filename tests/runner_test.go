@@ -5,8 +5,8 @@ import (
 	"testing"
 	"time"
 
-	"github.com/yourusername/subfinder-pro/pkg/runner"
-	"github.com/yourusername/subfinder-pro/pkg/sources"
+	"github.com/yourusername/subrecon/pkg/runner"
+	"github.com/yourusername/subrecon/pkg/sources"
 )
 
 // MockSource implements the Source interface for testing
@@ -0,0 +1,127 @@
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/yourusername/subrecon/pkg/scope"
+)
+
+const testScope = `
+in_scope:
+  - example.com
+  - "*.example.com"
+  - api-*.example.org
+out_of_scope:
+  - "*.corp.example.com"
+  - legacy.example.com
+allowed_cidrs:
+  - 192.0.2.0/24
+  - 2001:db8::/32
+forbidden_cidrs:
+  - 192.0.2.128/25
+  - 192.0.2.7
+`
+
+func TestScopeHosts(t *testing.T) {
+	sc, err := scope.Parse([]byte(testScope))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	tests := []struct {
+		host string
+		want bool
+	}{
+		{"example.com", true},
+		{"www.example.com", true},
+		{"a.b.example.com.", true},
+		{"WWW.Example.COM", true},
+		{"notexample.com", false},
+		{"legacy.example.com", false},
+		{"vpn.corp.example.com", false},
+		{"corp.example.com", true},
+		{"api-v2.example.org", true},
+		{"api-v2.eu.example.org", false},
+		{"example.org", false},
+	}
+	for _, tt := range tests {
+		if got := sc.HostAllowed(tt.host); got != tt.want {
+			t.Errorf("HostAllowed(%s) = %v, want %v", tt.host, got, tt.want)
+		}
+	}
+}
+
+func TestScopeAddresses(t *testing.T) {
+	sc, err := scope.Parse([]byte(testScope))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	for ip, want := range map[string]bool{
+		"192.0.2.1":    true,
+		"192.0.2.7":    false,
+		"192.0.2.200":  false,
+		"198.51.100.1": false,
+		"2001:db8::1":  true,
+		"2001:db9::1":  false,
+		"garbage":      false,
+	} {
+		if got := sc.AddressAllowed(ip); got != want {
+			t.Errorf("AddressAllowed(%s) = %v, want %v", ip, got, want)
+		}
+	}
+
+	// One address out of range is enough to keep away from a host
+	ok, reason := sc.Allowed("www.example.com", []string{"192.0.2.1", "192.0.2.130"})
+	if ok || !strings.Contains(reason, "192.0.2.130") {
+		t.Errorf("Allowed = %v, %q", ok, reason)
+	}
+	if ok, _ := sc.Allowed("www.example.com", []string{"192.0.2.1", "2001:db8::1"}); !ok {
+		t.Error("Expected a host with only allowed addresses to be in scope")
+	}
+	if ok, reason := sc.Allowed("legacy.example.com", nil); ok || reason != "host out of scope" {
+		t.Errorf("Allowed = %v, %q", ok, reason)
+	}
+}
+
+func TestScopeOnlyExclusions(t *testing.T) {
+	sc, err := scope.Parse([]byte("out_of_scope: [admin.example.com]\nforbidden_cidrs: [10.0.0.0/8]\n"))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	if !sc.HostAllowed("www.anything.net") || sc.HostAllowed("admin.example.com") {
+		t.Error("Expected everything but the excluded host to be in scope")
+	}
+	if !sc.AddressAllowed("192.0.2.1") || sc.AddressAllowed("10.1.2.3") {
+		t.Error("Expected everything but the forbidden range to be in scope")
+	}
+}
+
+func TestLoadScope(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "scope.yaml")
+	if err := os.WriteFile(path, []byte(testScope), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := scope.Load(path); err != nil {
+		t.Errorf("Load failed: %v", err)
+	}
+
+	for _, bad := range []string{
+		"",
+		"in_scope: [example..com]",
+		"allowed_cidrs: [192.0.2.0/33]",
+		"forbidden_cidrs: [not-an-address]",
+	} {
+		if _, err := scope.Parse([]byte(bad)); err == nil {
+			t.Errorf("Expected %q to be rejected", bad)
+		}
+	}
+	if _, err := scope.Load(filepath.Join(dir, "missing.yaml")); err == nil {
+		t.Error("Expected a missing scope file to fail")
+	}
+}
@@ -7,7 +7,7 @@ import (
 	"testing"
 	"time"
 
-	"github.com/yourusername/subfinder-pro/pkg/sources"
+	"github.com/yourusername/subrecon/pkg/sources"
 )
 
 func TestCrtSh(t *testing.T) {
@@ -27,7 +27,7 @@ func TestCrtSh(t *testing.T) {
 
 	config := &sources.SourceConfig{
 		RateLimit:  5,
-		Timeout:    10 * time.Second,
+		Timeout:    10,
 		Enabled:    true,
 		Retry:      3,
 		UserAgent:  "Test/1.0",
@@ -53,7 +53,7 @@ func TestCrtSh(t *testing.T) {
 func TestHackerTarget(t *testing.T) {
 	config := &sources.SourceConfig{
 		RateLimit:  2,
-		Timeout:    10 * time.Second,
+		Timeout:    10,
 		Enabled:    true,
 		Retry:      3,
 		UserAgent:  "Test/1.0",
@@ -74,7 +74,7 @@ func TestHackerTarget(t *testing.T) {
 func TestThreatCrowd(t *testing.T) {
 	config := &sources.SourceConfig{
 		RateLimit:  1,
-		Timeout:    10 * time.Second,
+		Timeout:    10,
 		Enabled:    true,
 		Retry:      3,
 		UserAgent:  "Test/1.0",
@@ -96,7 +96,7 @@ func TestAlienVault(t *testing.T) {
 	config := &sources.SourceConfig{
 		APIKey:     "test-key",
 		RateLimit:  10,
-		Timeout:    10 * time.Second,
+		Timeout:    10,
 		Enabled:    true,
 		Retry:      3,
 		UserAgent:  "Test/1.0",
@@ -117,7 +117,7 @@ func TestAlienVault(t *testing.T) {
 func TestURLScan(t *testing.T) {
 	config := &sources.SourceConfig{
 		RateLimit:  1,
-		Timeout:    10 * time.Second,
+		Timeout:    10,
 		Enabled:    true,
 		Retry:      3,
 		UserAgent:  "Test/1.0",
@@ -0,0 +1,141 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/yourusername/subrecon/internal/resolve"
+	"github.com/yourusername/subrecon/pkg/takeover"
+)
+
+// redirectTransport sends every request to a test server, whatever its host
+type redirectTransport struct {
+	target *url.URL
+}
+
+func (rt *redirectTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.URL.Scheme = rt.target.Scheme
+	req.URL.Host = rt.target.Host
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+// cnameResult builds a resolver answer for host with a CNAME to target
+func cnameResult(host, target string, rcode int) *resolve.Result {
+	res := &resolve.Result{
+		Host:  host,
+		Rcode: rcode,
+		Records: []resolve.RR{
+			{Name: host, Type: resolve.TypeCNAME, Class: resolve.ClassINET, TTL: 300, Data: target},
+		},
+	}
+	if rcode == resolve.RcodeSuccess {
+		res.Exists = true
+		res.IPs = []string{"192.0.2.80"}
+		res.Records = append(res.Records, resolve.RR{Name: target, Type: resolve.TypeA, Class: resolve.ClassINET, TTL: 300, Data: "192.0.2.80"})
+	}
+	return res
+}
+
+func newTakeoverChecker(t *testing.T, body string) *takeover.Checker {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, body)
+	}))
+	t.Cleanup(server.Close)
+
+	target, _ := url.Parse(server.URL)
+	checker := takeover.New(nil, 2*time.Second)
+	checker.HTTPClient = &http.Client{Transport: &redirectTransport{target: target}}
+
+	return checker
+}
+
+func TestDefaultFingerprints(t *testing.T) {
+	fingerprints := takeover.DefaultFingerprints()
+	if len(fingerprints) == 0 {
+		t.Fatal("Expected built-in fingerprints")
+	}
+
+	checker := takeover.New(fingerprints, 0)
+	fp, target := checker.Match([]string{"edge.example.net", "old-app.herokuapp.com"})
+	if fp == nil || fp.Service != "Heroku" || target != "old-app.herokuapp.com" {
+		t.Errorf("Match = %v, %s, want Heroku", fp, target)
+	}
+	if fp, _ := checker.Match([]string{"cdn.example.net"}); fp != nil {
+		t.Errorf("Expected no match, got %s", fp.Service)
+	}
+}
+
+func TestTakeoverNXDomain(t *testing.T) {
+	checker := newTakeoverChecker(t, "")
+
+	finding := checker.Check(context.Background(), cnameResult("old.example.com", "gone.azurewebsites.net", resolve.RcodeNXDomain))
+	if finding == nil {
+		t.Fatal("Expected dangling Azure CNAME to be a candidate")
+	}
+	if finding.Service != "Azure" || finding.Evidence != takeover.EvidenceNXDomain {
+		t.Errorf("Unexpected finding: %+v", finding)
+	}
+
+	// GitHub Pages needs a body signature, not just NXDOMAIN
+	if finding := checker.Check(context.Background(), cnameResult("docs.example.com", "gone.github.io", resolve.RcodeNXDomain)); finding != nil {
+		t.Errorf("Expected no finding, got %+v", finding)
+	}
+}
+
+func TestTakeoverBodySignature(t *testing.T) {
+	checker := newTakeoverChecker(t, "<Error><Code>NoSuchBucket</Code><Message>The specified bucket does not exist</Message></Error>")
+
+	findings := checker.CheckMany(context.Background(), []*resolve.Result{
+		cnameResult("assets.example.com", "assets.example.com.s3.amazonaws.com", resolve.RcodeSuccess),
+		cnameResult("www.example.com", "www.example.net", resolve.RcodeSuccess),
+	}, 2)
+
+	if findings[0] == nil || findings[0].Service != "AWS S3" || findings[0].Evidence != takeover.EvidenceBody {
+		t.Errorf("Unexpected finding for S3 host: %+v", findings[0])
+	}
+	if findings[1] != nil {
+		t.Errorf("Expected no finding for unfingerprinted host, got %+v", findings[1])
+	}
+}
+
+func TestTakeoverClaimedResource(t *testing.T) {
+	checker := newTakeoverChecker(t, "<html>Welcome to our docs</html>")
+
+	if finding := checker.Check(context.Background(), cnameResult("docs.example.com", "example.github.io", resolve.RcodeSuccess)); finding != nil {
+		t.Errorf("Expected claimed site not to be a candidate, got %+v", finding)
+	}
+}
+
+func TestLoadFingerprints(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "fingerprints.yaml")
+	data := "- service: Example Cloud\n  cname: [.apps.example-cloud.test]\n  nxdomain: true\n"
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	fingerprints, err := takeover.LoadFingerprints(path)
+	if err != nil {
+		t.Fatalf("LoadFingerprints failed: %v", err)
+	}
+
+	checker := takeover.New(fingerprints, 0)
+	finding := checker.Check(context.Background(), cnameResult("app.example.com", "mine.apps.example-cloud.test", resolve.RcodeNXDomain))
+	if finding == nil || finding.Service != "Example Cloud" {
+		t.Errorf("Unexpected finding: %+v", finding)
+	}
+
+	if _, err := takeover.ParseFingerprints([]byte("- service: Incomplete\n  cname: [example.test]\n")); err == nil {
+		t.Error("Expected fingerprint without signatures to be rejected")
+	}
+}
@@ -0,0 +1,166 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/yourusername/subrecon/internal/resolve"
+)
+
+// runQueries acquires and releases n slots, failing the first failed of them
+func runQueries(t *testing.T, throttle *resolve.Throttle, n, failed int) {
+	t.Helper()
+
+	for i := 0; i < n; i++ {
+		if err := throttle.Acquire(context.Background()); err != nil {
+			t.Fatalf("Acquire failed: %v", err)
+		}
+		throttle.Release(i < failed)
+	}
+}
+
+func TestThrottleAIMD(t *testing.T) {
+	throttle := resolve.NewThrottle(10)
+	if st := throttle.Stats(); st.Limit != 5 || st.Max != 10 {
+		t.Fatalf("Expected to start at 5/10, got %d/%d", st.Limit, st.Max)
+	}
+
+	// A clean window ramps up by one
+	runQueries(t, throttle, 20, 0)
+	if st := throttle.Stats(); st.Limit != 6 {
+		t.Errorf("Expected limit 6 after a clean window, got %d", st.Limit)
+	}
+
+	// A lossy window halves the limit
+	runQueries(t, throttle, 20, 5)
+	st := throttle.Stats()
+	if st.Limit != 3 {
+		t.Errorf("Expected limit 3 after a lossy window, got %d", st.Limit)
+	}
+	if st.Loss != 0.25 || st.Queries != 40 || st.Failures != 5 {
+		t.Errorf("Unexpected stats: %+v", st)
+	}
+
+	// Never beyond the maximum
+	runQueries(t, throttle, 500, 0)
+	if st := throttle.Stats(); st.Limit != 10 {
+		t.Errorf("Expected limit to stop at 10, got %d", st.Limit)
+	}
+}
+
+func TestThrottleBlocksAtLimit(t *testing.T) {
+	throttle := resolve.NewThrottle(2)
+	if err := throttle.Acquire(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := throttle.Acquire(ctx); err == nil {
+		t.Fatal("Expected Acquire to block while the only slot is taken")
+	}
+
+	acquired := make(chan error, 1)
+	go func() { acquired <- throttle.Acquire(context.Background()) }()
+	throttle.Release(false)
+
+	select {
+	case err := <-acquired:
+		if err != nil {
+			t.Errorf("Acquire failed: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected Release to wake the waiting Acquire")
+	}
+}
+
+func TestThrottleQPS(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	throttle := resolve.NewThrottle(10)
+	throttle.SetClock(func() time.Time { return now })
+
+	for i := 0; i < 20; i++ {
+		throttle.Acquire(context.Background())
+		now = now.Add(100 * time.Millisecond)
+		throttle.Release(false)
+	}
+
+	if st := throttle.Stats(); st.QPS != 10 {
+		t.Errorf("Expected 10 queries/s, got %v", st.QPS)
+	}
+}
+
+func TestResolverThrottleCountsServFail(t *testing.T) {
+	server := newFakeDNSServer(t)
+	server.Add("www.example.com A 192.0.2.1")
+	server.SetRcode("broken.example.com", resolve.RcodeServFail)
+
+	resolver := resolve.NewResolver(&resolve.Config{
+		Servers:     []string{server.Addr},
+		Timeout:     2 * time.Second,
+		Retries:     1,
+		RecordTypes: []uint16{resolve.TypeA},
+		MaxInFlight: 4,
+	})
+
+	resolver.ResolveMany(context.Background(), []string{"www.example.com", "broken.example.com"}, 2)
+
+	st := resolver.Throttle().Stats()
+	if st.Queries != 2 || st.Failures != 1 || st.InFlight != 0 {
+		t.Errorf("Unexpected throttle stats: %+v", st)
+	}
+}
+
+func TestResolverRateLimitCoversEveryQuery(t *testing.T) {
+	server := newFakeDNSServer(t)
+	server.Add("www.example.com A 192.0.2.1")
+
+	// Two record types per host, so five hosts are ten queries
+	resolver := resolve.NewResolver(&resolve.Config{
+		Servers:   []string{server.Addr},
+		Timeout:   2 * time.Second,
+		RateLimit: 50,
+	})
+
+	hosts := make([]string, 5)
+	for i := range hosts {
+		hosts[i] = fmt.Sprintf("host%d.example.com", i)
+	}
+
+	start := time.Now()
+	resolver.ResolveMany(context.Background(), hosts, 5)
+	if elapsed := time.Since(start); elapsed < 150*time.Millisecond {
+		t.Errorf("Expected 10 queries at 50/s to take at least 150ms, took %v", elapsed)
+	}
+	if server.Queries() != 10 {
+		t.Errorf("Expected 10 queries, got %d", server.Queries())
+	}
+}
+
+func TestCheckServersRateLimited(t *testing.T) {
+	servers := make([]string, 6)
+	for i := range servers {
+		servers[i] = newFakeDNSServer(t).Addr
+	}
+
+	resolver := resolve.NewResolver(&resolve.Config{
+		Servers:     servers,
+		Timeout:     2 * time.Second,
+		RateLimit:   20,
+		MaxInFlight: 2,
+	})
+
+	// Six canary queries at 20/s can't all go out at once
+	start := time.Now()
+	if banned := resolver.CheckServers(context.Background()); len(banned) != 0 {
+		t.Errorf("Expected no servers to be banned, got %v", banned)
+	}
+	if elapsed := time.Since(start); elapsed < 200*time.Millisecond {
+		t.Errorf("Expected 6 queries at 20/s to take at least 200ms, took %v", elapsed)
+	}
+	if stats := resolver.Throttle().Stats(); stats.Queries != 6 || stats.InFlight != 0 {
+		t.Errorf("Expected 6 queries through the throttle, all released, got %+v", stats)
+	}
+}
@@ -0,0 +1,187 @@
+package tests
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yourusername/subrecon/internal/resolve"
+)
+
+func TestParseServer(t *testing.T) {
+	tests := []struct {
+		server string
+		want   string
+		scheme string
+	}{
+		{"8.8.8.8", "8.8.8.8:53", resolve.SchemeUDP},
+		{"udp://8.8.8.8", "8.8.8.8:53", resolve.SchemeUDP},
+		{"tcp://9.9.9.9", "tcp://9.9.9.9:53", resolve.SchemeTCP},
+		{"tls://1.1.1.1", "tls://1.1.1.1:853", resolve.SchemeTLS},
+		{"tls://1.1.1.1:853#cloudflare-dns.com", "tls://1.1.1.1:853#cloudflare-dns.com", resolve.SchemeTLS},
+		{"https://dns.example/dns-query", "https://dns.example/dns-query", resolve.SchemeHTTPS},
+		{"https://dns.example/dns-query#GET", "https://dns.example/dns-query#get", resolve.SchemeHTTPS},
+		{"2001:4860:4860::8888", "[2001:4860:4860::8888]:53", resolve.SchemeUDP},
+		{"[2001:db8::1]", "[2001:db8::1]:53", resolve.SchemeUDP},
+		{"[2001:db8::1]:5353", "[2001:db8::1]:5353", resolve.SchemeUDP},
+		{"tcp://[2001:db8::1]", "tcp://[2001:db8::1]:53", resolve.SchemeTCP},
+		{"tls://[2606:4700:4700::1111]#one.one.one.one", "tls://[2606:4700:4700::1111]:853#one.one.one.one", resolve.SchemeTLS},
+	}
+
+	for _, tt := range tests {
+		ep, err := resolve.ParseServer(tt.server)
+		if err != nil {
+			t.Errorf("ParseServer(%q) failed: %v", tt.server, err)
+			continue
+		}
+		if ep.Scheme != tt.scheme || ep.String() != tt.want {
+			t.Errorf("ParseServer(%q) = %s %s, want %s %s", tt.server, ep.Scheme, ep.String(), tt.scheme, tt.want)
+		}
+	}
+
+	if _, err := resolve.ParseServer("quic://dns.example"); err == nil {
+		t.Error("Expected unsupported scheme to fail")
+	}
+}
+
+// newDoHServer serves the fake zone over DNS-over-HTTPS
+func newDoHServer(t *testing.T, zone *fakeDNSServer, methods *[]string) *httptest.Server {
+	t.Helper()
+
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*methods = append(*methods, r.Method)
+
+		var data []byte
+		var err error
+		switch r.Method {
+		case http.MethodGet:
+			data, err = base64.RawURLEncoding.DecodeString(r.URL.Query().Get("dns"))
+		case http.MethodPost:
+			if r.Header.Get("Content-Type") != "application/dns-message" {
+				http.Error(w, "bad content type", http.StatusUnsupportedMediaType)
+				return
+			}
+			data, err = io.ReadAll(r.Body)
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		q, err := resolve.Unpack(data)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		resp, err := zone.answer(q, false).Pack()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/dns-message")
+		w.Write(resp)
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+func TestDoHTransport(t *testing.T) {
+	zone := newFakeDNSServer(t)
+	zone.Add("doh.example.com A 192.0.2.53")
+
+	var methods []string
+	srv := newDoHServer(t, zone, &methods)
+
+	client := resolve.NewClient(2 * time.Second)
+	client.HTTPClient = srv.Client()
+	ctx := context.Background()
+
+	for _, server := range []string{srv.URL + "/dns-query", srv.URL + "/dns-query#get"} {
+		resp, err := client.Query(ctx, server, "doh.example.com", resolve.TypeA)
+		if err != nil {
+			t.Fatalf("DoH query to %s failed: %v", server, err)
+		}
+		if len(resp.Answers) != 1 || resp.Answers[0].Data != "192.0.2.53" {
+			t.Errorf("Unexpected DoH answer: %v", resp.Answers)
+		}
+	}
+
+	if strings.Join(methods, ",") != "POST,GET" {
+		t.Errorf("Expected POST then GET, got %v", methods)
+	}
+}
+
+func TestDoTTransport(t *testing.T) {
+	zone := newFakeDNSServer(t)
+	zone.Add("dot.example.com AAAA 2001:db8::53")
+
+	// Borrow the httptest certificate, which is valid for 127.0.0.1
+	certSrv := httptest.NewTLSServer(http.NotFoundHandler())
+	defer certSrv.Close()
+
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: certSrv.TLS.Certificates})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go zone.handleTCP(conn)
+		}
+	}()
+
+	client := resolve.NewClient(2 * time.Second)
+	client.TLSConfig = certSrv.Client().Transport.(*http.Transport).TLSClientConfig
+
+	_, port, _ := net.SplitHostPort(ln.Addr().String())
+	resp, err := client.Query(context.Background(), "tls://127.0.0.1:"+port, "dot.example.com", resolve.TypeAAAA)
+	if err != nil {
+		t.Fatalf("DoT query failed: %v", err)
+	}
+	if len(resp.Answers) != 1 || resp.Answers[0].Data != "2001:db8::53" {
+		t.Errorf("Unexpected DoT answer: %v", resp.Answers)
+	}
+}
+
+func TestPoolMixesTransports(t *testing.T) {
+	zone := newFakeDNSServer(t)
+	zone.Add("mixed.example.com A 192.0.2.99")
+
+	var methods []string
+	srv := newDoHServer(t, zone, &methods)
+
+	pool := resolve.NewPool([]string{zone.Addr, srv.URL + "/dns-query"})
+	if pool.Size() != 2 {
+		t.Fatalf("Expected 2 servers in pool, got %d", pool.Size())
+	}
+
+	client := resolve.NewClient(2 * time.Second)
+	client.HTTPClient = srv.Client()
+	for i := 0; i < 2; i++ {
+		server := pool.Next()
+		start := time.Now()
+		resp, err := client.Query(context.Background(), server, "mixed.example.com", resolve.TypeA)
+		pool.Report(server, time.Since(start), err)
+		if err != nil || len(resp.Answers) != 1 {
+			t.Errorf("Query via %s failed: %v", server, err)
+		}
+	}
+
+	if len(methods) != 1 || zone.Queries() != 2 {
+		t.Errorf("Expected one DoH and one UDP query, got %d DoH and %d total", len(methods), zone.Queries())
+	}
+}
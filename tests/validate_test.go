@@ -0,0 +1,119 @@
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/yourusername/subrecon/internal/resolve"
+)
+
+func TestValidateAgainstTrustedResolvers(t *testing.T) {
+	// The pool server makes up answers for names the trusted server rejects
+	pool := newFakeDNSServer(t)
+	pool.Add(
+		"www.example.com A 192.0.2.1",
+		"cdn.example.com CNAME edge.example.net",
+		"edge.example.net A 192.0.2.50",
+		"bogus.example.com A 198.51.100.66",
+		"moved.example.com A 198.51.100.7",
+		"flaky.example.com A 192.0.2.9",
+	)
+
+	trusted := newFakeDNSServer(t)
+	trusted.Add(
+		"www.example.com A 192.0.2.1",
+		"www.example.com A 192.0.2.2",
+		"cdn.example.com CNAME edge.example.net",
+		"edge.example.net A 192.0.2.51",
+		"moved.example.com A 192.0.2.7",
+	)
+	trusted.SetRcode("flaky.example.com", resolve.RcodeServFail)
+
+	resolver := resolve.NewResolver(&resolve.Config{
+		Servers:        []string{pool.Addr},
+		TrustedServers: []string{trusted.Addr},
+		Timeout:        2 * time.Second,
+	})
+	if !resolver.HasTrusted() {
+		t.Fatal("Expected trusted servers to be configured")
+	}
+
+	ctx := context.Background()
+	hosts := []string{"www.example.com", "cdn.example.com", "bogus.example.com", "moved.example.com", "flaky.example.com"}
+	results, err := resolver.ResolveMany(ctx, hosts, 4)
+	if err != nil {
+		t.Fatalf("ResolveMany failed: %v", err)
+	}
+
+	want := map[string]string{
+		"www.example.com":   resolve.VerdictConfirmed,
+		"cdn.example.com":   resolve.VerdictConfirmed,
+		"bogus.example.com": resolve.VerdictNotFound,
+		"moved.example.com": resolve.VerdictMismatch,
+		"flaky.example.com": resolve.VerdictUnverified,
+	}
+
+	validations := resolver.ValidateMany(ctx, results, 4)
+	if len(validations) != len(results) {
+		t.Fatalf("Expected %d validations, got %d", len(results), len(validations))
+	}
+	for i, v := range validations {
+		if v.Host != results[i].Host {
+			t.Errorf("Validation %d is for %s, want %s", i, v.Host, results[i].Host)
+		}
+		if v.Verdict != want[v.Host] {
+			t.Errorf("Verdict for %s = %s, want %s", v.Host, v.Verdict, want[v.Host])
+		}
+		if v.Disagrees() != (v.Verdict == resolve.VerdictNotFound || v.Verdict == resolve.VerdictMismatch) {
+			t.Errorf("Disagrees() wrong for %s", v.Host)
+		}
+		if v.Refuted() != (v.Verdict == resolve.VerdictNotFound) {
+			t.Errorf("Refuted() wrong for %s", v.Host)
+		}
+	}
+}
+
+func TestValidateBypassesCache(t *testing.T) {
+	server := newFakeDNSServer(t)
+	server.Add("www.example.com A 192.0.2.1")
+
+	resolver := resolve.NewResolver(&resolve.Config{
+		Servers:        []string{server.Addr},
+		TrustedServers: []string{server.Addr},
+		Timeout:        2 * time.Second,
+		RecordTypes:    []uint16{resolve.TypeA},
+	})
+
+	ctx := context.Background()
+	result, err := resolver.Resolve(ctx, "www.example.com")
+	if err != nil {
+		t.Fatalf("Resolve failed: %v", err)
+	}
+
+	queries := server.Queries()
+	if v := resolver.Validate(ctx, result); v.Verdict != resolve.VerdictConfirmed {
+		t.Errorf("Expected confirmed, got %s", v.Verdict)
+	}
+	if server.Queries() == queries {
+		t.Error("Expected validation to query the trusted server")
+	}
+}
+
+func TestValidateWithoutTrustedServers(t *testing.T) {
+	server := newFakeDNSServer(t)
+	server.Add("www.example.com A 192.0.2.1")
+
+	resolver := newTestResolver(t, server)
+	if resolver.HasTrusted() {
+		t.Fatal("Expected no trusted servers")
+	}
+
+	result, err := resolver.Resolve(context.Background(), "www.example.com")
+	if err != nil {
+		t.Fatalf("Resolve failed: %v", err)
+	}
+	if v := resolver.Validate(context.Background(), result); v.Verdict != resolve.VerdictUnverified {
+		t.Errorf("Expected unverified without trusted servers, got %s", v.Verdict)
+	}
+}
@@ -0,0 +1,140 @@
+package tests
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yourusername/subrecon/internal/resolve"
+)
+
+func newTestResolver(t *testing.T, server *fakeDNSServer) *resolve.Resolver {
+	t.Helper()
+	return resolve.NewResolver(&resolve.Config{
+		Servers: []string{server.Addr},
+		Timeout: 2 * time.Second,
+	})
+}
+
+func TestWildcardBelowApex(t *testing.T) {
+	server := newFakeDNSServer(t)
+	server.Add(
+		"www.example.com A 192.0.2.1",
+		"*.dev.example.com A 192.0.2.66",
+		"api.dev.example.com A 192.0.2.10",
+	)
+
+	resolver := newTestResolver(t, server)
+	ctx := context.Background()
+
+	if isWildcard, _, _ := resolver.DetectWildcard(ctx, "example.com"); isWildcard {
+		t.Error("Expected no wildcard at the apex")
+	}
+
+	tests := map[string]bool{
+		"www.example.com":           false,
+		"api.dev.example.com":       false,
+		"junk.dev.example.com":      true,
+		"deep.junk.dev.example.com": true,
+	}
+	for host, want := range tests {
+		if got := resolver.IsWildcard(host, "example.com"); got != want {
+			t.Errorf("IsWildcard(%s) = %v, want %v", host, got, want)
+		}
+	}
+
+	zones := resolver.WildcardZones()
+	found := false
+	for _, zone := range zones {
+		if zone == "dev.example.com" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("Expected dev.example.com among wildcard zones, got %v", zones)
+	}
+}
+
+func TestWildcardKeepsHostSharingOneIP(t *testing.T) {
+	server := newFakeDNSServer(t)
+	server.Add(
+		"*.example.com A 192.0.2.1",
+		"shared.example.com A 192.0.2.1",
+		"shared.example.com A 192.0.2.2",
+		"same.example.com A 192.0.2.1",
+	)
+
+	resolver := newTestResolver(t, server)
+
+	if resolver.IsWildcard("shared.example.com", "example.com") {
+		t.Error("Host with a distinct answer set should not be treated as wildcard")
+	}
+	if !resolver.IsWildcard("same.example.com", "example.com") {
+		t.Error("Host with the wildcard's exact answer set should be treated as wildcard")
+	}
+}
+
+func TestWildcardCNAMETarget(t *testing.T) {
+	server := newFakeDNSServer(t)
+	server.Add(
+		"*.cdn.example.com CNAME catchall.edge.example.net",
+		"catchall.edge.example.net A 192.0.2.80",
+		"own.cdn.example.com CNAME own.edge.example.net",
+		"own.edge.example.net A 192.0.2.80",
+	)
+
+	resolver := newTestResolver(t, server)
+
+	if !resolver.IsWildcard("anything.cdn.example.com", "example.com") {
+		t.Error("Expected host CNAMEd to the wildcard target to be filtered")
+	}
+	if resolver.IsWildcard("own.cdn.example.com", "example.com") {
+		t.Error("Expected host with its own CNAME target to be kept despite sharing an IP")
+	}
+}
+
+func TestWildcardFingerprintCached(t *testing.T) {
+	server := newFakeDNSServer(t)
+	server.Add("*.example.com A 192.0.2.1")
+
+	resolver := newTestResolver(t, server)
+	ctx := context.Background()
+
+	resolver.Fingerprint(ctx, "example.com")
+	queries := server.Queries()
+	fp := resolver.Fingerprint(ctx, "example.com")
+
+	if !fp.Wildcard {
+		t.Error("Expected wildcard fingerprint")
+	}
+	if server.Queries() != queries {
+		t.Errorf("Expected cached fingerprint, got %d extra queries", server.Queries()-queries)
+	}
+}
+
+func TestWildcardProbesNotCached(t *testing.T) {
+	server := newFakeDNSServer(t)
+	server.Add("*.example.com A 192.0.2.1")
+	server.SetSOA(exampleSOA)
+
+	resolver := newTestResolver(t, server)
+	if fp := resolver.Fingerprint(context.Background(), "example.com"); !fp.Wildcard {
+		t.Fatal("Expected wildcard fingerprint")
+	}
+
+	// The random probe names must not end up in a saved cache
+	path := filepath.Join(t.TempDir(), "dns-cache.json")
+	if err := resolver.SaveCache(path); err != nil {
+		t.Fatalf("SaveCache failed: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), ".example.com") {
+		t.Errorf("Expected no probe names in the cache, got %s", data)
+	}
+}